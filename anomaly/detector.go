@@ -0,0 +1,316 @@
+// Package anomaly detects spikes and drops of clicks. Click statistic of a
+// link is counted in time buckets, every bucket is compared with a baseline
+// learned from previous buckets (rolling z-score, EWMA or hour-of-week
+// seasonal baseline). Baselines are persisted per hash between runs.
+//
+//	detector, e := anomaly.New(client, "baselines.json")
+//	detector.Sink = anomaly.LogSink{}
+//	events, err := detector.Check("test", time.Now())
+package anomaly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DEFAULT_INTERVAL = time.Hour
+const DEFAULT_LOOKBACK = 7 * 24 * time.Hour
+
+type Kind string
+
+const (
+	KindSpike Kind = "spike"
+	KindDrop  Kind = "drop"
+)
+
+// Alert of one bucket detected by one method
+type Event struct {
+	Hash   string `json:"hash"`
+	Method string `json:"method"`
+	Kind   Kind   `json:"kind"`
+	// Start of bucket
+	Time     time.Time     `json:"time"`
+	Interval time.Duration `json:"interval"`
+	Count    float64       `json:"count"`
+	Expected float64       `json:"expected"`
+	// Deviation from expected count in standard deviations
+	Score     float64 `json:"score"`
+	Threshold float64 `json:"threshold"`
+}
+
+func (event *Event) String() string {
+	return fmt.Sprintf("%s %s at %s: %.0f clicks, expected %.1f (%s %+.1f)",
+		event.Hash, event.Kind, event.Time.Format(time.RFC3339), event.Count, event.Expected, event.Method, event.Score)
+}
+
+// Learned state of one hash
+type Baseline struct {
+	// End of the last observed bucket
+	Last time.Time `json:"last"`
+	// Recent bucket counts of ZScore
+	Recent []float64 `json:"recent,omitempty"`
+	// State of EWMA
+	EWMA struct {
+		Count    int64   `json:"count"`
+		Mean     float64 `json:"mean"`
+		Variance float64 `json:"variance"`
+	} `json:"ewma"`
+	// Hour-of-week slots of Seasonal
+	Seasonal []Moments `json:"seasonal,omitempty"`
+}
+
+type Detector struct {
+	Client *tinysrc.Client
+	// Bucket size, DEFAULT_INTERVAL when zero
+	Interval time.Duration
+	// History loaded for hashes without baseline, DEFAULT_LOOKBACK when zero
+	Lookback time.Duration
+	// ZScore, EWMA and Seasonal with defaults when empty
+	Methods []Method
+	// Buckets with both count and expected count below MinCount are ignored
+	MinCount float64
+	// Receives every event, optional
+	Sink Sink
+
+	path      string
+	mutex     sync.Mutex
+	baselines map[string]*Baseline
+}
+
+// Constructor of Detector, baselines are loaded from path if file exists.
+// Empty path keeps baselines in memory only.
+func New(client *tinysrc.Client, path string) (*Detector, error) {
+	detector := &Detector{
+		Client:    client,
+		Interval:  DEFAULT_INTERVAL,
+		Lookback:  DEFAULT_LOOKBACK,
+		path:      path,
+		baselines: make(map[string]*Baseline),
+	}
+
+	if path == "" {
+		return detector, nil
+	}
+
+	data, e := os.ReadFile(path)
+	if errors.Is(e, os.ErrNotExist) {
+		return detector, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	if e = json.Unmarshal(data, &detector.baselines); e != nil {
+		return nil, fmt.Errorf("anomaly: %s: %w", path, e)
+	}
+
+	if detector.baselines == nil {
+		detector.baselines = make(map[string]*Baseline)
+	}
+
+	return detector, nil
+}
+
+func (detector *Detector) methods() []Method {
+	if len(detector.Methods) > 0 {
+		return detector.Methods
+	}
+
+	return []Method{ZScore{}, EWMA{}, Seasonal{}}
+}
+
+func (detector *Detector) interval() time.Duration {
+	if detector.Interval <= 0 {
+		return DEFAULT_INTERVAL
+	}
+
+	return detector.Interval
+}
+
+// Copy of baseline of hash, nil when hash was never observed
+func (detector *Detector) Baseline(hash string) *Baseline {
+	detector.mutex.Lock()
+	defer detector.mutex.Unlock()
+
+	baseline, ok := detector.baselines[hash]
+	if !ok {
+		return nil
+	}
+
+	clone := *baseline
+	clone.Recent = append([]float64(nil), baseline.Recent...)
+	clone.Seasonal = append([]Moments(nil), baseline.Seasonal...)
+	return &clone
+}
+
+// Forget baseline of hash
+func (detector *Detector) Reset(hash string) error {
+	detector.mutex.Lock()
+	defer detector.mutex.Unlock()
+
+	delete(detector.baselines, hash)
+	return detector.save()
+}
+
+// Load complete buckets since the last run, detect anomalies and persist baseline
+func (detector *Detector) Check(hash string, now time.Time) (events []*Event, errorResponse models.ErrorResponse) {
+	interval := detector.interval()
+	end := now.Truncate(interval)
+
+	lookback := detector.Lookback
+	if lookback <= 0 {
+		lookback = DEFAULT_LOOKBACK
+	}
+
+	start := end.Add(-lookback)
+	if baseline := detector.Baseline(hash); baseline != nil && !baseline.Last.IsZero() {
+		start = baseline.Last
+	}
+
+	if !start.Before(end) {
+		return nil, errorResponse
+	}
+
+	stats, errorResponse := detector.Client.GetStatByHashChunked(hash, models.StatRequest{DateStart: start, DateEnd: end}, tinysrc.StatChunkOptions{})
+	if stats == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return nil, errorResponse
+	}
+
+	events, e := detector.Observe(hash, Buckets(stats.Data, start, end, interval))
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+	}
+
+	return events, errorResponse
+}
+
+// Detect anomalies of points in time order and learn them.
+// Points not after the last observed bucket are skipped. Events are sent to Sink.
+func (detector *Detector) Observe(hash string, points []Point) ([]*Event, error) {
+	interval := detector.interval()
+	methods := detector.methods()
+
+	points = append([]Point(nil), points...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	detector.mutex.Lock()
+
+	baseline, ok := detector.baselines[hash]
+	if !ok {
+		baseline = &Baseline{}
+		detector.baselines[hash] = baseline
+	}
+
+	var events []*Event
+	for _, point := range points {
+		if point.Time.Before(baseline.Last) {
+			continue
+		}
+
+		for _, method := range methods {
+			expected, score, ok := method.Evaluate(baseline, point)
+			if ok && (point.Count >= detector.MinCount || expected >= detector.MinCount) && math.Abs(score) >= method.Limit() {
+				kind := KindSpike
+				if score < 0 {
+					kind = KindDrop
+				}
+
+				events = append(events, &Event{
+					Hash:      hash,
+					Method:    method.Name(),
+					Kind:      kind,
+					Time:      point.Time,
+					Interval:  interval,
+					Count:     point.Count,
+					Expected:  expected,
+					Score:     score,
+					Threshold: method.Limit(),
+				})
+			}
+
+			method.Update(baseline, point)
+		}
+
+		baseline.Last = point.Time.Add(interval)
+	}
+
+	e := detector.save()
+	detector.mutex.Unlock()
+
+	var errs []error
+	if e != nil {
+		errs = append(errs, e)
+	}
+
+	if detector.Sink != nil {
+		for _, event := range events {
+			if e := detector.Sink.Emit(event); e != nil {
+				errs = append(errs, e)
+			}
+		}
+	}
+
+	return events, errors.Join(errs...)
+}
+
+// Count rows by Created in buckets of [start, end), empty buckets are included with zero count
+func Buckets(stats []*models.StatResponse, start time.Time, end time.Time, interval time.Duration) []Point {
+	start = start.Truncate(interval)
+
+	var points []Point
+	for t := start; t.Before(end); t = t.Add(interval) {
+		points = append(points, Point{Time: t})
+	}
+
+	for _, stat := range stats {
+		if stat.Created.Before(start) || !stat.Created.Before(end) {
+			continue
+		}
+
+		index := int(stat.Created.Sub(start) / interval)
+		if index < len(points) {
+			points[index].Count++
+		}
+	}
+
+	return points
+}
+
+// Write baselines atomically, caller holds mutex
+func (detector *Detector) save() error {
+	if detector.path == "" {
+		return nil
+	}
+
+	data, e := json.MarshalIndent(detector.baselines, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	tmp, e := os.CreateTemp(filepath.Dir(detector.path), filepath.Base(detector.path)+".*")
+	if e != nil {
+		return e
+	}
+
+	if _, e = tmp.Write(data); e != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return e
+	}
+
+	if e = tmp.Close(); e != nil {
+		_ = os.Remove(tmp.Name())
+		return e
+	}
+
+	return os.Rename(tmp.Name(), detector.path)
+}
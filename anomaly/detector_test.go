@@ -0,0 +1,185 @@
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuckets(t *testing.T) {
+	start := time.Date(2022, 4, 1, 10, 30, 0, 0, time.UTC)
+	end := time.Date(2022, 4, 1, 13, 0, 0, 0, time.UTC)
+
+	stats := []*models.StatResponse{
+		{Created: time.Date(2022, 4, 1, 9, 59, 0, 0, time.UTC)},
+		{Created: time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)},
+		{Created: time.Date(2022, 4, 1, 10, 45, 0, 0, time.UTC)},
+		{Created: time.Date(2022, 4, 1, 12, 59, 0, 0, time.UTC)},
+		{Created: time.Date(2022, 4, 1, 13, 0, 0, 0, time.UTC)},
+	}
+
+	want := []Point{
+		{Time: time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC), Count: 2},
+		{Time: time.Date(2022, 4, 1, 11, 0, 0, 0, time.UTC), Count: 0},
+		{Time: time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC), Count: 1},
+	}
+
+	if got := Buckets(stats, start, end, time.Hour); !reflect.DeepEqual(got, want) {
+		t.Errorf("Buckets() = %v, want %v", got, want)
+	}
+}
+
+func TestDetector_Observe(t *testing.T) {
+	start := time.Date(2022, 4, 4, 0, 0, 0, 0, time.UTC)
+
+	var points []Point
+	for i := 0; i < 30; i++ {
+		points = append(points, Point{Time: start.Add(time.Duration(i) * time.Hour), Count: float64(10 + i%2)})
+	}
+	points = append(points, Point{Time: start.Add(30 * time.Hour), Count: 60})
+
+	path := filepath.Join(t.TempDir(), "baselines.json")
+	detector, e := New(nil, path)
+	if e != nil {
+		t.Fatalf("New() error = %v", e)
+	}
+
+	var emitted []*Event
+	detector.Methods = []Method{ZScore{}, EWMA{}}
+	detector.Sink = FuncSink(func(event *Event) error {
+		emitted = append(emitted, event)
+		return nil
+	})
+
+	events, e := detector.Observe("test", points)
+	if e != nil {
+		t.Fatalf("Observe() error = %v", e)
+	}
+
+	if len(events) != 2 || !reflect.DeepEqual(events, emitted) {
+		t.Fatalf("Observe() = %v, emitted %v", events, emitted)
+	}
+	for _, event := range events {
+		if event.Kind != KindSpike || event.Count != 60 || !event.Time.Equal(start.Add(30*time.Hour)) {
+			t.Errorf("Observe() event = %s", event)
+		}
+	}
+
+	// Baseline is reloaded, observed points are skipped
+	reloaded, e := New(nil, path)
+	if e != nil {
+		t.Fatalf("New() error = %v", e)
+	}
+
+	baseline := reloaded.Baseline("test")
+	if baseline == nil || !baseline.Last.Equal(start.Add(31*time.Hour)) || len(baseline.Recent) != DEFAULT_ZSCORE_WINDOW {
+		t.Fatalf("Baseline() = %+v", baseline)
+	}
+
+	reloaded.Methods = detector.Methods
+	reloaded.Sink = FuncSink(func(event *Event) error { return errors.New("sink is down") })
+
+	events, e = reloaded.Observe("test", points[len(points)-1:])
+	if len(events) != 0 || e != nil {
+		t.Errorf("Observe() of old points = %v, %v", events, e)
+	}
+
+	var recovered []Point
+	for i := 31; i < 55; i++ {
+		recovered = append(recovered, Point{Time: start.Add(time.Duration(i) * time.Hour), Count: 10})
+	}
+	recovered = append(recovered, Point{Time: start.Add(55 * time.Hour), Count: 0})
+
+	events, e = reloaded.Observe("test", recovered)
+	if len(events) != 2 || events[0].Kind != KindDrop || e == nil {
+		t.Errorf("Observe() = %v, %v", events, e)
+	}
+
+	if e = reloaded.Reset("test"); e != nil || reloaded.Baseline("test") != nil {
+		t.Errorf("Reset() error = %v", e)
+	}
+}
+
+func TestDetector_Check(t *testing.T) {
+	now := time.Date(2022, 4, 2, 12, 20, 0, 0, time.UTC)
+
+	// 5 clicks every hour, 40 clicks at 11:00
+	var rows []*models.StatResponse
+	for t := now.Add(-DEFAULT_LOOKBACK).Truncate(time.Hour); t.Before(now); t = t.Add(time.Hour) {
+		count := 5
+		if t.Hour() == 11 && t.Day() == now.Day() {
+			count = 40
+		}
+		for i := 0; i < count; i++ {
+			rows = append(rows, &models.StatResponse{Ip: "1.1.1.1", Created: t.Add(time.Duration(i) * time.Minute)})
+		}
+	}
+
+	var mu sync.Mutex
+	requests := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		query := r.URL.Query()
+		from, _ := time.Parse(tinysrc.DATE_FORMAT, query.Get("date-start"))
+		to, _ := time.Parse(tinysrc.DATE_FORMAT, query.Get("date-end"))
+
+		resp := models.StatPaginatedResponse{}
+		if query.Get("page") == "1" {
+			for _, row := range rows {
+				if !row.Created.Before(from) && !row.Created.After(to) {
+					resp.Data = append(resp.Data, row)
+				}
+			}
+		}
+		resp.Total = int64(len(resp.Data))
+
+		if !strings.HasSuffix(r.URL.Path, "/test") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	detector, _ := New(client, "")
+	detector.Methods = []Method{ZScore{}}
+
+	events, errorResponse := detector.Check("test", now)
+	if len(errorResponse.Errors) > 0 {
+		t.Fatalf("Check() errors = %v", errorResponse.Errors)
+	}
+
+	if len(events) != 1 || events[0].Count != 40 || events[0].Time.Hour() != 11 {
+		t.Fatalf("Check() = %v", events)
+	}
+
+	// Nothing new within the same hour
+	requests = 0
+	if events, _ = detector.Check("test", now.Add(30*time.Minute)); len(events) != 0 || requests != 0 {
+		t.Errorf("Check() = %v with %d requests", events, requests)
+	}
+
+	if _, errorResponse = detector.Check("missing", now); len(errorResponse.Errors) == 0 {
+		t.Errorf("Check() expected errors")
+	}
+}
@@ -0,0 +1,214 @@
+package anomaly
+
+import (
+	"math"
+	"time"
+)
+
+const DEFAULT_THRESHOLD = 3.0
+const DEFAULT_ZSCORE_WINDOW = 24
+const DEFAULT_EWMA_ALPHA = 0.3
+const DEFAULT_SEASONAL_SAMPLES = 3
+
+// Standard deviation is never lower, so flat series do not alert on one extra click
+const MIN_DEVIATION = 1.0
+
+// Hours in week, slots of seasonal baseline
+const HOURS_OF_WEEK = 7 * 24
+
+// Click count of one bucket
+type Point struct {
+	Time  time.Time `json:"time"`
+	Count float64   `json:"count"`
+}
+
+// Detection method. Evaluate compares point with baseline learned so far, then Update learns it.
+type Method interface {
+	Name() string
+	// Expected count and score in standard deviations, ok is false while baseline is warming up
+	Evaluate(baseline *Baseline, point Point) (expected float64, score float64, ok bool)
+	Update(baseline *Baseline, point Point)
+	// Alerting threshold of absolute score
+	Limit() float64
+}
+
+// Running mean and variance (Welford)
+type Moments struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+}
+
+func (moments *Moments) Add(value float64) {
+	moments.Count++
+	delta := value - moments.Mean
+	moments.Mean += delta / float64(moments.Count)
+	moments.M2 += delta * (value - moments.Mean)
+}
+
+// Sample standard deviation, at least MIN_DEVIATION
+func (moments *Moments) Deviation() float64 {
+	if moments.Count < 2 {
+		return MIN_DEVIATION
+	}
+
+	return math.Max(math.Sqrt(moments.M2/float64(moments.Count-1)), MIN_DEVIATION)
+}
+
+// Rolling z-score over last Window buckets
+type ZScore struct {
+	Window    int
+	Threshold float64
+}
+
+func (method ZScore) Name() string {
+	return "zscore"
+}
+
+func (method ZScore) window() int {
+	if method.Window <= 1 {
+		return DEFAULT_ZSCORE_WINDOW
+	}
+
+	return method.Window
+}
+
+func (method ZScore) Limit() float64 {
+	return threshold(method.Threshold)
+}
+
+func (method ZScore) Evaluate(baseline *Baseline, point Point) (float64, float64, bool) {
+	if len(baseline.Recent) < method.window() {
+		return 0, 0, false
+	}
+
+	var moments Moments
+	for _, value := range baseline.Recent {
+		moments.Add(value)
+	}
+
+	return moments.Mean, (point.Count - moments.Mean) / moments.Deviation(), true
+}
+
+func (method ZScore) Update(baseline *Baseline, point Point) {
+	baseline.Recent = append(baseline.Recent, point.Count)
+	if extra := len(baseline.Recent) - method.window(); extra > 0 {
+		baseline.Recent = append([]float64(nil), baseline.Recent[extra:]...)
+	}
+}
+
+// Exponentially weighted moving average and variance
+type EWMA struct {
+	// Weight of the newest bucket, 0..1
+	Alpha     float64
+	Threshold float64
+	// Buckets learned before alerting, DEFAULT_ZSCORE_WINDOW when zero
+	Warmup int
+}
+
+func (method EWMA) Name() string {
+	return "ewma"
+}
+
+func (method EWMA) Limit() float64 {
+	return threshold(method.Threshold)
+}
+
+func (method EWMA) alpha() float64 {
+	if method.Alpha <= 0 || method.Alpha > 1 {
+		return DEFAULT_EWMA_ALPHA
+	}
+
+	return method.Alpha
+}
+
+func (method EWMA) Evaluate(baseline *Baseline, point Point) (float64, float64, bool) {
+	warmup := method.Warmup
+	if warmup <= 0 {
+		warmup = DEFAULT_ZSCORE_WINDOW
+	}
+
+	if baseline.EWMA.Count < int64(warmup) {
+		return 0, 0, false
+	}
+
+	deviation := math.Max(math.Sqrt(baseline.EWMA.Variance), MIN_DEVIATION)
+	return baseline.EWMA.Mean, (point.Count - baseline.EWMA.Mean) / deviation, true
+}
+
+func (method EWMA) Update(baseline *Baseline, point Point) {
+	state := &baseline.EWMA
+	state.Count++
+
+	if state.Count == 1 {
+		state.Mean = point.Count
+		return
+	}
+
+	alpha := method.alpha()
+	delta := point.Count - state.Mean
+	increment := alpha * delta
+	state.Mean += increment
+	state.Variance = (1 - alpha) * (state.Variance + delta*increment)
+}
+
+// Baseline of every hour of week, catches drops at usually busy hours
+type Seasonal struct {
+	Threshold float64
+	// Samples of hour-of-week slot needed before alerting, i.e. weeks of history
+	Samples int
+	// Time zone of hours, UTC when nil
+	Location *time.Location
+}
+
+func (method Seasonal) Name() string {
+	return "seasonal"
+}
+
+func (method Seasonal) Limit() float64 {
+	return threshold(method.Threshold)
+}
+
+func (method Seasonal) slot(t time.Time) int {
+	if method.Location != nil {
+		t = t.In(method.Location)
+	} else {
+		t = t.UTC()
+	}
+
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+func (method Seasonal) Evaluate(baseline *Baseline, point Point) (float64, float64, bool) {
+	samples := method.Samples
+	if samples <= 0 {
+		samples = DEFAULT_SEASONAL_SAMPLES
+	}
+
+	if len(baseline.Seasonal) != HOURS_OF_WEEK {
+		return 0, 0, false
+	}
+
+	slot := baseline.Seasonal[method.slot(point.Time)]
+	if slot.Count < int64(samples) {
+		return 0, 0, false
+	}
+
+	return slot.Mean, (point.Count - slot.Mean) / slot.Deviation(), true
+}
+
+func (method Seasonal) Update(baseline *Baseline, point Point) {
+	if len(baseline.Seasonal) != HOURS_OF_WEEK {
+		baseline.Seasonal = make([]Moments, HOURS_OF_WEEK)
+	}
+
+	baseline.Seasonal[method.slot(point.Time)].Add(point.Count)
+}
+
+func threshold(value float64) float64 {
+	if value <= 0 {
+		return DEFAULT_THRESHOLD
+	}
+
+	return value
+}
@@ -0,0 +1,78 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMethods(t *testing.T) {
+	start := time.Date(2022, 4, 4, 0, 0, 0, 0, time.UTC)
+
+	// Every day: 10 clicks at night, 50 clicks at 12:00
+	daily := func(hour int) float64 {
+		if hour == 12 {
+			return 50
+		}
+		return 10 + float64(hour%3)
+	}
+
+	tests := []struct {
+		name      string
+		method    Method
+		history   int
+		value     float64
+		at        time.Duration
+		wantOk    bool
+		wantAlert bool
+	}{
+		{name: "test_zscore_warmup", method: ZScore{Window: 48}, history: 10, value: 100, wantOk: false},
+		{name: "test_zscore_spike", method: ZScore{Window: 48}, history: 96, value: 100, at: 3 * time.Hour, wantOk: true, wantAlert: true},
+		{name: "test_zscore_normal", method: ZScore{Window: 48}, history: 96, value: 11, at: 3 * time.Hour, wantOk: true},
+		{name: "test_ewma_spike", method: EWMA{Alpha: 0.1}, history: 96, value: 120, at: 3 * time.Hour, wantOk: true, wantAlert: true},
+		{name: "test_ewma_normal", method: EWMA{}, history: 96, value: 12, at: 3 * time.Hour, wantOk: true},
+		{name: "test_seasonal_warmup", method: Seasonal{}, history: 24 * 14, value: 0, at: 12 * time.Hour, wantOk: false},
+		// Usual click count at busy hour is a spike only for rolling baseline
+		{name: "test_seasonal_drop", method: Seasonal{Samples: 2}, history: 24 * 14, value: 10, at: 12 * time.Hour, wantOk: true, wantAlert: true},
+		{name: "test_seasonal_busy_hour", method: Seasonal{Samples: 2}, history: 24 * 14, value: 50, at: 12 * time.Hour, wantOk: true},
+		{name: "test_zscore_busy_hour", method: ZScore{Window: 24}, history: 24 * 14, value: 50, at: 12 * time.Hour, wantOk: true, wantAlert: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseline := &Baseline{}
+			for i := 0; i < tt.history; i++ {
+				tt.method.Update(baseline, Point{Time: start.Add(time.Duration(i) * time.Hour), Count: daily(i % 24)})
+			}
+
+			// Next day after history, shifted by at
+			at := start.Add(time.Duration((tt.history+23)/24*24) * time.Hour).Add(tt.at)
+			expected, score, ok := tt.method.Evaluate(baseline, Point{Time: at, Count: tt.value})
+			if ok != tt.wantOk {
+				t.Fatalf("Evaluate() ok = %v, want %v", ok, tt.wantOk)
+			}
+
+			if alert := ok && math.Abs(score) >= tt.method.Limit(); alert != tt.wantAlert {
+				t.Errorf("Evaluate() expected = %.2f, score = %.2f, want alert %v", expected, score, tt.wantAlert)
+			}
+		})
+	}
+}
+
+func TestMoments(t *testing.T) {
+	var moments Moments
+	for _, value := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		moments.Add(value)
+	}
+
+	if moments.Mean != 5 || math.Abs(moments.Deviation()-2.138) > 0.001 {
+		t.Errorf("Moments = %+v, deviation %v", moments, moments.Deviation())
+	}
+
+	flat := Moments{}
+	flat.Add(3)
+	flat.Add(3)
+	if flat.Deviation() != MIN_DEVIATION {
+		t.Errorf("Deviation() = %v, want %v", flat.Deviation(), MIN_DEVIATION)
+	}
+}
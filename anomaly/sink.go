@@ -0,0 +1,67 @@
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Receiver of events
+type Sink interface {
+	Emit(event *Event) error
+}
+
+// Write events with slog, slog.Default() when Logger is nil
+type LogSink struct {
+	Logger *slog.Logger
+}
+
+func (sink LogSink) Emit(event *Event) error {
+	logger := sink.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.LogAttrs(context.Background(), slog.LevelWarn, "click anomaly",
+		slog.String("hash", event.Hash),
+		slog.String("kind", string(event.Kind)),
+		slog.String("method", event.Method),
+		slog.Time("time", event.Time),
+		slog.Duration("interval", event.Interval),
+		slog.Float64("count", event.Count),
+		slog.Float64("expected", event.Expected),
+		slog.Float64("score", event.Score),
+	)
+
+	return nil
+}
+
+// Callback sink, e.g. to post events to a webhook
+type FuncSink func(event *Event) error
+
+func (sink FuncSink) Emit(event *Event) error {
+	return sink(event)
+}
+
+// Send events to channel, Emit blocks while channel is full
+type ChannelSink chan<- *Event
+
+func (sink ChannelSink) Emit(event *Event) error {
+	sink <- event
+	return nil
+}
+
+// Send events to every sink, all sinks are called even if one fails
+type MultiSink []Sink
+
+func (sinks MultiSink) Emit(event *Event) error {
+	var errs []error
+
+	for _, sink := range sinks {
+		if e := sink.Emit(event); e != nil {
+			errs = append(errs, e)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,347 @@
+// Package bots classifies clicks locally, in addition to the Bot flag of the
+// server, with crawler patterns, IP ranges, click bursts and missing
+// referer or user agent. Every decision is explained.
+//
+//	classifier := bots.NewClassifier()
+//	classifier.Ranges, e = bots.ParseRanges(file)
+//	results := classifier.Classify(statistic.Data)
+//	summary := bots.Summarize(results)
+//	fmt.Println(summary.Clicks, summary.HumanClicks)
+package bots
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Signal string
+
+const (
+	SignalServer         Signal = "server"
+	SignalCrawler        Signal = "crawler"
+	SignalIpRange        Signal = "ip_range"
+	SignalBurst          Signal = "burst"
+	SignalMissingReferer Signal = "missing_referer"
+	SignalMissingAgent   Signal = "missing_agent"
+)
+
+const DEFAULT_BURST_LIMIT = 10
+const DEFAULT_BURST_WINDOW = time.Minute
+const DEFAULT_MISSING_REFERER_WEIGHT = 0.3
+const DEFAULT_MISSING_AGENT_WEIGHT = 0.6
+const DEFAULT_THRESHOLD = 0.7
+
+// Crawler, preview and HTTP library patterns matched against browser, os and platform
+var DefaultPatterns = []string{
+	`bot\b`, `crawl`, `spider`, `slurp`, `facebookexternalhit`, `facebookcatalog`, `preview`, `headless`,
+	`phantomjs`, `lighthouse`, `pingdom`, `uptimerobot`, `monitor`, `curl`, `wget`, `python`, `go-http-client`,
+	`okhttp`, `java/`, `httpclient`, `axios`, `node-fetch`, `scrapy`, `libwww`, `embedly`, `whatsapp`, `skypeuripreview`,
+}
+
+// Signal which contributed to decision
+type Reason struct {
+	Signal Signal `json:"signal"`
+	// Weight of signal, 1 for signals which are enough alone
+	Weight  float64 `json:"weight"`
+	Details string  `json:"details"`
+}
+
+type Result struct {
+	Stat *models.StatResponse `json:"stat"`
+	Bot  bool                 `json:"bot"`
+	// Sum of weights of reasons
+	Score   float64   `json:"score"`
+	Reasons []*Reason `json:"reasons,omitempty"`
+}
+
+type Classifier struct {
+	// Case-insensitive patterns of crawler agents
+	Patterns []*regexp.Regexp
+	// Known bot networks, e.g. published ranges of crawlers and data centers
+	Ranges []netip.Prefix
+	// More than BurstLimit clicks of one IP within BurstWindow are bots, zero disables
+	BurstLimit  int
+	BurstWindow time.Duration
+	// Weights of weak signals
+	MissingRefererWeight float64
+	MissingAgentWeight   float64
+	// Minimal score of bots
+	Threshold float64
+	// Keep server decision, otherwise Bot flag of rows is ignored
+	TrustServer bool
+}
+
+// Classifier with default patterns and weights
+func NewClassifier() *Classifier {
+	patterns, _ := CompilePatterns(DefaultPatterns)
+
+	return &Classifier{
+		Patterns:             patterns,
+		BurstLimit:           DEFAULT_BURST_LIMIT,
+		BurstWindow:          DEFAULT_BURST_WINDOW,
+		MissingRefererWeight: DEFAULT_MISSING_REFERER_WEIGHT,
+		MissingAgentWeight:   DEFAULT_MISSING_AGENT_WEIGHT,
+		Threshold:            DEFAULT_THRESHOLD,
+		TrustServer:          true,
+	}
+}
+
+// Compile case-insensitive patterns
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		compiled, e := regexp.Compile("(?i)" + pattern)
+		if e != nil {
+			return nil, fmt.Errorf("bots: pattern %q: %w", pattern, e)
+		}
+		result = append(result, compiled)
+	}
+
+	return result, nil
+}
+
+// Read IP ranges, one CIDR or address per line; empty lines and "#" comments are skipped
+func ParseRanges(r io.Reader) ([]netip.Prefix, error) {
+	var ranges []netip.Prefix
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		if !strings.Contains(text, "/") {
+			addr, e := netip.ParseAddr(text)
+			if e != nil {
+				return nil, fmt.Errorf("bots: line %d: %w", line, e)
+			}
+			ranges = append(ranges, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, e := netip.ParsePrefix(text)
+		if e != nil {
+			return nil, fmt.Errorf("bots: line %d: %w", line, e)
+		}
+		ranges = append(ranges, prefix.Masked())
+	}
+
+	return ranges, scanner.Err()
+}
+
+// Classify rows, rows are not modified
+func (classifier *Classifier) Classify(stats []*models.StatResponse) []*Result {
+	results := make([]*Result, len(stats))
+	for i, stat := range stats {
+		results[i] = &Result{Stat: stat}
+	}
+
+	classifier.detectBursts(results)
+
+	for _, result := range results {
+		classifier.classify(result)
+
+		for _, reason := range result.Reasons {
+			result.Score += reason.Weight
+		}
+		result.Bot = result.Score >= classifier.Threshold
+	}
+
+	return results
+}
+
+func (classifier *Classifier) classify(result *Result) {
+	stat := result.Stat
+	add := func(signal Signal, weight float64, details string) {
+		result.Reasons = append(result.Reasons, &Reason{Signal: signal, Weight: weight, Details: details})
+	}
+
+	if classifier.TrustServer && stat.Bot {
+		add(SignalServer, 1, "flagged by server")
+	}
+
+	agent := strings.Join(strings.Fields(strings.Join([]string{stat.Browser, stat.BrowserVersion, stat.Os, stat.Platform}, " ")), " ")
+	for _, pattern := range classifier.Patterns {
+		if pattern.MatchString(agent) {
+			add(SignalCrawler, 1, fmt.Sprintf("agent %q matches %s", agent, strings.TrimPrefix(pattern.String(), "(?i)")))
+			break
+		}
+	}
+
+	if addr, e := netip.ParseAddr(strings.TrimSpace(stat.Ip)); e == nil {
+		addr = addr.Unmap()
+		for _, prefix := range classifier.Ranges {
+			if prefix.Contains(addr) {
+				add(SignalIpRange, 1, fmt.Sprintf("ip %s is in %s", addr, prefix))
+				break
+			}
+		}
+	}
+
+	if strings.TrimSpace(stat.Referer) == "" && classifier.MissingRefererWeight > 0 {
+		add(SignalMissingReferer, classifier.MissingRefererWeight, "no referer")
+	}
+
+	if isMissing(stat.Browser) && isMissing(stat.Os) && isMissing(stat.Platform) && classifier.MissingAgentWeight > 0 {
+		add(SignalMissingAgent, classifier.MissingAgentWeight, "no browser, os and platform")
+	}
+}
+
+func isMissing(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.EqualFold(value, "unknown")
+}
+
+// Mark rows of IPs with more than BurstLimit clicks within BurstWindow
+func (classifier *Classifier) detectBursts(results []*Result) {
+	if classifier.BurstLimit <= 0 {
+		return
+	}
+
+	window := classifier.BurstWindow
+	if window <= 0 {
+		window = DEFAULT_BURST_WINDOW
+	}
+
+	byIp := make(map[string][]*Result)
+	for _, result := range results {
+		if ip := strings.TrimSpace(result.Stat.Ip); ip != "" {
+			byIp[ip] = append(byIp[ip], result)
+		}
+	}
+
+	for ip, clicks := range byIp {
+		if len(clicks) <= classifier.BurstLimit {
+			continue
+		}
+
+		sort.SliceStable(clicks, func(i, j int) bool { return clicks[i].Stat.Created.Before(clicks[j].Stat.Created) })
+
+		burst := make([]bool, len(clicks))
+		peak := make([]int, len(clicks))
+		for start, end := 0, 0; end < len(clicks); end++ {
+			for clicks[end].Stat.Created.Sub(clicks[start].Stat.Created) >= window {
+				start++
+			}
+
+			if count := end - start + 1; count > classifier.BurstLimit {
+				for i := start; i <= end; i++ {
+					burst[i] = true
+					if count > peak[i] {
+						peak[i] = count
+					}
+				}
+			}
+		}
+
+		for i, result := range clicks {
+			if burst[i] {
+				result.Reasons = append(result.Reasons, &Reason{
+					Signal:  SignalBurst,
+					Weight:  1,
+					Details: fmt.Sprintf("%d clicks of ip %s within %s", peak[i], ip, window),
+				})
+			}
+		}
+	}
+}
+
+// Copies of rows with Bot flag set by classifier
+func Relabel(results []*Result) []*models.StatResponse {
+	stats := make([]*models.StatResponse, len(results))
+
+	for i, result := range results {
+		stat := *result.Stat
+		stat.Bot = result.Bot
+		stats[i] = &stat
+	}
+
+	return stats
+}
+
+type Summary struct {
+	Clicks      int64 `json:"clicks"`
+	HumanClicks int64 `json:"human_clicks"`
+	Bots        int64 `json:"bots"`
+	// Bots according to server
+	ServerBots int64 `json:"server_bots"`
+	// Rows which are bots locally but humans according to server
+	Relabeled int64 `json:"relabeled"`
+	// Bot rows by signal, one row can have several signals
+	Signals map[Signal]int64 `json:"signals"`
+}
+
+// Count clicks and human clicks of results
+func Summarize(results []*Result) *Summary {
+	summary := &Summary{Clicks: int64(len(results)), Signals: make(map[Signal]int64)}
+
+	for _, result := range results {
+		if result.Stat.Bot {
+			summary.ServerBots++
+		}
+
+		if !result.Bot {
+			summary.HumanClicks++
+			continue
+		}
+
+		summary.Bots++
+		if !result.Stat.Bot {
+			summary.Relabeled++
+		}
+		for _, reason := range result.Reasons {
+			summary.Signals[reason.Signal]++
+		}
+	}
+
+	return summary
+}
+
+// Human readable summary
+func (summary *Summary) String() string {
+	var builder strings.Builder
+
+	_, _ = fmt.Fprintf(&builder, "%d clicks, %d human clicks, %d bots (%d by server, %d relabeled)\n",
+		summary.Clicks, summary.HumanClicks, summary.Bots, summary.ServerBots, summary.Relabeled)
+
+	signals := make([]string, 0, len(summary.Signals))
+	for signal := range summary.Signals {
+		signals = append(signals, string(signal))
+	}
+	sort.Strings(signals)
+
+	for _, signal := range signals {
+		_, _ = fmt.Fprintf(&builder, "  %-16s %d\n", signal, summary.Signals[Signal(signal)])
+	}
+
+	return builder.String()
+}
+
+// Explanation of decision, e.g. "bot (1.30): crawler: agent ...; missing_referer: no referer"
+func (result *Result) Explain() string {
+	label := "human"
+	if result.Bot {
+		label = "bot"
+	}
+
+	if len(result.Reasons) == 0 {
+		return label + " (0.00): no signals"
+	}
+
+	reasons := make([]string, len(result.Reasons))
+	for i, reason := range result.Reasons {
+		reasons[i] = string(reason.Signal) + ": " + reason.Details
+	}
+
+	return fmt.Sprintf("%s (%.2f): %s", label, result.Score, strings.Join(reasons, "; "))
+}
@@ -0,0 +1,157 @@
+package bots
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{
+			name:  "test_success",
+			input: "# crawlers\n66.249.64.0/19\n\n 40.77.167.5  # single address\n2001:4860:4801::/48\n::ffff:10.0.0.1\n10.1.2.3/16\n",
+			want: []netip.Prefix{
+				netip.MustParsePrefix("66.249.64.0/19"),
+				netip.MustParsePrefix("40.77.167.5/32"),
+				netip.MustParsePrefix("2001:4860:4801::/48"),
+				netip.MustParsePrefix("10.0.0.1/32"),
+				netip.MustParsePrefix("10.1.0.0/16"),
+			},
+		},
+		{
+			name:    "test_invalid",
+			input:   "66.249.64.0/19\nnot-an-ip\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, e := ParseRanges(strings.NewReader(tt.input))
+			if (e != nil) != tt.wantErr {
+				t.Fatalf("ParseRanges() error = %v, wantErr %v", e, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifier_Classify(t *testing.T) {
+	now := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	human := func(ip string, offset time.Duration) *models.StatResponse {
+		return &models.StatResponse{Ip: ip, Browser: "Chrome", BrowserVersion: "99.0", Os: "Windows", Referer: "https://test.com", Created: now.Add(offset)}
+	}
+
+	var stats []*models.StatResponse
+	stats = append(stats,
+		human("1.1.1.1", 0),
+		&models.StatResponse{Ip: "1.1.1.2", Browser: "Googlebot", BrowserVersion: "2.1", Created: now},
+		&models.StatResponse{Ip: "66.249.66.1", Browser: "Chrome", Os: "Linux", Referer: "https://test.com", Created: now},
+		&models.StatResponse{Ip: "1.1.1.3", Browser: "Chrome", Os: "Android", Created: now},
+		&models.StatResponse{Ip: "1.1.1.4", Created: now},
+		&models.StatResponse{Ip: "1.1.1.5", Browser: "Safari", Os: "iOS", Referer: "https://test.com", Bot: true, Created: now},
+	)
+
+	// 4 clicks within a minute are burst, clicks a minute apart are not
+	for i := 0; i < 4; i++ {
+		stats = append(stats, human("2.2.2.2", time.Duration(i)*10*time.Second))
+	}
+	for i := 0; i < 4; i++ {
+		stats = append(stats, human("3.3.3.3", time.Duration(i)*time.Minute))
+	}
+
+	classifier := NewClassifier()
+	classifier.BurstLimit = 3
+	classifier.Ranges = []netip.Prefix{netip.MustParsePrefix("66.249.64.0/19")}
+
+	results := classifier.Classify(stats)
+
+	tests := []struct {
+		name        string
+		index       int
+		wantBot     bool
+		wantSignals []Signal
+	}{
+		{name: "test_human", index: 0, wantBot: false},
+		{name: "test_crawler", index: 1, wantBot: true, wantSignals: []Signal{SignalCrawler, SignalMissingReferer}},
+		{name: "test_ip_range", index: 2, wantBot: true, wantSignals: []Signal{SignalIpRange}},
+		{name: "test_missing_referer_only", index: 3, wantBot: false, wantSignals: []Signal{SignalMissingReferer}},
+		{name: "test_missing_referer_and_agent", index: 4, wantBot: true, wantSignals: []Signal{SignalMissingReferer, SignalMissingAgent}},
+		{name: "test_server", index: 5, wantBot: true, wantSignals: []Signal{SignalServer}},
+		{name: "test_burst", index: 7, wantBot: true, wantSignals: []Signal{SignalBurst}},
+		{name: "test_no_burst", index: 12, wantBot: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := results[tt.index]
+			if result.Stat != stats[tt.index] {
+				t.Fatalf("Classify() changed order of rows")
+			}
+
+			var signals []Signal
+			for _, reason := range result.Reasons {
+				signals = append(signals, reason.Signal)
+			}
+
+			if result.Bot != tt.wantBot || !reflect.DeepEqual(signals, tt.wantSignals) {
+				t.Errorf("Classify() = %s, want bot %v with %v", result.Explain(), tt.wantBot, tt.wantSignals)
+			}
+		})
+	}
+
+	if explanation := results[7].Explain(); explanation != "bot (1.00): burst: 4 clicks of ip 2.2.2.2 within 1m0s" {
+		t.Errorf("Explain() = %s", explanation)
+	}
+	if explanation := results[0].Explain(); explanation != "human (0.00): no signals" {
+		t.Errorf("Explain() = %s", explanation)
+	}
+
+	classifier.TrustServer = false
+	if results := classifier.Classify(stats[5:6]); results[0].Bot {
+		t.Errorf("Classify() without TrustServer = %s", results[0].Explain())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stats := []*models.StatResponse{
+		{Ip: "1.1.1.1", Browser: "Chrome", Os: "Windows", Referer: "https://test.com"},
+		{Ip: "1.1.1.2", Browser: "curl", BrowserVersion: "7.79", Referer: "https://test.com"},
+		{Ip: "1.1.1.3", Browser: "bingbot", Bot: true},
+	}
+
+	results := NewClassifier().Classify(stats)
+	summary := Summarize(results)
+
+	want := &Summary{
+		Clicks:      3,
+		HumanClicks: 1,
+		Bots:        2,
+		ServerBots:  1,
+		Relabeled:   1,
+		Signals:     map[Signal]int64{SignalCrawler: 2, SignalServer: 1, SignalMissingReferer: 1},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("Summarize() = %+v, want %+v", summary, want)
+	}
+
+	if got := summary.String(); !strings.HasPrefix(got, "3 clicks, 1 human clicks, 2 bots (1 by server, 1 relabeled)\n  crawler") {
+		t.Errorf("String() = %s", got)
+	}
+
+	relabeled := Relabel(results)
+	if !relabeled[1].Bot || stats[1].Bot || relabeled[0].Bot {
+		t.Errorf("Relabel() = %+v", relabeled)
+	}
+}
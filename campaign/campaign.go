@@ -0,0 +1,238 @@
+// Package campaign builds destination URLs with utm_* tracking parameters
+// for CreateShortLink and extracts them back for reporting.
+package campaign
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const PARAM_SOURCE = "utm_source"
+const PARAM_MEDIUM = "utm_medium"
+const PARAM_CAMPAIGN = "utm_campaign"
+const PARAM_TERM = "utm_term"
+const PARAM_CONTENT = "utm_content"
+
+// Max length of normalized value
+const MAX_VALUE_LENGTH = 100
+
+// Campaign tracking fields
+type Campaign struct {
+	Source  string `json:"source"`
+	Medium  string `json:"medium"`
+	Name    string `json:"name"`
+	Term    string `json:"term,omitempty"`
+	Content string `json:"content,omitempty"`
+	// Custom query parameters
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Builder of tracked destination URL
+type Builder struct {
+	Url string
+	Campaign
+}
+
+// Build destination URL, utm_* parameters of Url are replaced whatever their case is, others are kept in order
+func (builder *Builder) Build() (string, error) {
+	base, e := url.Parse(strings.TrimSpace(builder.Url))
+	if e != nil {
+		return "", e
+	}
+
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return "", fmt.Errorf("campaign: url %q must be absolute http(s) url", builder.Url)
+	}
+
+	params, e := builder.params()
+	if e != nil {
+		return "", e
+	}
+
+	replaced := make(map[string]bool, len(params))
+	for _, param := range params {
+		replaced[replacedName(param[0])] = true
+	}
+
+	var pairs []string
+	seen := make(map[string]bool)
+
+	for _, pair := range strings.Split(base.RawQuery, "&") {
+		if pair == "" || seen[pair] {
+			continue
+		}
+
+		key, _, _ := strings.Cut(pair, "=")
+		if name, e := url.QueryUnescape(key); e == nil && replaced[replacedName(name)] {
+			continue
+		}
+
+		seen[pair] = true
+		pairs = append(pairs, pair)
+	}
+
+	for _, param := range params {
+		pairs = append(pairs, url.QueryEscape(param[0])+"="+url.QueryEscape(param[1]))
+	}
+
+	base.RawQuery = strings.Join(pairs, "&")
+	base.ForceQuery = false
+
+	return base.String(), nil
+}
+
+// Build request of tracked short link
+func (builder *Builder) LinkRequest() (models.LinkRequest, error) {
+	destination, e := builder.Build()
+	if e != nil {
+		return models.LinkRequest{}, e
+	}
+
+	return models.LinkRequest{Url: destination}, nil
+}
+
+// Normalized and validated parameters in stable order
+func (builder *Builder) params() ([][2]string, error) {
+	var params [][2]string
+
+	fields := []struct {
+		name     string
+		value    string
+		required bool
+	}{
+		{PARAM_SOURCE, builder.Source, true},
+		{PARAM_MEDIUM, builder.Medium, true},
+		{PARAM_CAMPAIGN, builder.Name, true},
+		{PARAM_TERM, builder.Term, false},
+		{PARAM_CONTENT, builder.Content, false},
+	}
+
+	var errs []error
+
+	for _, field := range fields {
+		value := Normalize(field.value)
+		if value == "" {
+			if field.required {
+				errs = append(errs, fmt.Errorf("campaign: %s is required", field.name))
+			}
+			continue
+		}
+
+		if e := validate(field.name, value); e != nil {
+			errs = append(errs, e)
+			continue
+		}
+
+		params = append(params, [2]string{field.name, value})
+	}
+
+	keys := make([]string, 0, len(builder.Params))
+	for key := range builder.Params {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+
+	for _, key := range keys {
+		// Name is kept as given, case is ignored only when it is compared with utm_ parameters
+		name := strings.TrimSpace(key)
+		lower := strings.ToLower(name)
+		switch lower {
+		case "":
+			errs = append(errs, errors.New("campaign: custom parameter name is empty"))
+			continue
+		case PARAM_SOURCE, PARAM_MEDIUM, PARAM_CAMPAIGN, PARAM_TERM, PARAM_CONTENT:
+			errs = append(errs, fmt.Errorf("campaign: custom parameter %s overrides campaign field", name))
+			continue
+		}
+
+		value := strings.TrimSpace(builder.Params[key])
+		if strings.HasPrefix(lower, "utm_") {
+			value = Normalize(value)
+		}
+
+		if e := validate(name, value); e != nil {
+			errs = append(errs, e)
+			continue
+		}
+
+		params = append(params, [2]string{name, value})
+	}
+
+	return params, errors.Join(errs...)
+}
+
+// Name of query parameter compared when parameters of Url are replaced, utm_ parameters ignore case
+func replacedName(name string) string {
+	if lower := strings.ToLower(name); strings.HasPrefix(lower, "utm_") {
+		return lower
+	}
+
+	return name
+}
+
+// Normalize tracking value: trimmed, lower case, whitespace replaced by underscore
+func Normalize(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), "_")
+}
+
+func validate(name string, value string) error {
+	if len(value) > MAX_VALUE_LENGTH {
+		return fmt.Errorf("campaign: %s is longer than %d characters", name, MAX_VALUE_LENGTH)
+	}
+
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("campaign: %s contains control characters", name)
+		}
+	}
+
+	return nil
+}
+
+// Extract campaign fields from tracked URL, other query parameters are returned in Params
+func Parse(rawURL string) (*Campaign, error) {
+	parsed, e := url.Parse(rawURL)
+	if e != nil {
+		return nil, e
+	}
+
+	query := parsed.Query()
+	campaign := &Campaign{
+		Source:  query.Get(PARAM_SOURCE),
+		Medium:  query.Get(PARAM_MEDIUM),
+		Name:    query.Get(PARAM_CAMPAIGN),
+		Term:    query.Get(PARAM_TERM),
+		Content: query.Get(PARAM_CONTENT),
+	}
+
+	for key, values := range query {
+		switch key {
+		case PARAM_SOURCE, PARAM_MEDIUM, PARAM_CAMPAIGN, PARAM_TERM, PARAM_CONTENT:
+			continue
+		}
+
+		if campaign.Params == nil {
+			campaign.Params = make(map[string]string)
+		}
+		campaign.Params[key] = values[0]
+	}
+
+	return campaign, nil
+}
+
+// Extract campaign fields from link destination
+func FromLink(link *models.LinkUserResponse) (*Campaign, error) {
+	return Parse(link.Url)
+}
+
+// Check if URL has any campaign field
+func (campaign *Campaign) Tracked() bool {
+	return campaign.Source != "" || campaign.Medium != "" || campaign.Name != "" || campaign.Term != "" || campaign.Content != ""
+}
@@ -0,0 +1,141 @@
+package campaign
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder Builder
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "test_success",
+			builder: Builder{
+				Url:      "https://test.com/page",
+				Campaign: Campaign{Source: " Newsletter ", Medium: "Email", Name: "Spring Sale  2022"},
+			},
+			want: "https://test.com/page?utm_source=newsletter&utm_medium=email&utm_campaign=spring_sale_2022",
+		},
+		{
+			name: "test_merge_existing_query",
+			builder: Builder{
+				Url: "https://test.com/page?b=2&utm_source=old&a=1&b=2#top",
+				Campaign: Campaign{
+					Source:  "google",
+					Medium:  "cpc",
+					Name:    "brand",
+					Term:    "Running Shoes",
+					Content: "logo link",
+					Params:  map[string]string{"ref": "Partner A", "UTM_ID": "Abc 1"},
+				},
+			},
+			want: "https://test.com/page?b=2&a=1&utm_source=google&utm_medium=cpc&utm_campaign=brand&utm_term=running_shoes&utm_content=logo_link&ref=Partner+A&UTM_ID=abc_1#top",
+		},
+		{
+			name: "test_replace_upper_case_utm",
+			builder: Builder{
+				Url:      "https://test.com/page?UTM_SOURCE=old&Utm_Id=1&Ref=a",
+				Campaign: Campaign{Source: "google", Medium: "cpc", Name: "brand", Params: map[string]string{"utm_id": "2", "ref": "b"}},
+			},
+			want: "https://test.com/page?Ref=a&utm_source=google&utm_medium=cpc&utm_campaign=brand&ref=b&utm_id=2",
+		},
+		{
+			name:    "test_required_fields",
+			builder: Builder{Url: "https://test.com", Campaign: Campaign{Source: "google"}},
+			wantErr: true,
+		},
+		{
+			name:    "test_relative_url",
+			builder: Builder{Url: "/page", Campaign: Campaign{Source: "a", Medium: "b", Name: "c"}},
+			wantErr: true,
+		},
+		{
+			name: "test_custom_overrides_field",
+			builder: Builder{
+				Url:      "https://test.com",
+				Campaign: Campaign{Source: "a", Medium: "b", Name: "c", Params: map[string]string{"UTM_Source": "d"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test_control_characters",
+			builder: Builder{
+				Url:      "https://test.com",
+				Campaign: Campaign{Source: "a", Medium: "b", Name: "c", Params: map[string]string{"ref": "a\x00b"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_LinkRequest(t *testing.T) {
+	builder := Builder{Url: "https://test.com", Campaign: Campaign{Source: "a", Medium: "b", Name: "c"}}
+
+	got, err := builder.LinkRequest()
+	if err != nil {
+		t.Fatalf("LinkRequest() error = %v", err)
+	}
+
+	want := models.LinkRequest{Url: "https://test.com?utm_source=a&utm_medium=b&utm_campaign=c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkRequest() = %v, want %v", got, want)
+	}
+}
+
+func TestFromLink(t *testing.T) {
+	tests := []struct {
+		name        string
+		link        *models.LinkUserResponse
+		want        *Campaign
+		wantTracked bool
+	}{
+		{
+			name: "test_tracked",
+			link: &models.LinkUserResponse{Url: "https://test.com/?utm_source=google&utm_medium=cpc&utm_campaign=brand&utm_term=shoes&ref=a"},
+			want: &Campaign{
+				Source: "google",
+				Medium: "cpc",
+				Name:   "brand",
+				Term:   "shoes",
+				Params: map[string]string{"ref": "a"},
+			},
+			wantTracked: true,
+		},
+		{
+			name:        "test_not_tracked",
+			link:        &models.LinkUserResponse{Url: "https://test.com/"},
+			want:        &Campaign{},
+			wantTracked: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromLink(tt.link)
+			if err != nil {
+				t.Fatalf("FromLink() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromLink() = %v, want %v", got, tt.want)
+			}
+			if got.Tracked() != tt.wantTracked {
+				t.Errorf("Tracked() = %v, want %v", got.Tracked(), tt.wantTracked)
+			}
+		})
+	}
+}
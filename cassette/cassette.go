@@ -0,0 +1,340 @@
+// Package cassette records HTTP interactions of the TinySRC client into JSON
+// files and replays them later, so tests run without network and API keys.
+//
+//	recorder, e := cassette.New("testdata/create.json", cassette.ModeReplay, nil)
+//	client, e := tinysrc.NewClient(ctx, "test", &http.Client{Transport: recorder})
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type Mode int
+
+const (
+	// Serve recorded interactions, never touch the network
+	ModeReplay Mode = iota
+	// Send requests through the real transport and record them
+	ModeRecord
+)
+
+// Headers which are never written to cassette files
+var scrubbedHeaders = []string{"x-api-key", "Authorization", "Cookie", "Set-Cookie"}
+
+// JSON fields which are never written to cassette files
+var scrubbedFields = map[string]bool{
+	"api_key":       true,
+	"password":      true,
+	"stat_password": true,
+}
+
+type Request struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Recorder is http.RoundTripper which records or replays interactions
+type Recorder struct {
+	// Replay interaction again when every matching interaction was already replayed,
+	// otherwise such request fails
+	AllowReuse bool
+
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+
+	mu        sync.Mutex
+	cassette  Cassette
+	replayed  []bool
+	unmatched []string
+}
+
+// Constructor of Recorder, cassette file is loaded in replay mode.
+// Transport is used in record mode only, http.DefaultTransport when nil.
+func New(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	recorder := &Recorder{mode: mode, path: path, transport: transport}
+
+	if mode == ModeReplay {
+		data, e := os.ReadFile(path)
+		if e != nil {
+			return nil, e
+		}
+
+		if e = json.Unmarshal(data, &recorder.cassette); e != nil {
+			return nil, fmt.Errorf("cassette: %s: %w", path, e)
+		}
+
+		recorder.replayed = make([]bool, len(recorder.cassette.Interactions))
+	}
+
+	return recorder, nil
+}
+
+// Send Request according to mode
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, e := readRequestBody(req)
+	if e != nil {
+		return nil, e
+	}
+
+	if recorder.mode == ModeRecord {
+		return recorder.record(req, body)
+	}
+
+	return recorder.replay(req, body)
+}
+
+// Requests which did not match any recorded interaction not replayed yet in replay mode
+func (recorder *Recorder) Unmatched() []string {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return append([]string(nil), recorder.unmatched...)
+}
+
+// Write recorded interactions to cassette file
+func (recorder *Recorder) Save() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return recorder.save()
+}
+
+func (recorder *Recorder) save() error {
+	data, e := json.MarshalIndent(&recorder.cassette, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	if e = os.MkdirAll(filepath.Dir(recorder.path), 0o755); e != nil {
+		return e
+	}
+
+	return os.WriteFile(recorder.path, data, 0o644)
+}
+
+// Request of caller is not changed, its clone with buffered body is sent
+func (recorder *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	outgoing := req.Clone(req.Context())
+	if body != nil {
+		outgoing.Body = io.NopCloser(bytes.NewReader(body))
+		outgoing.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, e := recorder.transport.RoundTrip(outgoing)
+	if e != nil {
+		return nil, e
+	}
+	resp.Request = req
+
+	respBody, e := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if e != nil {
+		return nil, e
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{
+		Request: Request{
+			Method:  req.Method,
+			Path:    req.URL.Path,
+			Query:   req.URL.Query().Encode(),
+			Headers: scrubHeaders(req.Header),
+			Body:    string(scrubBody(body)),
+		},
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: scrubHeaders(resp.Header),
+			Body:    string(scrubBody(respBody)),
+		},
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, interaction)
+	if e = recorder.save(); e != nil {
+		return nil, e
+	}
+
+	return resp, nil
+}
+
+// Find recorded interaction which was not replayed yet, already replayed one only with AllowReuse
+func (recorder *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	found, reused := -1, -1
+	for i, interaction := range recorder.cassette.Interactions {
+		if !matches(&interaction.Request, req, body) {
+			continue
+		}
+		if !recorder.replayed[i] {
+			found = i
+			break
+		}
+		if reused < 0 {
+			reused = i
+		}
+	}
+
+	if found < 0 && recorder.AllowReuse {
+		found = reused
+	}
+
+	if found < 0 {
+		description := req.Method + " " + req.URL.RequestURI()
+		recorder.unmatched = append(recorder.unmatched, description)
+		if reused >= 0 {
+			return nil, fmt.Errorf("cassette: every interaction matching %s in %s was already replayed", description, recorder.path)
+		}
+		return nil, fmt.Errorf("cassette: no recorded interaction matches %s in %s", description, recorder.path)
+	}
+
+	recorder.replayed[found] = true
+	recorded := recorder.cassette.Interactions[found].Response
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Match on method, path, query and body
+func matches(recorded *Request, req *http.Request, body []byte) bool {
+	if recorded.Method != req.Method || recorded.Path != req.URL.Path {
+		return false
+	}
+
+	query, e := url.ParseQuery(recorded.Query)
+	if e != nil || query.Encode() != req.URL.Query().Encode() {
+		return false
+	}
+
+	return normalizeBody([]byte(recorded.Body)) == normalizeBody(scrubBody(body))
+}
+
+// Body of request is read from GetBody when possible, req is never modified
+// and its body is closed as http.RoundTripper requires
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	reader := req.Body
+	if req.GetBody != nil {
+		copied, e := req.GetBody()
+		if e != nil {
+			return nil, e
+		}
+		defer copied.Close()
+		reader = copied
+	}
+
+	return io.ReadAll(reader)
+}
+
+func scrubHeaders(header http.Header) http.Header {
+	scrubbed := header.Clone()
+
+	for _, name := range scrubbedHeaders {
+		if scrubbed.Get(name) != "" {
+			scrubbed.Set(name, tinysrc.REDACTED)
+		}
+	}
+
+	return scrubbed
+}
+
+func scrubBody(body []byte) []byte {
+	var value interface{}
+
+	if len(body) == 0 || json.Unmarshal(body, &value) != nil {
+		return body
+	}
+
+	scrubbed, e := json.Marshal(scrubValue(value))
+	if e != nil {
+		return body
+	}
+
+	return scrubbed
+}
+
+func scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if scrubbedFields[key] {
+				// Empty secret is kept, so replayed link has no password as recorded one
+				if item != nil && item != "" {
+					v[key] = tinysrc.REDACTED
+				}
+				continue
+			}
+			v[key] = scrubValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubValue(item)
+		}
+	}
+
+	return value
+}
+
+// JSON bodies are compared regardless of formatting and key order
+func normalizeBody(body []byte) string {
+	var value interface{}
+
+	if json.Unmarshal(body, &value) != nil {
+		return string(body)
+	}
+
+	normalized, _ := json.Marshal(value)
+	return string(normalized)
+}
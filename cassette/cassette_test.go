@@ -0,0 +1,220 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_RecordReplay(t *testing.T) {
+	successUser := models.CurrentUserResponse{
+		Username: "test",
+		ApiKey:   "secret-key",
+		Email:    "test@test.com",
+		Active:   1,
+	}
+
+	successUserJson, _ := json.Marshal(&successUser)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, string(successUserJson))
+	}))
+
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "user.json")
+
+	recorder, e := New(path, ModeRecord, nil)
+	if e != nil {
+		t.Fatalf("New() error = %v", e)
+	}
+
+	recordClient, _ := tinysrc.NewClient(context.Background(), "secret-key", &http.Client{Transport: recorder})
+	_ = recordClient.SetBaseURL(ts.URL + "/" + tinysrc.VERSION)
+
+	gotR, gotErrorResponse := recordClient.GetCurrentUser()
+	if len(gotErrorResponse.Errors) > 0 || !reflect.DeepEqual(gotR, &successUser) {
+		t.Fatalf("GetCurrentUser() gotR = %v, gotErrorResponse = %v", gotR, gotErrorResponse)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret-key") {
+		t.Errorf("cassette contains API key:\n%s", data)
+	}
+
+	ts.Close()
+
+	replayer, e := New(path, ModeReplay, nil)
+	if e != nil {
+		t.Fatalf("New() error = %v", e)
+	}
+
+	replayClient, _ := tinysrc.NewClient(context.Background(), "another-key", &http.Client{Transport: replayer})
+	_ = replayClient.SetBaseURL("http://replay.test/" + tinysrc.VERSION)
+
+	successUser.ApiKey = tinysrc.REDACTED
+
+	tests := []struct {
+		name          string
+		call          func() (interface{}, models.ErrorResponse)
+		want          interface{}
+		wantErr       bool
+		wantUnmatched []string
+	}{
+		{
+			name: "test_replay",
+			call: func() (interface{}, models.ErrorResponse) {
+				return replayClient.GetCurrentUser()
+			},
+			want: &successUser,
+		},
+		{
+			name: "test_replay_again",
+			call: func() (interface{}, models.ErrorResponse) {
+				return replayClient.GetCurrentUser()
+			},
+			want:          (*models.CurrentUserResponse)(nil),
+			wantErr:       true,
+			wantUnmatched: []string{"GET /v1/client/user"},
+		},
+		{
+			name: "test_allow_reuse",
+			call: func() (interface{}, models.ErrorResponse) {
+				replayer.AllowReuse = true
+				defer func() { replayer.AllowReuse = false }()
+				return replayClient.GetCurrentUser()
+			},
+			want:          &successUser,
+			wantUnmatched: []string{"GET /v1/client/user"},
+		},
+		{
+			name: "test_unmatched",
+			call: func() (interface{}, models.ErrorResponse) {
+				return replayClient.GetUrlByHash("test")
+			},
+			want:          (*models.LinkUserResponse)(nil),
+			wantErr:       true,
+			wantUnmatched: []string{"GET /v1/client/user", "GET /v1/client/url/test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotErrorResponse := tt.call()
+			if (len(gotErrorResponse.Errors) > 0) != tt.wantErr {
+				t.Errorf("replay gotErrorResponse = %v, wantErr %v", gotErrorResponse, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replay got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(replayer.Unmatched(), tt.wantUnmatched) {
+				t.Errorf("Unmatched() = %v, want %v", replayer.Unmatched(), tt.wantUnmatched)
+			}
+		})
+	}
+}
+
+func TestRecorder_RoundTripRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+
+	recorder, _ := New(filepath.Join(t.TempDir(), "create.json"), ModeRecord, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/create", strings.NewReader(`{"url":"http://test.com"}`))
+	body := req.Body
+
+	resp, e := recorder.RoundTrip(req)
+	if e != nil {
+		t.Fatalf("RoundTrip() error = %v", e)
+	}
+	got, _ := io.ReadAll(resp.Body)
+
+	// Request of caller is left as it was
+	if req.Body != body || resp.Request != req || string(got) != `{"url":"http://test.com"}` {
+		t.Errorf("RoundTrip() changed request or lost body, got = %s", got)
+	}
+}
+
+func Test_scrubBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "test_request",
+			body: `{"url":"http://test.com","password":"secret","stat_password":"stat-secret"}`,
+			want: `{"password":"[REDACTED]","stat_password":"[REDACTED]","url":"http://test.com"}`,
+		},
+		{
+			name: "test_response",
+			body: `{"data":[{"api_key":"key","password":"","stat_password":"stat-secret"}]}`,
+			want: `{"data":[{"api_key":"[REDACTED]","password":"","stat_password":"[REDACTED]"}]}`,
+		},
+		{
+			name: "test_not_json",
+			body: `password=secret`,
+			want: `password=secret`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(scrubBody([]byte(tt.body))); got != tt.want {
+				t.Errorf("scrubBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matches(t *testing.T) {
+	recorded := &Request{
+		Method: http.MethodPost,
+		Path:   "/v1/create",
+		Query:  "a=1&b=2",
+		Body:   `{"url":"http://test.com","auth_required":0}`,
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+		want bool
+	}{
+		{
+			name: "test_query_order_and_body_format",
+			url:  "http://test/v1/create?b=2&a=1",
+			body: `{"auth_required": 0, "url": "http://test.com"}`,
+			want: true,
+		},
+		{
+			name: "test_other_query",
+			url:  "http://test/v1/create?a=1",
+			body: `{"url":"http://test.com","auth_required":0}`,
+			want: false,
+		},
+		{
+			name: "test_other_body",
+			url:  "http://test/v1/create?a=1&b=2",
+			body: `{"url":"http://other.com","auth_required":0}`,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			if got := matches(recorded, req, []byte(tt.body)); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // Client to send request to TinySRC API
@@ -16,6 +17,12 @@ type Client struct {
 	ApiKey     string
 	baseURL    *url.URL
 	ctx        context.Context
+
+	debug          io.Writer
+	debugBodyLimit int
+
+	serverLocation *time.Location
+	location       *time.Location
 }
 
 // Constructor of httpClient
@@ -33,6 +40,17 @@ func NewClient(ctx context.Context, apiKey string, httpClient *http.Client) (*Cl
 	return c, nil
 }
 
+// Change API endpoint, e.g. to point the client to a test server
+func (client *Client) SetBaseURL(baseURL string) error {
+	base, e := url.Parse(baseURL)
+	if e != nil {
+		return e
+	}
+
+	client.baseURL = base
+	return nil
+}
+
 // Set required headers by TinySRC
 func (client *Client) setRequestHeaders(req *http.Request) {
 	req.Header.Add("Accept", "application/json")
@@ -65,6 +83,10 @@ func (client *Client) sendRequest(method string, pathURL string, body io.Reader)
 }
 
 func (client *Client) do(req *http.Request) (*http.Response, error) {
+	if client.debug != nil {
+		client.dumpRequest(req)
+	}
+
 	resp, e := client.httpClient.Do(req.WithContext(client.ctx))
 	if e != nil {
 		select {
@@ -75,6 +97,10 @@ func (client *Client) do(req *http.Request) (*http.Response, error) {
 		return nil, e
 	}
 
+	if client.debug != nil {
+		client.dumpResponse(resp)
+	}
+
 	return resp, e
 }
 
@@ -398,3 +398,37 @@ func TestNewClient(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_SetBaseURL(t *testing.T) {
+	testClient, _ := NewClient(context.Background(), "test", nil)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "test_success",
+			baseURL: "http://127.0.0.1:8080/v1",
+			want:    "http://127.0.0.1:8080/v1",
+			wantErr: false,
+		},
+		{
+			name:    "test_fail",
+			baseURL: "http://[::1",
+			want:    "http://127.0.0.1:8080/v1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := testClient.SetBaseURL(tt.baseURL); (err != nil) != tt.wantErr {
+				t.Errorf("SetBaseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := testClient.baseURL.String(); got != tt.want {
+				t.Errorf("SetBaseURL() baseURL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
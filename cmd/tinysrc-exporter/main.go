@@ -0,0 +1,91 @@
+// Command tinysrc-exporter serves metrics of links in Prometheus text format.
+// Account is scraped every interval, /metrics returns values of the last scrape.
+//
+//	TINYSRC_API_KEY=... tinysrc-exporter -listen :9750 -interval 1m -deny 'test-*'
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/exporter"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	listen := flag.String("listen", ":9750", "address of HTTP server")
+	interval := flag.Duration("interval", time.Minute, "scrape interval")
+	query := flag.String("query", "", "search query of links")
+	allow := flag.String("allow", "", "comma separated glob patterns of exported hashes")
+	deny := flag.String("deny", "", "comma separated glob patterns of hashes which are not exported")
+	maxLinks := flag.Int("max-links", exporter.DEFAULT_MAX_LINKS, "limit of links with labels, links with the most clicks are kept")
+	flag.Parse()
+
+	client, e := tinysrc.NewClient(context.Background(), os.Getenv("TINYSRC_API_KEY"), nil)
+	if e != nil {
+		fail(e)
+	}
+
+	metrics := exporter.NewExporter(client)
+	metrics.Query = *query
+	metrics.Allow = split(*allow)
+	metrics.Deny = split(*deny)
+	metrics.MaxLinks = *maxLinks
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	metrics.OnError = func(e error) {
+		_, _ = fmt.Fprintln(os.Stderr, e)
+	}
+
+	go func() {
+		_ = metrics.Run(ctx, *interval)
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "tinysrc-exporter, metrics: /metrics")
+	})
+
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+
+	if e = server.ListenAndServe(); e != nil && !errors.Is(e, http.ErrServerClosed) {
+		fail(e)
+	}
+}
+
+func split(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func fail(e error) {
+	_, _ = fmt.Fprintln(os.Stderr, e)
+	os.Exit(1)
+}
@@ -0,0 +1,75 @@
+// Command tinysrc-gateway is an internal shortening API holding the API key.
+// Callers are read from JSON file: [{"name": "billing", "token": "...", "quota": 1000}].
+// Audit log is written to stderr as JSON lines.
+//
+//	TINYSRC_API_KEY=... tinysrc-gateway -listen :8080 -callers callers.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/gateway"
+	"github.com/dmitrypro77/tinysrc-api-sdk/idempotent"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	listen := flag.String("listen", ":8080", "address of HTTP server")
+	callers := flag.String("callers", "callers.json", "JSON file of callers with tokens and quotas")
+	quotaWindow := flag.Duration("quota-window", gateway.DEFAULT_QUOTA_WINDOW, "window of caller quotas")
+	cacheTTL := flag.Duration("cache-ttl", gateway.DEFAULT_CACHE_TTL, "lifetime of cached links and statistics, negative disables cache")
+	index := flag.String("index", "", "file index of created links, every request creates a new link when empty")
+	flag.Parse()
+
+	client, e := tinysrc.NewClient(context.Background(), os.Getenv("TINYSRC_API_KEY"), nil)
+	if e != nil {
+		fail(e)
+	}
+
+	loaded, e := gateway.LoadCallers(*callers)
+	if e != nil {
+		fail(e)
+	}
+
+	gw := gateway.New(client, loaded...)
+	gw.QuotaWindow = *quotaWindow
+	gw.CacheTTL = *cacheTTL
+	gw.Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	if *index != "" {
+		fileIndex, e := idempotent.OpenFileIndex(*index)
+		if e != nil {
+			fail(e)
+		}
+		gw.Creator = idempotent.NewCreator(client, fileIndex)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *listen, Handler: gw, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+
+	if e = server.ListenAndServe(); e != nil && !errors.Is(e, http.ErrServerClosed) {
+		fail(e)
+	}
+}
+
+func fail(e error) {
+	_, _ = fmt.Fprintln(os.Stderr, e)
+	os.Exit(1)
+}
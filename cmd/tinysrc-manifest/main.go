@@ -0,0 +1,77 @@
+// Command tinysrc-manifest plans and applies a links manifest.
+//
+//	TINYSRC_API_KEY=... tinysrc-manifest -manifest links.json -state links.state.json plan
+//	TINYSRC_API_KEY=... tinysrc-manifest -manifest links.json -state links.state.json apply
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/manifest"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"os"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "links.json", "manifest file")
+	statePath := flag.String("state", "links.state.json", "state file mapping manifest keys to hashes")
+	prune := flag.Bool("prune", false, "deactivate links removed from manifest")
+	recreate := flag.Bool("recreate", false, "recreate links which can not be changed in place")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] plan|apply\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command != "plan" && command != "apply" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	m, e := manifest.Load(*manifestPath)
+	if e != nil {
+		fail(e)
+	}
+
+	state, e := manifest.OpenState(*statePath)
+	if e != nil {
+		fail(e)
+	}
+
+	client, e := tinysrc.NewClient(context.Background(), os.Getenv("TINYSRC_API_KEY"), nil)
+	if e != nil {
+		fail(e)
+	}
+
+	engine := &manifest.Engine{Client: client, Prune: *prune, Recreate: *recreate}
+
+	plan, errorResponse := engine.Plan(m, state)
+	if failed(errorResponse) {
+		fail(fmt.Errorf("%v %v", errorResponse.Errors, errorResponse.Validations))
+	}
+
+	fmt.Print(plan)
+
+	if command == "plan" || plan.Empty() {
+		return
+	}
+
+	applied, errorResponse := engine.Apply(plan, state)
+	fmt.Printf("Applied %d of %d actions.\n", len(applied), len(plan.Actions))
+
+	if failed(errorResponse) {
+		fail(fmt.Errorf("%v %v", errorResponse.Errors, errorResponse.Validations))
+	}
+}
+
+func failed(errorResponse models.ErrorResponse) bool {
+	return len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0
+}
+
+func fail(e error) {
+	_, _ = fmt.Fprintln(os.Stderr, e)
+	os.Exit(1)
+}
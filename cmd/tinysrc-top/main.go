@@ -0,0 +1,143 @@
+// Command tinysrc-top is a live terminal dashboard of links sorted by recent
+// clicks. Selected link is activated or deactivated with a keypress.
+//
+//	TINYSRC_API_KEY=... tinysrc-top -interval 10s -window 1h
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/dashboard"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const ESCAPE_ENTER = "\x1b[?1049h\x1b[?25l"
+const ESCAPE_LEAVE = "\x1b[?25h\x1b[?1049l"
+
+func main() {
+	interval := flag.Duration("interval", 10*time.Second, "refresh interval")
+	window := flag.Duration("window", dashboard.DEFAULT_WINDOW, "recent clicks window")
+	buckets := flag.Int("buckets", dashboard.DEFAULT_BUCKETS, "sparkline points")
+	limit := flag.Int("limit", dashboard.DEFAULT_LIMIT, "links loaded from the first page of the list")
+	query := flag.String("query", "", "search query of links")
+	flag.Parse()
+
+	client, e := tinysrc.NewClient(context.Background(), os.Getenv("TINYSRC_API_KEY"), nil)
+	if e != nil {
+		fail(e)
+	}
+
+	board := dashboard.New(client)
+	board.Window = *window
+	board.Buckets = *buckets
+	board.Limit = *limit
+	board.Query = *query
+
+	state, e := stty("-g")
+	if e != nil {
+		fail(fmt.Errorf("terminal is required: %w", e))
+	}
+
+	if _, e = stty("raw", "-echo"); e != nil {
+		fail(e)
+	}
+
+	fmt.Print(ESCAPE_ENTER)
+	restore := func() {
+		fmt.Print(ESCAPE_LEAVE)
+		_, _ = stty(strings.TrimSpace(state))
+	}
+	defer restore()
+
+	keys := make(chan dashboard.Key, 16)
+	go read(keys)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	render := func() {
+		width, height := size()
+		_ = board.Render(os.Stdout, width, height)
+	}
+
+	board.Refresh()
+	render()
+
+	for {
+		select {
+		case <-ticker.C:
+			board.Refresh()
+		case key := <-keys:
+			if quit, _ := board.Handle(key); quit {
+				return
+			}
+		case <-signals:
+			return
+		}
+
+		render()
+	}
+}
+
+// Decode keys of stdin until it is closed
+func read(keys chan<- dashboard.Key) {
+	buf := make([]byte, 64)
+
+	for {
+		n, e := os.Stdin.Read(buf)
+		for _, key := range dashboard.DecodeKeys(buf[:n]) {
+			keys <- key
+		}
+
+		if e != nil {
+			keys <- dashboard.KeyQuit
+			return
+		}
+	}
+}
+
+// Run stty on the terminal of stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+
+	out, e := cmd.Output()
+	return string(out), e
+}
+
+// Columns and lines of terminal, 80x24 when unknown
+func size() (int, int) {
+	out, e := stty("size")
+	if e != nil {
+		return 80, 24
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 80, 24
+	}
+
+	height, e1 := strconv.Atoi(fields[0])
+	width, e2 := strconv.Atoi(fields[1])
+	if e1 != nil || e2 != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+
+	return width, height
+}
+
+func fail(e error) {
+	_, _ = fmt.Fprintln(os.Stderr, e)
+	os.Exit(1)
+}
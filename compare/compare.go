@@ -0,0 +1,325 @@
+// Package compare builds period-over-period reports of links, e.g. this week
+// compared with the previous week: clicks, human clicks, unique IPs, mobile
+// share and top referers with deltas and percentage changes.
+//
+//	comparer := compare.NewComparer(client)
+//	current := compare.Week(time.Now())
+//	comparisons, errorResponse := comparer.Compare([]string{"test"}, current, current.Previous())
+//	e := compare.Render(os.Stdout, comparisons, compare.FormatTable)
+package compare
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/bots"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/traffic"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DEFAULT_TOP = 5
+const DEFAULT_CONCURRENCY = 4
+
+// Format of days in rendered periods
+const DAY_FORMAT = "2006-01-02"
+
+// Date range [Start, End)
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Period of the same length right before period
+func (period Period) Previous() Period {
+	return Period{Start: period.Start.Add(-period.End.Sub(period.Start)), End: period.Start}
+}
+
+func (period Period) Contains(t time.Time) bool {
+	return !t.Before(period.Start) && t.Before(period.End)
+}
+
+func (period Period) String() string {
+	// End is exclusive, last day of period is shown
+	end := period.End
+	if end.After(period.Start) {
+		end = end.Add(-1)
+	}
+
+	return period.Start.Format(DAY_FORMAT) + ".." + end.Format(DAY_FORMAT)
+}
+
+// Week starting on Monday which contains t, in location of t
+func Week(t time.Time) Period {
+	days := (int(t.Weekday()) + 6) % 7
+	start := time.Date(t.Year(), t.Month(), t.Day()-days, 0, 0, 0, 0, t.Location())
+
+	return Period{Start: start, End: start.AddDate(0, 0, 7)}
+}
+
+// Metrics of one period
+type Metrics struct {
+	Clicks int64 `json:"clicks"`
+	// Clicks not classified as bots
+	HumanClicks int64 `json:"human_clicks"`
+	UniqueIps   int64 `json:"unique_ips"`
+	// Share of human clicks from phones and tablets, 0..1
+	MobileShare float64 `json:"mobile_share"`
+	// Top referring domains of human clicks
+	Referers []*traffic.Count `json:"referers"`
+
+	// Clicks of every referring domain
+	domains map[string]int64
+}
+
+// Change of one metric
+type Delta struct {
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Change   float64 `json:"change"`
+	// Change in percent of previous value, nil when previous value is zero
+	Percent *float64 `json:"percent"`
+}
+
+func NewDelta(previous float64, current float64) Delta {
+	delta := Delta{Previous: previous, Current: current, Change: current - previous}
+	if previous != 0 {
+		percent := delta.Change / previous * 100
+		delta.Percent = &percent
+	}
+
+	return delta
+}
+
+// Change of clicks of one referring domain
+type RefererDelta struct {
+	Name string `json:"name"`
+	Delta
+}
+
+type Comparison struct {
+	Hash           string   `json:"hash"`
+	Current        Period   `json:"current"`
+	Previous       Period   `json:"previous"`
+	CurrentMetrics *Metrics `json:"current_metrics"`
+	// Metrics of previous period
+	PreviousMetrics *Metrics `json:"previous_metrics"`
+	Clicks          Delta    `json:"clicks"`
+	HumanClicks     Delta    `json:"human_clicks"`
+	UniqueIps       Delta    `json:"unique_ips"`
+	// Change of MobileShare, Change is in percentage points
+	MobileShare Delta `json:"mobile_share"`
+	// Top referers of both periods, sorted by current clicks desc
+	Referers []*RefererDelta `json:"referers"`
+}
+
+type Comparer struct {
+	Client *tinysrc.Client
+	// Classifier of human clicks, Bot flag of server is used when nil
+	Classifier *bots.Classifier
+	// Referers kept per period, DEFAULT_TOP when zero
+	Top int
+	// Hashes compared at the same time, DEFAULT_CONCURRENCY when zero
+	Concurrency int
+	// Options of statistic requests
+	Options tinysrc.StatChunkOptions
+}
+
+// Constructor of Comparer with local bot classifier
+func NewComparer(client *tinysrc.Client) *Comparer {
+	return &Comparer{
+		Client:      client,
+		Classifier:  bots.NewClassifier(),
+		Top:         DEFAULT_TOP,
+		Concurrency: DEFAULT_CONCURRENCY,
+	}
+}
+
+// Load statistic of both periods for every hash and compare them, comparisons are in order of hashes.
+// Hashes which failed are skipped and their errors are returned.
+func (comparer *Comparer) Compare(hashes []string, current Period, previous Period) (r []*Comparison, errorResponse models.ErrorResponse) {
+	concurrency := comparer.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+
+	comparisons := make([]*Comparison, len(hashes))
+	failures := make([]models.ErrorResponse, len(hashes))
+
+	var wg sync.WaitGroup
+	pool := make(chan struct{}, concurrency)
+
+	for i, hash := range hashes {
+		wg.Add(1)
+		pool <- struct{}{}
+
+		go func(i int, hash string) {
+			defer wg.Done()
+			defer func() { <-pool }()
+
+			currentStats, failure := comparer.stats(hash, current)
+			if len(failure.Errors) > 0 || len(failure.Validations) > 0 {
+				failures[i] = failure
+				return
+			}
+
+			previousStats, failure := comparer.stats(hash, previous)
+			if len(failure.Errors) > 0 || len(failure.Validations) > 0 {
+				failures[i] = failure
+				return
+			}
+
+			comparisons[i] = comparer.Compute(hash, current, previous, currentStats, previousStats)
+		}(i, hash)
+	}
+
+	wg.Wait()
+
+	for i, comparison := range comparisons {
+		if comparison != nil {
+			r = append(r, comparison)
+			continue
+		}
+
+		for _, e := range failures[i].Errors {
+			errorResponse.Errors = append(errorResponse.Errors, hashes[i]+": "+e)
+		}
+		for field, messages := range failures[i].Validations {
+			if errorResponse.Validations == nil {
+				errorResponse.Validations = make(map[string][]string)
+			}
+			errorResponse.Validations[field] = append(errorResponse.Validations[field], messages...)
+		}
+		if failures[i].Status != 0 {
+			errorResponse.Status = failures[i].Status
+		}
+	}
+
+	return r, errorResponse
+}
+
+func (comparer *Comparer) stats(hash string, period Period) ([]*models.StatResponse, models.ErrorResponse) {
+	r, errorResponse := comparer.Client.GetStatByHashChunked(hash, models.StatRequest{DateStart: period.Start, DateEnd: period.End}, comparer.Options)
+	if r == nil {
+		return nil, errorResponse
+	}
+
+	// DateEnd is inclusive, rows of the next period are dropped
+	var stats []*models.StatResponse
+	for _, stat := range r.Data {
+		if period.Contains(stat.Created) {
+			stats = append(stats, stat)
+		}
+	}
+
+	return stats, errorResponse
+}
+
+// Compare rows of two periods which are already loaded
+func (comparer *Comparer) Compute(hash string, current Period, previous Period, currentStats []*models.StatResponse, previousStats []*models.StatResponse) *Comparison {
+	comparison := &Comparison{
+		Hash:            hash,
+		Current:         current,
+		Previous:        previous,
+		CurrentMetrics:  comparer.Measure(currentStats),
+		PreviousMetrics: comparer.Measure(previousStats),
+	}
+
+	before, after := comparison.PreviousMetrics, comparison.CurrentMetrics
+	comparison.Clicks = NewDelta(float64(before.Clicks), float64(after.Clicks))
+	comparison.HumanClicks = NewDelta(float64(before.HumanClicks), float64(after.HumanClicks))
+	comparison.UniqueIps = NewDelta(float64(before.UniqueIps), float64(after.UniqueIps))
+	comparison.MobileShare = NewDelta(before.MobileShare*100, after.MobileShare*100)
+	comparison.Referers = referers(before, after)
+
+	return comparison
+}
+
+// Metrics of rows of one period
+func (comparer *Comparer) Measure(stats []*models.StatResponse) *Metrics {
+	metrics := &Metrics{Clicks: int64(len(stats)), Referers: []*traffic.Count{}}
+
+	human := make([]bool, len(stats))
+	if comparer.Classifier != nil {
+		for i, result := range comparer.Classifier.Classify(stats) {
+			human[i] = !result.Bot
+		}
+	} else {
+		for i, stat := range stats {
+			human[i] = !stat.Bot
+		}
+	}
+
+	ips := make(map[string]bool)
+	var humans []*traffic.Stat
+	var mobile int64
+
+	for i, stat := range stats {
+		ips[stat.Ip] = true
+
+		if !human[i] {
+			continue
+		}
+
+		enriched := &traffic.Stat{StatResponse: stat, Agent: traffic.ParseAgent(stat), Source: traffic.ParseReferer(stat.Referer)}
+		if enriched.Agent.Device == traffic.DeviceMobile || enriched.Agent.Device == traffic.DeviceTablet {
+			mobile++
+		}
+		humans = append(humans, enriched)
+	}
+
+	metrics.UniqueIps = int64(len(ips))
+	metrics.HumanClicks = int64(len(humans))
+	if metrics.HumanClicks > 0 {
+		metrics.MobileShare = float64(mobile) / float64(metrics.HumanClicks)
+	}
+
+	top := comparer.Top
+	if top <= 0 {
+		top = DEFAULT_TOP
+	}
+
+	metrics.domains = make(map[string]int64)
+	for _, count := range traffic.Analyze(humans, 0).Domains {
+		metrics.domains[count.Name] = count.Clicks
+
+		if len(metrics.Referers) < top {
+			metrics.Referers = append(metrics.Referers, count)
+		}
+	}
+
+	return metrics
+}
+
+// Union of top referers of both periods, clicks are counted in both periods
+// even when referer is not in top of one of them
+func referers(previous *Metrics, current *Metrics) []*RefererDelta {
+	var result []*RefererDelta
+	seen := make(map[string]bool)
+
+	for _, counts := range [][]*traffic.Count{current.Referers, previous.Referers} {
+		for _, count := range counts {
+			if seen[count.Name] {
+				continue
+			}
+
+			seen[count.Name] = true
+			result = append(result, &RefererDelta{
+				Name:  count.Name,
+				Delta: NewDelta(float64(previous.domains[count.Name]), float64(current.domains[count.Name])),
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Current != result[j].Current {
+			return result[i].Current > result[j].Current
+		}
+		if result[i].Previous != result[j].Previous {
+			return result[i].Previous > result[j].Previous
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
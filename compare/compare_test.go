@@ -0,0 +1,163 @@
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeek(t *testing.T) {
+	// Sunday
+	week := Week(time.Date(2022, 4, 10, 23, 0, 0, 0, time.UTC))
+
+	want := Period{Start: time.Date(2022, 4, 4, 0, 0, 0, 0, time.UTC), End: time.Date(2022, 4, 11, 0, 0, 0, 0, time.UTC)}
+	if week != want {
+		t.Fatalf("Week() = %v, want %v", week, want)
+	}
+
+	previous := week.Previous()
+	if !previous.Start.Equal(time.Date(2022, 3, 28, 0, 0, 0, 0, time.UTC)) || !previous.End.Equal(week.Start) {
+		t.Errorf("Previous() = %v", previous)
+	}
+
+	if week.String() != "2022-04-04..2022-04-10" {
+		t.Errorf("String() = %s", week.String())
+	}
+}
+
+func TestComparer_Compute(t *testing.T) {
+	current := Week(time.Date(2022, 4, 6, 0, 0, 0, 0, time.UTC))
+	previous := current.Previous()
+
+	click := func(period Period, ip string, os string, referer string) *models.StatResponse {
+		return &models.StatResponse{Ip: ip, Browser: "Chrome", Os: os, Referer: referer, Created: period.Start.Add(time.Hour)}
+	}
+
+	previousStats := []*models.StatResponse{
+		click(previous, "1.1.1.1", "Windows", "https://www.google.com/"),
+		click(previous, "1.1.1.1", "Windows", "https://a.test.com/page"),
+		click(previous, "1.1.1.2", "Android", "https://other.com"),
+		click(previous, "1.1.1.3", "Windows", "https://other.com"),
+	}
+
+	currentStats := []*models.StatResponse{
+		click(current, "1.1.1.1", "Windows", "https://www.google.com/"),
+		click(current, "1.1.1.4", "iOS", "https://test.com"),
+		click(current, "1.1.1.5", "Android", "https://test.com"),
+		click(current, "1.1.1.6", "Android", "https://new.com"),
+		click(current, "1.1.1.7", "Windows", ""),
+		{Ip: "1.1.1.8", Browser: "Googlebot", Created: current.Start},
+	}
+
+	comparer := &Comparer{Classifier: nil, Top: 2}
+	currentStats[5].Bot = true
+
+	comparison := comparer.Compute("test", current, previous, currentStats, previousStats)
+
+	deltas := []struct {
+		name     string
+		delta    Delta
+		previous float64
+		current  float64
+		percent  float64
+	}{
+		{name: "test_clicks", delta: comparison.Clicks, previous: 4, current: 6, percent: 50},
+		{name: "test_human_clicks", delta: comparison.HumanClicks, previous: 4, current: 5, percent: 25},
+		{name: "test_unique_ips", delta: comparison.UniqueIps, previous: 3, current: 6, percent: 100},
+		{name: "test_mobile_share", delta: comparison.MobileShare, previous: 25, current: 60, percent: 140},
+	}
+
+	for _, tt := range deltas {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.delta.Previous != tt.previous || tt.delta.Current != tt.current || tt.delta.Change != tt.current-tt.previous {
+				t.Fatalf("Delta = %+v, want %v -> %v", tt.delta, tt.previous, tt.current)
+			}
+			if tt.delta.Percent == nil || *tt.delta.Percent-tt.percent > 1e-9 || tt.percent-*tt.delta.Percent > 1e-9 {
+				t.Errorf("Percent = %v, want %v", tt.delta.Percent, tt.percent)
+			}
+		})
+	}
+
+	// test.com is in top of current period only, other.com of previous only
+	var referers []string
+	for _, referer := range comparison.Referers {
+		referers = append(referers, referer.Name+" "+strings.Join(countRow("", referer.Delta)[1:], " "))
+	}
+
+	want := []string{
+		"test.com 1 2 +1 +100.0%",
+		"google.com 1 1 0 0.0%",
+		"other.com 2 0 -2 -100.0%",
+	}
+	if !reflect.DeepEqual(referers, want) {
+		t.Errorf("Referers = %v, want %v", referers, want)
+	}
+
+	if len(comparison.CurrentMetrics.Referers) != 2 {
+		t.Errorf("CurrentMetrics.Referers = %v", comparison.CurrentMetrics.Referers)
+	}
+}
+
+func TestComparer_Compare(t *testing.T) {
+	current := Period{Start: time.Date(2022, 4, 2, 0, 0, 0, 0, time.UTC), End: time.Date(2022, 4, 3, 0, 0, 0, 0, time.UTC)}
+
+	rows := []*models.StatResponse{
+		{Ip: "1.1.1.1", Created: time.Date(2022, 4, 1, 10, 0, 0, 0, time.UTC)},
+		{Ip: "1.1.1.2", Created: time.Date(2022, 4, 2, 10, 0, 0, 0, time.UTC)},
+		{Ip: "1.1.1.3", Created: time.Date(2022, 4, 2, 11, 0, 0, 0, time.UTC)},
+		// DateEnd is inclusive, row belongs to next period
+		{Ip: "1.1.1.4", Created: time.Date(2022, 4, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/test") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+			return
+		}
+
+		query := r.URL.Query()
+		from, _ := time.Parse(tinysrc.DATE_FORMAT, query.Get("date-start"))
+		to, _ := time.Parse(tinysrc.DATE_FORMAT, query.Get("date-end"))
+
+		resp := models.StatPaginatedResponse{}
+		if query.Get("page") == "1" {
+			for _, row := range rows {
+				if !row.Created.Before(from) && !row.Created.After(to) {
+					resp.Data = append(resp.Data, row)
+				}
+			}
+		}
+		resp.Total = int64(len(resp.Data))
+
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	comparer := NewComparer(client)
+	comparer.Classifier = nil
+
+	comparisons, errorResponse := comparer.Compare([]string{"missing", "test"}, current, current.Previous())
+	if len(comparisons) != 1 || comparisons[0].Hash != "test" {
+		t.Fatalf("Compare() = %v", comparisons)
+	}
+
+	if len(errorResponse.Errors) != 1 || !strings.HasPrefix(errorResponse.Errors[0], "missing: ") {
+		t.Errorf("Compare() errors = %v", errorResponse.Errors)
+	}
+
+	if clicks := comparisons[0].Clicks; clicks.Previous != 1 || clicks.Current != 2 {
+		t.Errorf("Compare() clicks = %+v", clicks)
+	}
+}
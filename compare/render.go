@@ -0,0 +1,158 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type Format string
+
+const (
+	FormatTable    Format = "table"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+)
+
+// Parse name of format, e.g. value of command line flag
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatTable, FormatJSON, FormatMarkdown:
+		return format, nil
+	case "md":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("compare: unknown format %q", name)
+	}
+}
+
+// Write comparisons in format
+func Render(w io.Writer, comparisons []*Comparison, format Format) error {
+	switch format {
+	case FormatTable:
+		return WriteTable(w, comparisons)
+	case FormatJSON:
+		return WriteJSON(w, comparisons)
+	case FormatMarkdown:
+		return WriteMarkdown(w, comparisons)
+	default:
+		return fmt.Errorf("compare: unknown format %q", format)
+	}
+}
+
+func WriteJSON(w io.Writer, comparisons []*Comparison) error {
+	if comparisons == nil {
+		comparisons = []*Comparison{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(comparisons)
+}
+
+// Aligned plain text table, one block per hash
+func WriteTable(w io.Writer, comparisons []*Comparison) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for i, comparison := range comparisons {
+		if i > 0 {
+			fmt.Fprintln(writer)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\tchange\t%%\n", comparison.Hash, comparison.Previous, comparison.Current)
+		for _, row := range rows(comparison) {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+	}
+
+	return writer.Flush()
+}
+
+// Markdown section with table per hash
+func WriteMarkdown(w io.Writer, comparisons []*Comparison) error {
+	var b strings.Builder
+
+	for i, comparison := range comparisons {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		fmt.Fprintf(&b, "### %s\n\n", escapeMarkdown(comparison.Hash))
+		fmt.Fprintf(&b, "| Metric | %s | %s | Change | %% |\n", comparison.Previous, comparison.Current)
+		b.WriteString("|:--|--:|--:|--:|--:|\n")
+
+		for _, row := range rows(comparison) {
+			for j := range row {
+				row[j] = escapeMarkdown(row[j])
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+
+	_, e := io.WriteString(w, b.String())
+	return e
+}
+
+// Rows of metric, previous, current, change and percent
+func rows(comparison *Comparison) [][]string {
+	result := [][]string{
+		countRow("clicks", comparison.Clicks),
+		countRow("human clicks", comparison.HumanClicks),
+		countRow("unique ips", comparison.UniqueIps),
+		{
+			"mobile share",
+			strconv.FormatFloat(comparison.MobileShare.Previous, 'f', 1, 64) + "%",
+			strconv.FormatFloat(comparison.MobileShare.Current, 'f', 1, 64) + "%",
+			signed(comparison.MobileShare.Change, 1) + " pp",
+			percent(comparison.MobileShare),
+		},
+	}
+
+	for _, referer := range comparison.Referers {
+		result = append(result, countRow("referer "+referer.Name, referer.Delta))
+	}
+
+	return result
+}
+
+func countRow(name string, delta Delta) []string {
+	return []string{
+		name,
+		strconv.FormatFloat(delta.Previous, 'f', 0, 64),
+		strconv.FormatFloat(delta.Current, 'f', 0, 64),
+		signed(delta.Change, 0),
+		percent(delta),
+	}
+}
+
+func signed(value float64, precision int) string {
+	if math.Abs(value) < math.Pow(10, -float64(precision))/2 {
+		return strconv.FormatFloat(0, 'f', precision, 64)
+	}
+
+	formatted := strconv.FormatFloat(value, 'f', precision, 64)
+	if value > 0 {
+		formatted = "+" + formatted
+	}
+
+	return formatted
+}
+
+func percent(delta Delta) string {
+	if delta.Percent == nil {
+		if delta.Current == 0 {
+			return "-"
+		}
+		return "new"
+	}
+
+	return signed(*delta.Percent, 1) + "%"
+}
+
+func escapeMarkdown(value string) string {
+	return strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`").Replace(value)
+}
@@ -0,0 +1,100 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk/traffic"
+	"testing"
+	"time"
+)
+
+func testComparison() *Comparison {
+	current := Period{Start: time.Date(2022, 4, 4, 0, 0, 0, 0, time.UTC), End: time.Date(2022, 4, 11, 0, 0, 0, 0, time.UTC)}
+
+	return &Comparison{
+		Hash:            "spring_sale",
+		Current:         current,
+		Previous:        current.Previous(),
+		CurrentMetrics:  &Metrics{Referers: []*traffic.Count{}},
+		PreviousMetrics: &Metrics{Referers: []*traffic.Count{}},
+		Clicks:          NewDelta(120, 150),
+		HumanClicks:     NewDelta(100, 90),
+		UniqueIps:       NewDelta(0, 80),
+		MobileShare:     NewDelta(40, 45.5),
+		Referers:        []*RefererDelta{{Name: "test.com", Delta: NewDelta(10, 0)}},
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{
+			name:   "test_table",
+			format: FormatTable,
+			want: "spring_sale       2022-03-28..2022-04-03  2022-04-04..2022-04-10  change   %\n" +
+				"clicks            120                     150                     +30      +25.0%\n" +
+				"human clicks      100                     90                      -10      -10.0%\n" +
+				"unique ips        0                       80                      +80      new\n" +
+				"mobile share      40.0%                   45.5%                   +5.5 pp  +13.8%\n" +
+				"referer test.com  10                      0                       -10      -100.0%\n",
+		},
+		{
+			name:   "test_markdown",
+			format: FormatMarkdown,
+			want: "### spring\\_sale\n\n" +
+				"| Metric | 2022-03-28..2022-04-03 | 2022-04-04..2022-04-10 | Change | % |\n" +
+				"|:--|--:|--:|--:|--:|\n" +
+				"| clicks | 120 | 150 | +30 | +25.0% |\n" +
+				"| human clicks | 100 | 90 | -10 | -10.0% |\n" +
+				"| unique ips | 0 | 80 | +80 | new |\n" +
+				"| mobile share | 40.0% | 45.5% | +5.5 pp | +13.8% |\n" +
+				"| referer test.com | 10 | 0 | -10 | -100.0% |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if e := Render(&b, []*Comparison{testComparison()}, tt.format); e != nil {
+				t.Fatalf("Render() error = %v", e)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Render() = \n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if e := Render(&b, []*Comparison{testComparison()}, FormatJSON); e != nil {
+		t.Fatalf("Render() error = %v", e)
+	}
+
+	var decoded []map[string]any
+	if e := json.Unmarshal(b.Bytes(), &decoded); e != nil {
+		t.Fatalf("Unmarshal() error = %v", e)
+	}
+
+	uniqueIps := decoded[0]["unique_ips"].(map[string]any)
+	if percent, ok := uniqueIps["percent"]; !ok || percent != nil {
+		t.Errorf("unique_ips = %v", uniqueIps)
+	}
+
+	if clicks := decoded[0]["clicks"].(map[string]any); clicks["percent"] != 25.0 {
+		t.Errorf("clicks = %v", clicks)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, e := ParseFormat(" MD "); e != nil || format != FormatMarkdown {
+		t.Errorf("ParseFormat() = %v, %v", format, e)
+	}
+
+	if _, e := ParseFormat("csv"); e == nil {
+		t.Errorf("ParseFormat() expected error")
+	}
+}
@@ -1,5 +1,16 @@
 package tinysrc
 
+import "time"
+
 const API_URL = "https://tinysrc.me/api"
+const SHORT_URL = "https://tinysrc.me"
 const VERSION = "v1"
 const DATE_FORMAT = "2006-01-02 15:04"
+const DEBUG_BODY_LIMIT = 4096
+const LIST_PAGE_SIZE = 100
+
+const STAT_WINDOW_HOUR = time.Hour
+const STAT_WINDOW_DAY = 24 * time.Hour
+const STAT_WINDOW_WEEK = 7 * 24 * time.Hour
+const STAT_CHUNK_CONCURRENCY = 4
+const STAT_CHUNK_LIMIT = 1000
@@ -0,0 +1,287 @@
+// Package dashboard keeps state of a live terminal dashboard of links: links
+// of GetListUrls sorted by recent clicks with sparklines of GetStatByHash,
+// selection and activation toggle. Frames are rendered with ANSI escapes,
+// the terminal itself is handled by cmd/tinysrc-top.
+//
+//	board := dashboard.New(client)
+//	errorResponse := board.Refresh()
+//	e := board.Render(os.Stdout, 120, 40)
+package dashboard
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/anomaly"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clicks of the last DEFAULT_WINDOW are recent clicks
+const DEFAULT_WINDOW = time.Hour
+
+// Points of sparkline
+const DEFAULT_BUCKETS = 30
+
+// Links read from the first page of GetListUrls
+const DEFAULT_LIMIT = 50
+
+const DEFAULT_CONCURRENCY = 4
+
+type Row struct {
+	Link *models.LinkUserResponse
+	// Clicks within Window
+	Recent int64
+	// Clicks per bucket of Window, oldest first
+	History []float64
+	Expired bool
+	// Statistic of link could not be loaded
+	Err string
+}
+
+func (row *Row) Active() bool {
+	return row.Link.Active != 0
+}
+
+type Dashboard struct {
+	Client      *tinysrc.Client
+	Window      time.Duration
+	Buckets     int
+	Limit       int
+	Query       string
+	Concurrency int
+
+	mutex    sync.Mutex
+	rows     []*Row
+	selected string
+	updated  time.Time
+	status   string
+	now      func() time.Time
+}
+
+func New(client *tinysrc.Client) *Dashboard {
+	return &Dashboard{
+		Client:      client,
+		Window:      DEFAULT_WINDOW,
+		Buckets:     DEFAULT_BUCKETS,
+		Limit:       DEFAULT_LIMIT,
+		Concurrency: DEFAULT_CONCURRENCY,
+		now:         time.Now,
+	}
+}
+
+// Reload links and their recent statistic, selection is kept by hash
+func (dashboard *Dashboard) Refresh() (errorResponse models.ErrorResponse) {
+	limit := dashboard.Limit
+	if limit <= 0 {
+		limit = DEFAULT_LIMIT
+	}
+
+	list, errorResponse := dashboard.Client.GetListUrls(models.ListUrlsRequest{Limit: limit, Page: 1, Query: dashboard.Query})
+	if list == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		dashboard.setStatus(errorResponse)
+		return errorResponse
+	}
+
+	now := dashboard.now()
+	rows := make([]*Row, len(list.Data))
+
+	concurrency := dashboard.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+
+	var wg sync.WaitGroup
+	pool := make(chan struct{}, concurrency)
+
+	for i, link := range list.Data {
+		wg.Add(1)
+		pool <- struct{}{}
+
+		go func(i int, link *models.LinkUserResponse) {
+			defer wg.Done()
+			defer func() { <-pool }()
+
+			rows[i] = dashboard.row(link, now)
+		}(i, link)
+	}
+
+	wg.Wait()
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Recent != rows[j].Recent {
+			return rows[i].Recent > rows[j].Recent
+		}
+		if rows[i].Link.Clicks != rows[j].Link.Clicks {
+			return rows[i].Link.Clicks > rows[j].Link.Clicks
+		}
+		return rows[i].Link.Hash < rows[j].Link.Hash
+	})
+
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	dashboard.rows = rows
+	dashboard.updated = now
+	dashboard.status = ""
+
+	if dashboard.index() < 0 && len(rows) > 0 {
+		dashboard.selected = rows[0].Link.Hash
+	}
+
+	return errorResponse
+}
+
+func (dashboard *Dashboard) row(link *models.LinkUserResponse, now time.Time) *Row {
+	window := dashboard.Window
+	if window <= 0 {
+		window = DEFAULT_WINDOW
+	}
+
+	buckets := dashboard.Buckets
+	if buckets <= 0 {
+		buckets = DEFAULT_BUCKETS
+	}
+
+	row := &Row{Link: link, Expired: link.ExpirationTime != nil && link.ExpirationTime.Before(now)}
+
+	start := now.Add(-window)
+	// All pages of window are read, so busy links are not undercounted
+	stats, errorResponse := dashboard.Client.GetStatByHashChunked(link.Hash, models.StatRequest{DateStart: start, DateEnd: now}, tinysrc.StatChunkOptions{Window: window, Concurrency: 1})
+	if stats == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		row.Err = "statistic is not available"
+		if len(errorResponse.Errors) > 0 {
+			row.Err = errorResponse.Errors[0]
+		}
+		return row
+	}
+
+	row.Recent = int64(len(stats.Data))
+
+	// Buckets are aligned to now, so the last one is the current one
+	step := window / time.Duration(buckets)
+	if step <= 0 {
+		step = time.Nanosecond
+	}
+
+	shift := now.Sub(now.Truncate(step))
+	shifted := make([]*models.StatResponse, len(stats.Data))
+	for i, stat := range stats.Data {
+		copied := *stat
+		copied.Created = stat.Created.Add(-shift)
+		shifted[i] = &copied
+	}
+
+	for _, point := range anomaly.Buckets(shifted, start.Add(-shift), now.Add(-shift), step) {
+		row.History = append(row.History, point.Count)
+	}
+	if len(row.History) > buckets {
+		row.History = row.History[len(row.History)-buckets:]
+	}
+
+	return row
+}
+
+// Copy of rows in display order
+func (dashboard *Dashboard) Rows() []*Row {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	return append([]*Row(nil), dashboard.rows...)
+}
+
+// Selected row, nil when there are no rows
+func (dashboard *Dashboard) Selected() *Row {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	if index := dashboard.index(); index >= 0 {
+		return dashboard.rows[index]
+	}
+
+	return nil
+}
+
+// Move selection by offset rows
+func (dashboard *Dashboard) Move(offset int) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	if len(dashboard.rows) == 0 {
+		return
+	}
+
+	index := dashboard.index() + offset
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(dashboard.rows) {
+		index = len(dashboard.rows) - 1
+	}
+
+	dashboard.selected = dashboard.rows[index].Link.Hash
+}
+
+// Activate inactive or deactivate active selected link with SetActive
+func (dashboard *Dashboard) Toggle() (errorResponse models.ErrorResponse) {
+	row := dashboard.Selected()
+	if row == nil {
+		return errorResponse
+	}
+
+	active := !row.Active()
+	_, errorResponse = dashboard.Client.SetActive(row.Link.Hash, &models.LinkActivationRequest{Active: active})
+	if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		dashboard.setStatus(errorResponse)
+		return errorResponse
+	}
+
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	// Rows are shared with Rows() callers, link is replaced instead of changed
+	link := *row.Link
+	link.Active = 0
+	dashboard.status = row.Link.Hash + " deactivated"
+	if active {
+		link.Active = 1
+		dashboard.status = row.Link.Hash + " activated"
+	}
+
+	for i, current := range dashboard.rows {
+		if current == row {
+			updated := *row
+			updated.Link = &link
+			dashboard.rows[i] = &updated
+		}
+	}
+
+	return errorResponse
+}
+
+func (dashboard *Dashboard) setStatus(errorResponse models.ErrorResponse) {
+	dashboard.mutex.Lock()
+	defer dashboard.mutex.Unlock()
+
+	dashboard.status = "request failed"
+	if len(errorResponse.Errors) > 0 {
+		dashboard.status = errorResponse.Errors[0]
+	}
+	for field, messages := range errorResponse.Validations {
+		if len(messages) > 0 {
+			dashboard.status = field + ": " + messages[0]
+			break
+		}
+	}
+}
+
+// Index of selected row, caller holds mutex
+func (dashboard *Dashboard) index() int {
+	for i, row := range dashboard.rows {
+		if row.Link.Hash == dashboard.selected {
+			return i
+		}
+	}
+
+	return -1
+}
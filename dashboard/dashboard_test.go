@@ -0,0 +1,158 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDashboard_Refresh(t *testing.T) {
+	now := time.Date(2022, 4, 1, 12, 0, 30, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+
+	links := []*models.LinkUserResponse{
+		{Hash: "quiet", Url: "https://test.com/quiet", Active: 1, Clicks: 100},
+		{Hash: "busy", Url: "https://test.com/busy", Active: 1, Clicks: 10},
+		{Hash: "old", Url: "https://test.com/old", Active: 0, Clicks: 500, ExpirationTime: &expired},
+		{Hash: "broken", Url: "https://test.com/broken", Active: 1},
+	}
+
+	stats := map[string][]*models.StatResponse{
+		"busy": {
+			{Ip: "1.1.1.1", Created: now.Add(-55 * time.Minute)},
+			{Ip: "1.1.1.2", Created: now.Add(-90 * time.Second)},
+			{Ip: "1.1.1.3", Created: now.Add(-10 * time.Second)},
+		},
+		"old": {
+			{Ip: "1.1.1.4", Created: now.Add(-10 * time.Minute)},
+		},
+	}
+
+	var mu sync.Mutex
+	var activations []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/client/url" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(&models.PaginatedLinkUserResponse{Data: links, Total: int64(len(links))})
+		case strings.HasPrefix(r.URL.Path, "/v1/client/stat/"):
+			hash := strings.TrimPrefix(r.URL.Path, "/v1/client/stat/")
+			if hash == "broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"errors":["Internal Server Error"]}`))
+				return
+			}
+			// Pages have at most 2 rows whatever limit is
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			from, to := (page-1)*2, page*2
+			if to > len(stats[hash]) {
+				to = len(stats[hash])
+			}
+			var data []*models.StatResponse
+			if from < to {
+				data = stats[hash][from:to]
+			}
+			_ = json.NewEncoder(w).Encode(&models.StatPaginatedResponse{Data: data, Total: int64(len(stats[hash]))})
+		default:
+			request := models.LinkActivationRequest{}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+
+			mu.Lock()
+			activations = append(activations, r.URL.Path+" "+strconv.FormatBool(request.Active))
+			mu.Unlock()
+			_, _ = w.Write([]byte("{}"))
+		}
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	board := New(client)
+	board.Buckets = 6
+	board.now = func() time.Time { return now }
+
+	if errorResponse := board.Refresh(); len(errorResponse.Errors) > 0 {
+		t.Fatalf("Refresh() errors = %v", errorResponse.Errors)
+	}
+
+	rows := board.Rows()
+
+	var order []string
+	for _, row := range rows {
+		order = append(order, row.Link.Hash)
+	}
+	if want := []string{"busy", "old", "quiet", "broken"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("Rows() = %v, want %v", order, want)
+	}
+
+	if busy := rows[0]; busy.Recent != 3 || !reflect.DeepEqual(busy.History, []float64{1, 0, 0, 0, 0, 2}) {
+		t.Errorf("busy = %+v", busy)
+	}
+	if old := rows[1]; !old.Expired || old.Active() {
+		t.Errorf("old = %+v", old)
+	}
+	if broken := rows[3]; broken.Err == "" {
+		t.Errorf("broken = %+v", broken)
+	}
+
+	// Selection follows hash, toggle deactivates busy
+	if selected := board.Selected(); selected == nil || selected.Link.Hash != "busy" {
+		t.Fatalf("Selected() = %+v", selected)
+	}
+
+	if _, errorResponse := board.Handle(KeyToggle); len(errorResponse.Errors) > 0 {
+		t.Fatalf("Handle() errors = %v", errorResponse.Errors)
+	}
+	if board.Selected().Active() || rows[0].Link.Active != 1 {
+		t.Errorf("Toggle() did not replace link")
+	}
+
+	board.Handle(KeyDown)
+	board.Handle(KeyDown)
+	board.Handle(KeyToggle)
+	board.Handle(KeyPageDown)
+	if selected := board.Selected(); selected.Link.Hash != "broken" {
+		t.Errorf("Selected() = %s", selected.Link.Hash)
+	}
+
+	if want := []string{"/v1/client/busy false", "/v1/client/quiet false"}; !reflect.DeepEqual(activations, want) {
+		t.Errorf("SetActive() calls = %v, want %v", activations, want)
+	}
+
+	if quit, _ := board.Handle(KeyQuit); !quit {
+		t.Errorf("Handle() expected quit")
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Key
+	}{
+		{name: "test_letters", input: "jkar q", want: []Key{KeyDown, KeyUp, KeyToggle, KeyRefresh, KeyToggle, KeyQuit}},
+		{name: "test_arrows", input: "\x1b[A\x1b[B\x1b[5~\x1b[6~", want: []Key{KeyUp, KeyDown, KeyPageUp, KeyPageDown}},
+		{name: "test_escape", input: "\x1b", want: []Key{KeyQuit}},
+		{name: "test_ctrl_c", input: "\x03", want: []Key{KeyQuit}},
+		{name: "test_unknown", input: "x\x1b[Cz", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package dashboard
+
+import "github.com/dmitrypro77/tinysrc-api-sdk/models"
+
+type Key string
+
+const (
+	KeyUp       Key = "up"
+	KeyDown     Key = "down"
+	KeyPageUp   Key = "page_up"
+	KeyPageDown Key = "page_down"
+	KeyToggle   Key = "toggle"
+	KeyRefresh  Key = "refresh"
+	KeyQuit     Key = "quit"
+)
+
+// Rows moved by KeyPageUp and KeyPageDown
+const PAGE_SIZE = 10
+
+// Decode keys of raw terminal input: arrows, vim keys, a or space, r, q, Esc and Ctrl+C.
+// Unknown bytes are skipped.
+func DecodeKeys(input []byte) []Key {
+	var keys []Key
+
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case 'k':
+			keys = append(keys, KeyUp)
+		case 'j':
+			keys = append(keys, KeyDown)
+		case 'a', ' ':
+			keys = append(keys, KeyToggle)
+		case 'r':
+			keys = append(keys, KeyRefresh)
+		case 'q', 0x03:
+			keys = append(keys, KeyQuit)
+		case 0x1b:
+			// CSI sequences: ESC [ A, ESC [ B, ESC [ 5 ~, ESC [ 6 ~
+			if i+2 < len(input) && input[i+1] == '[' {
+				switch input[i+2] {
+				case 'A':
+					keys = append(keys, KeyUp)
+				case 'B':
+					keys = append(keys, KeyDown)
+				case '5', '6':
+					if i+3 < len(input) && input[i+3] == '~' {
+						if input[i+2] == '5' {
+							keys = append(keys, KeyPageUp)
+						} else {
+							keys = append(keys, KeyPageDown)
+						}
+						i++
+					}
+				}
+				i += 2
+				continue
+			}
+
+			// Lone Esc
+			if i+1 == len(input) {
+				keys = append(keys, KeyQuit)
+			}
+		}
+	}
+
+	return keys
+}
+
+// Apply key, quit is true for KeyQuit
+func (dashboard *Dashboard) Handle(key Key) (quit bool, errorResponse models.ErrorResponse) {
+	switch key {
+	case KeyUp:
+		dashboard.Move(-1)
+	case KeyDown:
+		dashboard.Move(1)
+	case KeyPageUp:
+		dashboard.Move(-PAGE_SIZE)
+	case KeyPageDown:
+		dashboard.Move(PAGE_SIZE)
+	case KeyToggle:
+		errorResponse = dashboard.Toggle()
+	case KeyRefresh:
+		errorResponse = dashboard.Refresh()
+	case KeyQuit:
+		return true, errorResponse
+	}
+
+	return false, errorResponse
+}
@@ -0,0 +1,157 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ESCAPE_CLEAR   = "\x1b[H\x1b[2J"
+	ESCAPE_RESET   = "\x1b[0m"
+	ESCAPE_BOLD    = "\x1b[1m"
+	ESCAPE_DIM     = "\x1b[2m"
+	ESCAPE_REVERSE = "\x1b[7m"
+	ESCAPE_RED     = "\x1b[31m"
+	ESCAPE_YELLOW  = "\x1b[33m"
+)
+
+const HELP = "↑/↓ select  a toggle active  r refresh  q quit"
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline of values scaled to the max value, zero is the lowest bar
+func Sparkline(values []float64) string {
+	var peak float64
+	for _, value := range values {
+		if value > peak {
+			peak = value
+		}
+	}
+
+	var b strings.Builder
+	for _, value := range values {
+		index := 0
+		if peak > 0 && value > 0 {
+			index = int(value / peak * float64(len(sparks)-1))
+			if index == 0 {
+				// Any click is visible
+				index = 1
+			}
+		}
+		b.WriteRune(sparks[index])
+	}
+
+	return b.String()
+}
+
+// Write one frame of width columns and height lines, lines end with \r\n for raw terminals
+func (dashboard *Dashboard) Render(w io.Writer, width int, height int) error {
+	dashboard.mutex.Lock()
+	rows := dashboard.rows
+	index := dashboard.index()
+	updated := dashboard.updated
+	status := dashboard.status
+	dashboard.mutex.Unlock()
+
+	if width < 40 {
+		width = 40
+	}
+	if height < 5 {
+		height = 5
+	}
+
+	window := dashboard.Window
+	if window <= 0 {
+		window = DEFAULT_WINDOW
+	}
+
+	buckets := dashboard.Buckets
+	if buckets <= 0 {
+		buckets = DEFAULT_BUCKETS
+	}
+
+	var b strings.Builder
+	b.WriteString(ESCAPE_CLEAR)
+
+	header := fmt.Sprintf("tinysrc top - %d links, clicks of last %s", len(rows), window)
+	if !updated.IsZero() {
+		header += ", updated " + updated.Format("15:04:05")
+	}
+	line(&b, ESCAPE_BOLD, header, width)
+
+	columns := fmt.Sprintf("  %-12s %8s %8s %-8s %-*s %s", "HASH", "RECENT", "TOTAL", "STATE", buckets, "HISTORY", "URL")
+	line(&b, ESCAPE_REVERSE, columns, width)
+
+	// Rows scroll to keep selection visible
+	visible := height - 3
+	offset := 0
+	if index >= visible {
+		offset = index - visible + 1
+	}
+
+	for i := offset; i < len(rows) && i < offset+visible; i++ {
+		row := rows[i]
+
+		state, style := "active", ""
+		switch {
+		case row.Expired:
+			state, style = "expired", ESCAPE_RED
+		case !row.Active():
+			state, style = "inactive", ESCAPE_DIM
+		}
+
+		history := Sparkline(row.History)
+		if row.Err != "" {
+			history = strings.Repeat("?", buckets)
+			style += ESCAPE_YELLOW
+		}
+
+		marker := " "
+		if i == index {
+			marker = ">"
+			style += ESCAPE_REVERSE
+		}
+
+		text := fmt.Sprintf("%s %-12s %8d %8d %-8s %s%s %s",
+			marker, row.Link.Hash, row.Recent, row.Link.Clicks, state, history, strings.Repeat(" ", buckets-utf8.RuneCountInString(history)), row.Link.Url)
+		line(&b, style, text, width)
+	}
+
+	for i := len(rows) - offset; i < visible; i++ {
+		b.WriteString("\r\n")
+	}
+
+	footer := HELP
+	if status != "" {
+		footer = status + "  |  " + HELP
+	}
+	if len(rows) > visible {
+		footer = strconv.Itoa(index+1) + "/" + strconv.Itoa(len(rows)) + "  " + footer
+	}
+	b.WriteString(ESCAPE_DIM + truncate(footer, width) + ESCAPE_RESET)
+
+	_, e := io.WriteString(w, b.String())
+	return e
+}
+
+func line(b *strings.Builder, style string, text string, width int) {
+	b.WriteString(style)
+	b.WriteString(truncate(text, width))
+	if style != "" {
+		b.WriteString(ESCAPE_RESET)
+	}
+	b.WriteString("\r\n")
+}
+
+// Cut text to width runes, ellipsis marks cut text
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,70 @@
+package dashboard
+
+import (
+	"bytes"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   string
+	}{
+		{name: "test_empty", values: nil, want: ""},
+		{name: "test_zero", values: []float64{0, 0}, want: "▁▁"},
+		{name: "test_scaled", values: []float64{0, 1, 50, 100}, want: "▁▂▄█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values); got != tt.want {
+				t.Errorf("Sparkline() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDashboard_Render(t *testing.T) {
+	board := New(nil)
+	board.Buckets = 4
+	board.updated = time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	board.selected = "b"
+	board.status = "a deactivated"
+
+	for _, hash := range []string{"a", "b", "c", "d"} {
+		board.rows = append(board.rows, &Row{
+			Link:    &models.LinkUserResponse{Hash: hash, Url: "https://test.com/" + strings.Repeat(hash, 100), Active: 1, Clicks: 7},
+			Recent:  3,
+			History: []float64{0, 1, 0, 2},
+		})
+	}
+	board.rows[0].Link.Active = 0
+	board.rows[2].Expired = true
+
+	var b bytes.Buffer
+	if e := board.Render(&b, 60, 6); e != nil {
+		t.Fatalf("Render() error = %v", e)
+	}
+
+	lines := strings.Split(b.String(), "\r\n")
+	if len(lines) != 6 {
+		t.Fatalf("Render() has %d lines:\n%s", len(lines), b.String())
+	}
+
+	for i, want := range []string{
+		ESCAPE_CLEAR + ESCAPE_BOLD + "tinysrc top - 4 links, clicks of last 1h0m0s, updated 12:00",
+		ESCAPE_DIM + "  a                   3        7 inactive ▁▄▁█ https://test…" + ESCAPE_RESET,
+		ESCAPE_REVERSE + "> b                   3        7 active   ▁▄▁█ https://test…" + ESCAPE_RESET,
+		ESCAPE_RED + "  c                   3        7 expired  ▁▄▁█ https://test…" + ESCAPE_RESET,
+		ESCAPE_DIM + "2/4  a deactivated  |  " + "↑/↓ select",
+	} {
+		index := []int{0, 2, 3, 4, 5}[i]
+		if !strings.HasPrefix(lines[index], want) {
+			t.Errorf("Render() line %d = %q, want %q", index, lines[index], want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tinysrc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Value printed instead of secrets in debug output
+const REDACTED = models.REDACTED
+
+// JSON fields masked in debug output
+var redactedFields = map[string]bool{
+	"password":      true,
+	"stat_password": true,
+	"api_key":       true,
+}
+
+// Secret string fields of JSON which can not be decoded, e.g. truncated body
+var redactedPattern = func() *regexp.Regexp {
+	fields := make([]string, 0, len(redactedFields))
+	for field := range redactedFields {
+		fields = append(fields, regexp.QuoteMeta(field))
+	}
+	sort.Strings(fields)
+
+	return regexp.MustCompile(`"(` + strings.Join(fields, "|") + `)"\s*:\s*"(?:[^"\\]|\\.)+"?`)
+}()
+
+// Enable debug mode, every request and response is dumped to w.
+// Bodies longer than maxBodySize are truncated and only that much of response body is buffered,
+// DEBUG_BODY_LIMIT is used when maxBodySize <= 0.
+// Pass nil writer to disable debug mode.
+func (client *Client) SetDebug(w io.Writer, maxBodySize int) {
+	if maxBodySize <= 0 {
+		maxBodySize = DEBUG_BODY_LIMIT
+	}
+
+	client.debug = w
+	client.debugBodyLimit = maxBodySize
+}
+
+// Dump Outgoing Request
+func (client *Client) dumpRequest(req *http.Request) {
+	var body []byte
+
+	if req.GetBody != nil {
+		rc, e := req.GetBody()
+		if e == nil {
+			body, _ = io.ReadAll(rc)
+			_ = rc.Close()
+		}
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = nil
+	out.GetBody = nil
+	out.ContentLength = 0
+	if out.Header.Get("x-api-key") != "" {
+		out.Header.Set("x-api-key", REDACTED)
+	}
+
+	head, e := httputil.DumpRequestOut(out, false)
+	if e != nil {
+		_, _ = fmt.Fprintf(client.debug, "tinysrc: dump request: %s\n", e)
+		return
+	}
+
+	client.writeDump("request", head, body, int64(len(body)))
+}
+
+// Dump Incoming Response, only the dumped prefix of body is buffered and
+// put back in front of the rest, so the body can be decoded later
+func (client *Client) dumpResponse(resp *http.Response) {
+	body, e := io.ReadAll(io.LimitReader(resp.Body, int64(client.debugBodyLimit)+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	if e != nil {
+		_, _ = fmt.Fprintf(client.debug, "tinysrc: dump response: %s\n", e)
+		return
+	}
+
+	head, e := httputil.DumpResponse(resp, false)
+	if e != nil {
+		_, _ = fmt.Fprintf(client.debug, "tinysrc: dump response: %s\n", e)
+		return
+	}
+
+	client.writeDump("response", head, body, resp.ContentLength)
+}
+
+// Size is length of the whole body, -1 when unknown
+func (client *Client) writeDump(kind string, head []byte, body []byte, size int64) {
+	var buf bytes.Buffer
+
+	buf.WriteString("---- tinysrc " + kind + " ----\n")
+	buf.Write(bytes.TrimRight(head, "\r\n"))
+	buf.WriteString("\n\n")
+
+	if len(body) > 0 {
+		body = redactBody(body)
+
+		if len(body) > client.debugBodyLimit {
+			buf.Write(body[:client.debugBodyLimit])
+			if size > int64(client.debugBodyLimit) {
+				buf.WriteString(fmt.Sprintf("... (%d bytes truncated)", size-int64(client.debugBodyLimit)))
+			} else {
+				buf.WriteString("... (truncated)")
+			}
+		} else {
+			buf.Write(body)
+		}
+
+		buf.WriteString("\n")
+	}
+
+	_, _ = client.debug.Write(buf.Bytes())
+}
+
+// Mask secret fields of JSON body, secret string fields of body which is not valid JSON are masked by pattern
+func redactBody(body []byte) []byte {
+	var value interface{}
+
+	if e := json.Unmarshal(body, &value); e != nil {
+		return redactedPattern.ReplaceAll(body, []byte(`"$1":"`+REDACTED+`"`))
+	}
+
+	redacted, e := json.Marshal(redactValue(value))
+	if e != nil {
+		return body
+	}
+
+	return redacted
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if redactedFields[key] {
+				if s, ok := item.(string); ok && s == "" {
+					continue
+				}
+				v[key] = REDACTED
+				continue
+			}
+			v[key] = redactValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+
+	return value
+}
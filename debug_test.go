@@ -0,0 +1,127 @@
+package tinysrc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClient_SetDebug(t *testing.T) {
+	successLink := models.LinkResponse{
+		Url:          "http://test.com",
+		StatUrl:      "http://test.com/stat/122",
+		StatPassword: "stat-secret",
+		Password:     "link-secret",
+		AuthRequired: 1,
+	}
+
+	successLinkJson, _ := json.Marshal(&successLink)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, string(successLinkJson))
+	}))
+
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		limit       int
+		request     models.LinkRequest
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:    "test_redacted",
+			limit:   0,
+			request: models.LinkRequest{Url: "http://test.com", Password: "link-secret"},
+			contains: []string{
+				"POST /create HTTP/1.1",
+				"X-Api-Key: " + REDACTED,
+				"HTTP/1.1 200 OK",
+				`"password":"` + REDACTED + `"`,
+				`"stat_password":"` + REDACTED + `"`,
+			},
+			notContains: []string{"api-secret", "link-secret", "stat-secret"},
+		},
+		{
+			name:        "test_truncated",
+			limit:       10,
+			request:     models.LinkRequest{Url: "http://test.com"},
+			contains:    []string{"bytes truncated)"},
+			notContains: []string{"api-secret", "link-secret", "stat-secret"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			testClient, _ := NewClient(context.Background(), "api-secret", nil)
+			testClient.baseURL = &url.URL{
+				Path: ts.URL,
+			}
+			testClient.SetDebug(&out, tt.limit)
+
+			gotR, gotErrorResponse := testClient.CreateShortLink(tt.request)
+			if len(gotErrorResponse.Errors) > 0 {
+				t.Fatalf("CreateShortLink() gotErrorResponse = %v", gotErrorResponse)
+			}
+			if gotR.Password != successLink.Password {
+				t.Errorf("CreateShortLink() response body was not restored, got = %v", gotR)
+			}
+
+			for _, s := range tt.contains {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("SetDebug() output does not contain %q:\n%s", s, out.String())
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("SetDebug() output contains %q:\n%s", s, out.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_redactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "test_nested",
+			body: `{"data":[{"password":"1","url":"u"}],"total":1}`,
+			want: `{"data":[{"password":"` + REDACTED + `","url":"u"}],"total":1}`,
+		},
+		{
+			name: "test_empty_password",
+			body: `{"password":""}`,
+			want: `{"password":""}`,
+		},
+		{
+			name: "test_not_json",
+			body: "test 404",
+			want: "test 404",
+		},
+		{
+			name: "test_truncated_json",
+			body: `{"api_key": "k\"ey","password":"","stat_password":"sec`,
+			want: `{"api_key":"` + REDACTED + `","password":"","stat_password":"` + REDACTED + `"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactBody([]byte(tt.body))); got != tt.want {
+				t.Errorf("redactBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+// Package exporter exposes metrics of an account in Prometheus text format:
+// clicks, bots and state of every link, number of links and health of the
+// last scrape. Labels are limited by MaxLinks and allow/deny lists of hashes.
+//
+//	metrics := exporter.NewExporter(client)
+//	metrics.Deny = []string{"test-*"}
+//	go metrics.Run(ctx, time.Minute)
+//	http.Handle("/metrics", metrics)
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Links with labels, links with fewer clicks above the limit are counted by tinysrc_links_dropped
+const DEFAULT_MAX_LINKS = 1000
+
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+type Exporter struct {
+	Client *tinysrc.Client
+	// Search query of GetListUrls
+	Query string
+	// Glob patterns of exported hashes, all hashes when empty
+	Allow []string
+	// Glob patterns of hashes which are never exported, Deny wins over Allow
+	Deny []string
+	// Limit of link label values, DEFAULT_MAX_LINKS when zero
+	MaxLinks int
+	// Called for every failed scrape of Run, optional
+	OnError func(e error)
+
+	mutex   sync.Mutex
+	scrape  *scrape
+	success time.Time
+	errors  int64
+	scrapes int64
+}
+
+// Result of one scrape
+type scrape struct {
+	user     *models.CurrentUserResponse
+	links    []*models.LinkUserResponse
+	total    int64
+	active   int64
+	clicks   int64
+	bots     int64
+	dropped  int64
+	filtered int64
+	duration time.Duration
+	err      error
+}
+
+func NewExporter(client *tinysrc.Client) *Exporter {
+	return &Exporter{Client: client, MaxLinks: DEFAULT_MAX_LINKS}
+}
+
+// Hash passes allow and deny lists
+func (exporter *Exporter) Exported(hash string) bool {
+	for _, pattern := range exporter.Deny {
+		if matched, _ := path.Match(pattern, hash); matched {
+			return false
+		}
+	}
+
+	if len(exporter.Allow) == 0 {
+		return true
+	}
+
+	for _, pattern := range exporter.Allow {
+		if matched, _ := path.Match(pattern, hash); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Load account with GetCurrentUser and links with GetListUrls, metrics of failed scrape keep values of the last successful one
+func (exporter *Exporter) Scrape() error {
+	started := time.Now()
+	result := &scrape{}
+
+	user, errorResponse := exporter.Client.GetCurrentUser()
+	if user == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		result.err = responseError("GetCurrentUser", errorResponse)
+	}
+
+	var links []*models.LinkUserResponse
+	if result.err == nil {
+		links, errorResponse = exporter.Client.GetAllUrls(exporter.Query, 0)
+		if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			result.err = responseError("GetListUrls", errorResponse)
+		}
+	}
+
+	result.duration = time.Since(started)
+
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	exporter.scrapes++
+
+	if result.err != nil {
+		exporter.errors++
+		if exporter.scrape != nil {
+			// Values of the last successful scrape are kept
+			failed := *exporter.scrape
+			failed.err, failed.duration = result.err, result.duration
+			result = &failed
+		}
+		exporter.scrape = result
+		return result.err
+	}
+
+	result.user = user
+
+	var exported []*models.LinkUserResponse
+	for _, link := range links {
+		result.total++
+		result.clicks += link.Clicks
+		result.bots += link.Bots
+		if link.Active != 0 {
+			result.active++
+		}
+
+		if exporter.Exported(link.Hash) {
+			exported = append(exported, link)
+		} else {
+			result.filtered++
+		}
+	}
+
+	// Links with the most clicks keep their labels
+	sort.SliceStable(exported, func(i, j int) bool {
+		if exported[i].Clicks != exported[j].Clicks {
+			return exported[i].Clicks > exported[j].Clicks
+		}
+		return exported[i].Hash < exported[j].Hash
+	})
+
+	limit := exporter.MaxLinks
+	if limit <= 0 {
+		limit = DEFAULT_MAX_LINKS
+	}
+	if len(exported) > limit {
+		result.dropped = int64(len(exported) - limit)
+		exported = exported[:limit]
+	}
+
+	sort.SliceStable(exported, func(i, j int) bool { return exported[i].Hash < exported[j].Hash })
+	result.links = exported
+
+	exporter.scrape = result
+	exporter.success = time.Now()
+
+	return nil
+}
+
+// Scrape every interval until ctx is done
+func (exporter *Exporter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if e := exporter.Scrape(); e != nil && exporter.OnError != nil {
+			exporter.OnError(e)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (exporter *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	_ = exporter.WriteMetrics(w)
+}
+
+// Write metrics of the last scrape in Prometheus text format
+func (exporter *Exporter) WriteMetrics(w io.Writer) error {
+	exporter.mutex.Lock()
+	result := exporter.scrape
+	success := exporter.success
+	errors := exporter.errors
+	scrapes := exporter.scrapes
+	exporter.mutex.Unlock()
+
+	var b strings.Builder
+	writer := &metricWriter{b: &b}
+
+	if result != nil && result.user != nil {
+		writer.family("tinysrc_user_info", "gauge", "Account of API key, value is always 1.")
+		writer.sample("tinysrc_user_info", []string{"username", result.user.Username, "plan", strconv.Itoa(result.user.Plan)}, 1)
+		writer.family("tinysrc_user_active", "gauge", "1 when account is active.")
+		writer.sample("tinysrc_user_active", nil, float64(result.user.Active))
+		writer.family("tinysrc_user_banned", "gauge", "1 when account is banned.")
+		writer.sample("tinysrc_user_banned", nil, float64(result.user.Banned))
+
+		writer.family("tinysrc_links_total", "gauge", "Links of account.")
+		writer.sample("tinysrc_links_total", nil, float64(result.total))
+		writer.family("tinysrc_links_active", "gauge", "Active links of account.")
+		writer.sample("tinysrc_links_active", nil, float64(result.active))
+		writer.family("tinysrc_clicks_total", "gauge", "Clicks of all links of account.")
+		writer.sample("tinysrc_clicks_total", nil, float64(result.clicks))
+		writer.family("tinysrc_bots_total", "gauge", "Bot clicks of all links of account.")
+		writer.sample("tinysrc_bots_total", nil, float64(result.bots))
+		writer.family("tinysrc_links_filtered", "gauge", "Links excluded by allow and deny lists.")
+		writer.sample("tinysrc_links_filtered", nil, float64(result.filtered))
+		writer.family("tinysrc_links_dropped", "gauge", "Links without labels because of the cardinality limit.")
+		writer.sample("tinysrc_links_dropped", nil, float64(result.dropped))
+
+		writer.family("tinysrc_link_clicks_total", "gauge", "Clicks of link.")
+		for _, link := range result.links {
+			writer.sample("tinysrc_link_clicks_total", []string{"hash", link.Hash}, float64(link.Clicks))
+		}
+		writer.family("tinysrc_link_bots_total", "gauge", "Bot clicks of link.")
+		for _, link := range result.links {
+			writer.sample("tinysrc_link_bots_total", []string{"hash", link.Hash}, float64(link.Bots))
+		}
+		writer.family("tinysrc_link_active", "gauge", "1 when link is active.")
+		for _, link := range result.links {
+			writer.sample("tinysrc_link_active", []string{"hash", link.Hash}, float64(link.Active))
+		}
+	}
+
+	writer.family("tinysrc_scrape_success", "gauge", "1 when the last scrape succeeded.")
+	succeeded := 0.0
+	if result != nil && result.err == nil {
+		succeeded = 1
+	}
+	writer.sample("tinysrc_scrape_success", nil, succeeded)
+
+	if result != nil {
+		writer.family("tinysrc_scrape_duration_seconds", "gauge", "Duration of the last scrape.")
+		writer.sample("tinysrc_scrape_duration_seconds", nil, result.duration.Seconds())
+	}
+	if !success.IsZero() {
+		writer.family("tinysrc_scrape_last_success_timestamp_seconds", "gauge", "Unix time of the last successful scrape.")
+		writer.sample("tinysrc_scrape_last_success_timestamp_seconds", nil, float64(success.UnixNano())/1e9)
+	}
+
+	writer.family("tinysrc_scrapes_total", "counter", "Scrapes since start.")
+	writer.sample("tinysrc_scrapes_total", nil, float64(scrapes))
+	writer.family("tinysrc_scrape_errors_total", "counter", "Failed scrapes since start.")
+	writer.sample("tinysrc_scrape_errors_total", nil, float64(errors))
+
+	_, e := io.WriteString(w, b.String())
+	return e
+}
+
+type metricWriter struct {
+	b *strings.Builder
+}
+
+func (writer *metricWriter) family(name string, kind string, help string) {
+	fmt.Fprintf(writer.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Sample with labels as name, value pairs
+func (writer *metricWriter) sample(name string, labels []string, value float64) {
+	writer.b.WriteString(name)
+
+	if len(labels) > 0 {
+		writer.b.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				writer.b.WriteString(",")
+			}
+			writer.b.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+		}
+		writer.b.WriteString("}")
+	}
+
+	writer.b.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func responseError(method string, errorResponse models.ErrorResponse) error {
+	messages := append([]string(nil), errorResponse.Errors...)
+	for field, validations := range errorResponse.Validations {
+		messages = append(messages, field+": "+strings.Join(validations, ", "))
+	}
+	if len(messages) == 0 {
+		messages = append(messages, "empty response")
+	}
+
+	return fmt.Errorf("exporter: %s: %s", method, strings.Join(messages, "; "))
+}
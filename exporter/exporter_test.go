@@ -0,0 +1,188 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExporter_Exported(t *testing.T) {
+	exporter := &Exporter{Allow: []string{"promo-*", "landing"}, Deny: []string{"promo-test*"}}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "test_allowed_pattern", hash: "promo-spring", want: true},
+		{name: "test_allowed_hash", hash: "landing", want: true},
+		{name: "test_denied", hash: "promo-test1", want: false},
+		{name: "test_not_allowed", hash: "other", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exporter.Exported(tt.hash); got != tt.want {
+				t.Errorf("Exported() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !(&Exporter{Deny: []string{"a"}}).Exported("b") {
+		t.Errorf("Exported() without allow list = false")
+	}
+}
+
+func TestExporter_Scrape(t *testing.T) {
+	var mu sync.Mutex
+	failed := false
+
+	links := []*models.LinkUserResponse{
+		{Hash: "a", Clicks: 10, Bots: 1, Active: 1},
+		{Hash: "b", Clicks: 30, Bots: 2, Active: 0},
+		{Hash: "c", Clicks: 20, Bots: 3, Active: 1},
+		{Hash: "test-1", Clicks: 100, Bots: 0, Active: 1},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case failed:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":["Internal Server Error"]}`))
+		case r.URL.Path == "/v1/client/user":
+			_ = json.NewEncoder(w).Encode(&models.CurrentUserResponse{Username: `user "one"`, Active: 1, Plan: 2})
+		case r.URL.Path == "/v1/client/url":
+			var data []*models.LinkUserResponse
+			if r.URL.Query().Get("page") == "1" {
+				data = links
+			}
+			_ = json.NewEncoder(w).Encode(&models.PaginatedLinkUserResponse{Data: data, Total: int64(len(links))})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	exporter := NewExporter(client)
+	exporter.Deny = []string{"test-*"}
+	exporter.MaxLinks = 2
+
+	// Nothing is scraped yet
+	var b strings.Builder
+	_ = exporter.WriteMetrics(&b)
+	if !strings.Contains(b.String(), "tinysrc_scrape_success 0\n") || strings.Contains(b.String(), "tinysrc_links_total") {
+		t.Errorf("WriteMetrics() before scrape = %s", b.String())
+	}
+
+	if e := exporter.Scrape(); e != nil {
+		t.Fatalf("Scrape() error = %v", e)
+	}
+
+	want := []string{
+		"# HELP tinysrc_link_clicks_total Clicks of link.\n# TYPE tinysrc_link_clicks_total gauge\n",
+		`tinysrc_user_info{username="user \"one\"",plan="2"} 1` + "\n",
+		"tinysrc_links_total 4\n",
+		"tinysrc_links_active 3\n",
+		"tinysrc_clicks_total 160\n",
+		"tinysrc_links_filtered 1\n",
+		"tinysrc_links_dropped 1\n",
+		// Link a has the fewest clicks and is dropped by the limit
+		"tinysrc_link_clicks_total{hash=\"b\"} 30\ntinysrc_link_clicks_total{hash=\"c\"} 20\n",
+		"tinysrc_link_bots_total{hash=\"b\"} 2\n",
+		"tinysrc_link_active{hash=\"b\"} 0\ntinysrc_link_active{hash=\"c\"} 1\n",
+		"tinysrc_scrape_success 1\n",
+		"tinysrc_scrape_errors_total 0\n",
+	}
+
+	got := metrics(t, exporter)
+	for _, line := range want {
+		if !strings.Contains(got, line) {
+			t.Errorf("metrics do not contain %q:\n%s", line, got)
+		}
+	}
+	for _, hash := range []string{`hash="a"`, `hash="test-1"`} {
+		if strings.Contains(got, hash) {
+			t.Errorf("metrics contain %s", hash)
+		}
+	}
+
+	// Failed scrape keeps values of the last successful one
+	mu.Lock()
+	failed = true
+	mu.Unlock()
+
+	if e := exporter.Scrape(); e == nil || !strings.Contains(e.Error(), "Internal Server Error") {
+		t.Errorf("Scrape() error = %v", e)
+	}
+
+	got = metrics(t, exporter)
+	for _, line := range []string{"tinysrc_scrape_success 0\n", "tinysrc_scrape_errors_total 1\n", "tinysrc_scrapes_total 2\n", "tinysrc_links_total 4\n", "tinysrc_scrape_last_success_timestamp_seconds "} {
+		if !strings.Contains(got, line) {
+			t.Errorf("metrics do not contain %q:\n%s", line, got)
+		}
+	}
+}
+
+func metrics(t *testing.T, exporter *Exporter) string {
+	r := httptest.NewRecorder()
+	exporter.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if r.Code != http.StatusOK || r.Header().Get("Content-Type") != CONTENT_TYPE {
+		t.Fatalf("ServeHTTP() = %d %s", r.Code, r.Header().Get("Content-Type"))
+	}
+
+	return r.Body.String()
+}
+
+func TestExporter_Run(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errors":["Internal Server Error"]}`))
+	}))
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	exporter := NewExporter(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	failures := make(chan error, 10)
+	exporter.OnError = func(e error) {
+		failures <- e
+		if len(failures) >= 2 {
+			cancel()
+		}
+	}
+
+	if e := exporter.Run(ctx, time.Millisecond); !errors.Is(e, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", e)
+	}
+
+	// Scrape starts immediately and repeats every interval, ticker may win once more over cancel
+	count := len(failures)
+	if count < 2 || !strings.Contains((<-failures).Error(), "Internal Server Error") {
+		t.Errorf("OnError() failures = %d", count)
+	}
+
+	var b strings.Builder
+	_ = exporter.WriteMetrics(&b)
+	if !strings.Contains(b.String(), "tinysrc_scrape_errors_total "+strconv.Itoa(count)+"\n") {
+		t.Errorf("WriteMetrics() = %s", b.String())
+	}
+}
@@ -0,0 +1,201 @@
+// Package funcmap provides template functions shortening links at render time.
+// Short links are memoized, so every destination is sent to the API once even
+// when templates are rendered concurrently. In fail-open mode the original URL
+// is rendered when the API is unavailable.
+//
+//	provider := funcmap.New(client)
+//	provider.FailOpen = true
+//	provider.Campaign = campaign.Campaign{Medium: "email", Name: "spring_sale"}
+//	tmpl := template.Must(template.New("email").Funcs(provider.FuncMap()).Parse(
+//		`<a href="{{ shorten .URL }}">Shop</a> <a href="{{ shortenWithUTM .URL "newsletter" }}">Track</a> <img src="{{ qrcode .URL }}">`))
+package funcmap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/campaign"
+	"github.com/dmitrypro77/tinysrc-api-sdk/idempotent"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/qrcode"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+type Provider struct {
+	Client *tinysrc.Client
+	// Links are created only once per destination across runs when set
+	Creator *idempotent.Creator
+	// Render original URL instead of failing template when API is unavailable (transport
+	// error or 5xx), destinations rejected by API (4xx) always fail template
+	FailOpen bool
+	// Called with every failed destination, also in fail-open mode
+	OnError func(rawURL string, errorResponse models.ErrorResponse)
+	// Defaults of shortenWithUTM, Source is set by template
+	Campaign campaign.Campaign
+	// Error correction level and rendering of qrcode
+	QRLevel   qrcode.Level
+	QROptions qrcode.Options
+
+	mutex sync.Mutex
+	cache map[string]string
+	calls map[string]*call
+}
+
+// Request of destination in flight, concurrent callers wait for it
+type call struct {
+	done          chan struct{}
+	short         string
+	errorResponse models.ErrorResponse
+}
+
+func New(client *tinysrc.Client) *Provider {
+	return &Provider{Client: client, QRLevel: qrcode.Medium}
+}
+
+// Functions shorten, shortenWithUTM and qrcode for text/template and html/template
+func (provider *Provider) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"shorten":        provider.Shorten,
+		"shortenWithUTM": provider.ShortenWithUTM,
+		"qrcode":         provider.QRCode,
+	}
+}
+
+// Short link of URL with utm_source, optional values override medium and campaign name of Provider.Campaign
+func (provider *Provider) ShortenWithUTM(rawURL string, source string, values ...string) (string, error) {
+	if len(values) > 2 {
+		return "", fmt.Errorf("funcmap: shortenWithUTM accepts source, medium and campaign, got %d values", len(values)+1)
+	}
+
+	builder := &campaign.Builder{Url: rawURL, Campaign: provider.Campaign}
+	builder.Source = source
+	if len(values) > 0 {
+		builder.Medium = values[0]
+	}
+	if len(values) > 1 {
+		builder.Name = values[1]
+	}
+
+	// Invalid campaign is an error of template, it is not hidden by fail-open mode
+	destination, e := builder.Build()
+	if e != nil {
+		return "", e
+	}
+
+	return provider.Shorten(destination)
+}
+
+// PNG data URI of QR code of short link, usable as src of img in html/template
+func (provider *Provider) QRCode(rawURL string) (htmltemplate.URL, error) {
+	short, e := provider.Shorten(rawURL)
+	if e != nil {
+		return "", e
+	}
+
+	code, e := qrcode.Encode(short, provider.QRLevel)
+	if e != nil {
+		return "", e
+	}
+
+	var b bytes.Buffer
+	if e = code.PNG(&b, provider.QROptions); e != nil {
+		return "", e
+	}
+
+	return htmltemplate.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes())), nil
+}
+
+// Memoized short link of URL, failed destinations are not cached and are requested again
+func (provider *Provider) Shorten(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+
+	provider.mutex.Lock()
+	if short, found := provider.cache[rawURL]; found {
+		provider.mutex.Unlock()
+		return short, nil
+	}
+
+	c := provider.calls[rawURL]
+	if c == nil {
+		c = &call{done: make(chan struct{})}
+		if provider.calls == nil {
+			provider.calls = make(map[string]*call)
+		}
+		provider.calls[rawURL] = c
+		provider.mutex.Unlock()
+
+		provider.do(rawURL, c)
+
+		if c.short == "" && provider.OnError != nil {
+			provider.OnError(rawURL, c.errorResponse)
+		}
+	} else {
+		provider.mutex.Unlock()
+		<-c.done
+	}
+
+	if c.short != "" {
+		return c.short, nil
+	}
+
+	if provider.FailOpen && (c.errorResponse.Status == 0 || c.errorResponse.Status >= http.StatusInternalServerError) {
+		return rawURL, nil
+	}
+
+	messages := append([]string(nil), c.errorResponse.Errors...)
+	for field, validations := range c.errorResponse.Validations {
+		messages = append(messages, field+": "+strings.Join(validations, ", "))
+	}
+
+	return "", fmt.Errorf("funcmap: %s: %s", rawURL, strings.Join(messages, "; "))
+}
+
+// Create link of call, waiting callers are released even when create panics
+func (provider *Provider) do(rawURL string, c *call) {
+	defer func() {
+		if c.short == "" && len(c.errorResponse.Errors) == 0 && len(c.errorResponse.Validations) == 0 {
+			c.errorResponse.Errors = append(c.errorResponse.Errors, "link was not created")
+		}
+
+		provider.mutex.Lock()
+		delete(provider.calls, rawURL)
+		if c.short != "" {
+			if provider.cache == nil {
+				provider.cache = make(map[string]string)
+			}
+			provider.cache[rawURL] = c.short
+		}
+		provider.mutex.Unlock()
+		close(c.done)
+	}()
+
+	c.short, c.errorResponse = provider.create(rawURL)
+}
+
+func (provider *Provider) create(rawURL string) (string, models.ErrorResponse) {
+	var link *models.LinkResponse
+	var errorResponse models.ErrorResponse
+
+	request := models.LinkRequest{Url: rawURL}
+	if provider.Creator != nil {
+		link, _, errorResponse = provider.Creator.Create(request)
+	} else {
+		link, errorResponse = provider.Client.CreateShortLink(request)
+	}
+
+	if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return "", errorResponse
+	}
+
+	if link == nil || link.Url == "" {
+		errorResponse.Errors = append(errorResponse.Errors, "empty response")
+		return "", errorResponse
+	}
+
+	return link.Url, errorResponse
+}
@@ -0,0 +1,200 @@
+package funcmap
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/campaign"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	htmltemplate "html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+)
+
+type testServer struct {
+	mu       sync.Mutex
+	requests []string
+	failed   bool
+}
+
+func (server *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := models.LinkRequest{}
+	_ = json.NewDecoder(r.Body).Decode(&request)
+
+	// Concurrent renders wait for the same request
+	time.Sleep(10 * time.Millisecond)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.requests = append(server.requests, request.Url)
+
+	if strings.Contains(request.Url, "invalid") {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"validations":{"url":["invalid url"]}}`))
+		return
+	}
+
+	if server.failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"errors":["Service Unavailable"]}`))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(&models.LinkResponse{Url: tinysrc.ShortUrl("h" + strconv.Itoa(len(server.requests)))})
+}
+
+func (server *testServer) count() int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return len(server.requests)
+}
+
+func newTestProvider(t *testing.T) (*Provider, *testServer) {
+	server := &testServer{}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	return New(client), server
+}
+
+func TestProvider_FuncMap(t *testing.T) {
+	provider, server := newTestProvider(t)
+	provider.Campaign = campaign.Campaign{Medium: "email", Name: "spring"}
+
+	tmpl := template.Must(template.New("email").Funcs(provider.FuncMap()).Parse(
+		`{{ shorten .URL }} {{ shorten .URL }} {{ shortenWithUTM .URL "Newsletter" }} {{ shortenWithUTM .URL "newsletter" "social" }}`))
+
+	var b strings.Builder
+	if e := tmpl.Execute(&b, map[string]string{"URL": "https://test.com/a"}); e != nil {
+		t.Fatalf("Execute() error = %v", e)
+	}
+
+	if b.String() != "https://tinysrc.me/h1 https://tinysrc.me/h1 https://tinysrc.me/h2 https://tinysrc.me/h3" {
+		t.Errorf("Execute() = %s", b.String())
+	}
+
+	want := []string{
+		"https://test.com/a",
+		"https://test.com/a?utm_source=newsletter&utm_medium=email&utm_campaign=spring",
+		"https://test.com/a?utm_source=newsletter&utm_medium=social&utm_campaign=spring",
+	}
+	server.mu.Lock()
+	if strings.Join(server.requests, " ") != strings.Join(want, " ") {
+		t.Errorf("requests = %v, want %v", server.requests, want)
+	}
+	server.mu.Unlock()
+
+	// Campaign without name is an error of template
+	provider.Campaign = campaign.Campaign{}
+	if e := tmpl.Execute(&strings.Builder{}, map[string]string{"URL": "https://test.com/b"}); e == nil || !strings.Contains(e.Error(), "utm_medium is required") {
+		t.Errorf("Execute() error = %v", e)
+	}
+}
+
+func TestProvider_QRCode(t *testing.T) {
+	provider, _ := newTestProvider(t)
+
+	tmpl := htmltemplate.Must(htmltemplate.New("email").Funcs(provider.FuncMap()).Parse(`<a href="{{ shorten .URL }}"><img src="{{ qrcode .URL }}"></a>`))
+
+	var b strings.Builder
+	if e := tmpl.Execute(&b, map[string]string{"URL": "https://test.com/a"}); e != nil {
+		t.Fatalf("Execute() error = %v", e)
+	}
+
+	if !strings.HasPrefix(b.String(), `<a href="https://tinysrc.me/h1"><img src="data:image/png;base64,`) {
+		t.Errorf("Execute() = %s", b.String())
+	}
+}
+
+func TestProvider_Shorten(t *testing.T) {
+	provider, server := newTestProvider(t)
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = provider.Shorten("https://test.com/a")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != "https://tinysrc.me/h1" {
+			t.Errorf("Shorten() = %s", result)
+		}
+	}
+	if server.count() != 1 {
+		t.Errorf("requests = %d, want 1", server.count())
+	}
+
+	server.mu.Lock()
+	server.failed = true
+	server.mu.Unlock()
+
+	var failures []string
+	provider.OnError = func(rawURL string, errorResponse models.ErrorResponse) {
+		failures = append(failures, rawURL+" "+strings.Join(errorResponse.Errors, ","))
+	}
+
+	if _, e := provider.Shorten("https://test.com/b"); e == nil || e.Error() != "funcmap: https://test.com/b: Service Unavailable" {
+		t.Errorf("Shorten() error = %v", e)
+	}
+
+	// Fail-open renders original URL, failed URL is requested again
+	provider.FailOpen = true
+	if got, e := provider.Shorten("https://test.com/b"); got != "https://test.com/b" || e != nil {
+		t.Errorf("Shorten() fail-open = %s, %v", got, e)
+	}
+	if got, _ := provider.Shorten("https://test.com/a"); got != "https://tinysrc.me/h1" {
+		t.Errorf("Shorten() cached = %s", got)
+	}
+
+	// Destination rejected by API fails also in fail-open mode
+	if _, e := provider.Shorten("https://test.com/invalid"); e == nil || e.Error() != "funcmap: https://test.com/invalid: url: invalid url" {
+		t.Errorf("Shorten() rejected error = %v", e)
+	}
+
+	if server.count() != 4 || len(failures) != 3 || failures[0] != "https://test.com/b Service Unavailable" {
+		t.Errorf("requests = %d, failures = %v", server.count(), failures)
+	}
+}
+
+func TestProvider_ShortenPanic(t *testing.T) {
+	// Client is missing, so create panics
+	provider := New(nil)
+
+	shorten := func() (recovered interface{}) {
+		defer func() { recovered = recover() }()
+		_, _ = provider.Shorten("https://test.com/a")
+		return nil
+	}
+
+	if shorten() == nil {
+		t.Fatalf("Shorten() expected panic")
+	}
+
+	// Later callers do not wait for call which panicked
+	done := make(chan interface{})
+	go func() { done <- shorten() }()
+
+	select {
+	case recovered := <-done:
+		if recovered == nil {
+			t.Errorf("Shorten() expected panic")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Shorten() waits for call which panicked")
+	}
+}
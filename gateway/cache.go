@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Encoded responses of read endpoints shared by all callers, keys are "hash link" and "hash stat query"
+type cache struct {
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+func (cache *cache) get(key string, now time.Time) []byte {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := cache.entries[key]
+	if entry == nil {
+		return nil
+	}
+
+	if !now.Before(entry.expires) {
+		delete(cache.entries, key)
+		return nil
+	}
+
+	return entry.body
+}
+
+// Store body for ttl, expired entries are dropped when cache is full and arbitrary ones when none is expired
+func (cache *cache) put(key string, body []byte, now time.Time, ttl time.Duration, size int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.entries == nil {
+		cache.entries = map[string]*cacheEntry{}
+	}
+
+	if _, found := cache.entries[key]; !found && len(cache.entries) >= size {
+		for other, entry := range cache.entries {
+			if !now.Before(entry.expires) {
+				delete(cache.entries, other)
+			}
+		}
+
+		for other := range cache.entries {
+			if len(cache.entries) < size {
+				break
+			}
+			delete(cache.entries, other)
+		}
+	}
+
+	cache.entries[key] = &cacheEntry{body: body, expires: now.Add(ttl)}
+}
+
+// Drop entries with key prefix
+func (cache *cache) invalidate(prefix string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for key := range cache.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(cache.entries, key)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Service calling gateway with its own token
+type Caller struct {
+	Name  string        `json:"name"`
+	Token models.Secret `json:"token"`
+	// Requests per quota window, unlimited when zero
+	Quota int `json:"quota,omitempty"`
+}
+
+// Requests of caller in the current quota window
+type usage struct {
+	start time.Time
+	count int
+}
+
+// Read JSON array of callers
+func LoadCallers(path string) ([]*Caller, error) {
+	data, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	var callers []*Caller
+	if e = json.Unmarshal(data, &callers); e != nil {
+		return nil, e
+	}
+
+	names := map[string]bool{}
+	for _, caller := range callers {
+		if caller.Name == "" || caller.Token == "" {
+			return nil, errors.New("gateway: caller without name or token")
+		}
+		if names[caller.Name] {
+			return nil, errors.New("gateway: duplicate caller " + caller.Name)
+		}
+		names[caller.Name] = true
+	}
+
+	return callers, nil
+}
+
+// Caller of bearer token, nil when token is unknown
+func (gateway *Gateway) authenticate(r *http.Request) *Caller {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return nil
+	}
+
+	var matched *Caller
+	for _, caller := range gateway.Callers {
+		// Every token is compared, so timing does not tell which caller matched
+		if subtle.ConstantTimeCompare([]byte(caller.Token.Reveal()), []byte(token)) == 1 {
+			matched = caller
+		}
+	}
+
+	return matched
+}
+
+// Count request of caller, remaining requests and time until reset are returned when quota is exceeded
+func (gateway *Gateway) take(caller *Caller) (remaining int, reset time.Duration, allowed bool) {
+	if caller.Quota <= 0 {
+		return -1, 0, true
+	}
+
+	window := gateway.QuotaWindow
+	if window <= 0 {
+		window = DEFAULT_QUOTA_WINDOW
+	}
+
+	now := gateway.time()
+
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	if gateway.usage == nil {
+		gateway.usage = map[string]*usage{}
+	}
+
+	current := gateway.usage[caller.Name]
+	if current == nil || !now.Before(current.start.Add(window)) {
+		current = &usage{start: now.Truncate(window)}
+		gateway.usage[caller.Name] = current
+	}
+
+	reset = current.start.Add(window).Sub(now)
+	if current.count >= caller.Quota {
+		return 0, reset, false
+	}
+
+	current.count++
+	return caller.Quota - current.count, reset, true
+}
@@ -0,0 +1,446 @@
+// Package gateway is an HTTP service which holds the API key and exposes
+// shortening to internal services. Callers authenticate with their own bearer
+// tokens and have request quotas, reads are cached, every request is written
+// to the audit log and errors are JSON of models.ErrorResponse.
+//
+//	POST  /v1/links              {"url": "https://test.com"}
+//	GET   /v1/links/{hash}
+//	PATCH /v1/links/{hash}       {"active": false}
+//	GET   /v1/links/{hash}/stats ?date-start=2022-04-01T00:00:00Z&date-end=2022-04-02T00:00:00Z&limit=100&page=1
+//
+//	gw := gateway.New(client, &gateway.Caller{Name: "billing", Token: "token", Quota: 1000})
+//	http.ListenAndServe(":8080", gw)
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/idempotent"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_QUOTA_WINDOW = time.Hour
+const DEFAULT_CACHE_TTL = time.Minute
+const DEFAULT_CACHE_SIZE = 1000
+const DEFAULT_STAT_RANGE = 24 * time.Hour
+const DEFAULT_STAT_LIMIT = 100
+
+// Limit of request body
+const MAX_BODY_SIZE = 64 << 10
+
+var hashPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type Gateway struct {
+	Client  *tinysrc.Client
+	Callers []*Caller
+	// Links are created only once per destination when set
+	Creator *idempotent.Creator
+	// Quota window of callers, DEFAULT_QUOTA_WINDOW when zero
+	QuotaWindow time.Duration
+	// Lifetime of cached links and statistics, reads are not cached when negative
+	CacheTTL  time.Duration
+	CacheSize int
+	// Audit log, slog.Default() when nil
+	Logger *slog.Logger
+
+	mutex sync.Mutex
+	usage map[string]*usage
+	cache cache
+	now   func() time.Time
+}
+
+func New(client *tinysrc.Client, callers ...*Caller) *Gateway {
+	return &Gateway{
+		Client:      client,
+		Callers:     callers,
+		QuotaWindow: DEFAULT_QUOTA_WINDOW,
+		CacheTTL:    DEFAULT_CACHE_TTL,
+		CacheSize:   DEFAULT_CACHE_SIZE,
+	}
+}
+
+// Fields of audit log record filled by handlers
+type auditRecord struct {
+	caller string
+	hash   string
+	cached bool
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, record *auditRecord)
+
+func (gateway *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	started := gateway.time()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	record := &auditRecord{}
+
+	gateway.serve(recorder, r, record)
+
+	logger := gateway.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	level := slog.LevelInfo
+	if recorder.status >= 500 {
+		level = slog.LevelError
+	} else if recorder.status >= 400 {
+		level = slog.LevelWarn
+	}
+
+	logger.LogAttrs(r.Context(), level, "gateway request",
+		slog.String("caller", record.caller),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("hash", record.hash),
+		slog.Int("status", recorder.status),
+		slog.Bool("cached", record.cached),
+		slog.Duration("duration", gateway.time().Sub(started)),
+		slog.String("remote", r.RemoteAddr),
+	)
+}
+
+func (gateway *Gateway) serve(w http.ResponseWriter, r *http.Request, record *auditRecord) {
+	if r.URL.Path == "/healthz" {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	caller := gateway.authenticate(r)
+	if caller == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, newErrorResponse("Unauthorized"))
+		return
+	}
+	record.caller = caller.Name
+
+	handler, allow := gateway.route(r, record)
+	if handler == nil {
+		if allow != "" {
+			w.Header().Set("Allow", allow)
+			writeError(w, http.StatusMethodNotAllowed, newErrorResponse("Method Not Allowed"))
+			return
+		}
+		writeError(w, http.StatusNotFound, newErrorResponse("Not Found"))
+		return
+	}
+
+	remaining, reset, allowed := gateway.take(caller)
+	if remaining >= 0 {
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(caller.Quota))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int((reset+time.Second-1)/time.Second)))
+		writeError(w, http.StatusTooManyRequests, newErrorResponse("Quota Exceeded"))
+		return
+	}
+
+	handler(w, r, record)
+}
+
+// Handler of path and method, allowed methods are returned when only method does not match.
+// Routes are matched by segments, so hash can not contain slash.
+func (gateway *Gateway) route(r *http.Request, record *auditRecord) (handler handlerFunc, allow string) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] != "v1" || segments[1] != "links" {
+		return nil, ""
+	}
+
+	routes := map[string]handlerFunc{}
+	switch len(segments) {
+	case 2:
+		routes[http.MethodPost] = gateway.create
+	case 3:
+		routes[http.MethodGet] = gateway.get
+		routes[http.MethodPatch] = gateway.setActive
+	case 4:
+		if segments[3] == "stats" {
+			routes[http.MethodGet] = gateway.stats
+		}
+	}
+
+	if len(routes) == 0 || (len(segments) > 2 && !hashPattern.MatchString(segments[2])) {
+		return nil, ""
+	}
+
+	if len(segments) > 2 {
+		record.hash = segments[2]
+	}
+
+	if handler = routes[r.Method]; handler == nil {
+		methods := make([]string, 0, len(routes))
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPatch} {
+			if routes[method] != nil {
+				methods = append(methods, method)
+			}
+		}
+		return nil, strings.Join(methods, ", ")
+	}
+
+	return handler, ""
+}
+
+func (gateway *Gateway) create(w http.ResponseWriter, r *http.Request, record *auditRecord) {
+	linkRequest := models.LinkRequest{}
+	if e := decodeBody(r, &linkRequest); e != nil {
+		writeError(w, http.StatusBadRequest, newErrorResponse(e.Error()))
+		return
+	}
+
+	var link *models.LinkResponse
+	var errorResponse models.ErrorResponse
+
+	if gateway.Creator != nil {
+		link, record.cached, errorResponse = gateway.Creator.Create(linkRequest)
+	} else {
+		link, errorResponse = gateway.Client.CreateShortLink(linkRequest)
+	}
+
+	if failed(errorResponse) || link == nil {
+		writeError(w, upstreamStatus(errorResponse), errorResponse)
+		return
+	}
+
+	record.hash = tinysrc.HashFromUrl(link.Url)
+	writeJSON(w, http.StatusCreated, link)
+}
+
+func (gateway *Gateway) get(w http.ResponseWriter, r *http.Request, record *auditRecord) {
+	gateway.cached(w, record, record.hash+" link", func() (interface{}, models.ErrorResponse) {
+		return gateway.Client.GetUrlByHash(record.hash)
+	})
+}
+
+func (gateway *Gateway) setActive(w http.ResponseWriter, r *http.Request, record *auditRecord) {
+	activation := models.LinkActivationRequest{}
+	if e := decodeBody(r, &activation); e != nil {
+		writeError(w, http.StatusBadRequest, newErrorResponse(e.Error()))
+		return
+	}
+
+	_, errorResponse := gateway.Client.SetActive(record.hash, &activation)
+	if failed(errorResponse) {
+		writeError(w, upstreamStatus(errorResponse), errorResponse)
+		return
+	}
+
+	gateway.cache.invalidate(record.hash + " ")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"hash": record.hash, "active": activation.Active})
+}
+
+func (gateway *Gateway) stats(w http.ResponseWriter, r *http.Request, record *auditRecord) {
+	params, errorResponse := gateway.statRequest(r)
+	if failed(errorResponse) {
+		writeError(w, http.StatusUnprocessableEntity, errorResponse)
+		return
+	}
+
+	key := record.hash + " stat " + strings.Join([]string{
+		params.DateStart.UTC().Format(time.RFC3339),
+		params.DateEnd.UTC().Format(time.RFC3339),
+		strconv.FormatInt(params.Limit, 10),
+		strconv.FormatInt(params.Page, 10),
+	}, "&")
+
+	gateway.cached(w, record, key, func() (interface{}, models.ErrorResponse) {
+		return gateway.Client.GetStatByHash(record.hash, params)
+	})
+}
+
+// Statistics request of query, date range defaults to the last DEFAULT_STAT_RANGE
+func (gateway *Gateway) statRequest(r *http.Request) (params models.StatRequest, errorResponse models.ErrorResponse) {
+	query := r.URL.Query()
+	validation := func(field string, message string) {
+		if errorResponse.Validations == nil {
+			errorResponse.Validations = map[string][]string{}
+		}
+		errorResponse.Validations[field] = append(errorResponse.Validations[field], message)
+	}
+
+	// Default end is truncated to minute precision of API, so it does not split cache keys
+	params.DateEnd = gateway.time().Truncate(time.Minute)
+	params.Limit, params.Page = DEFAULT_STAT_LIMIT, 1
+
+	for _, field := range []string{"date-start", "date-end"} {
+		value := query.Get(field)
+		if value == "" {
+			continue
+		}
+
+		t, e := parseTime(value)
+		if e != nil {
+			validation(field, "must be RFC 3339 or "+tinysrc.DATE_FORMAT+" UTC time")
+			continue
+		}
+
+		if field == "date-start" {
+			params.DateStart = t
+		} else {
+			params.DateEnd = t
+		}
+	}
+
+	if params.DateStart.IsZero() {
+		params.DateStart = params.DateEnd.Add(-DEFAULT_STAT_RANGE)
+	}
+	if params.DateStart.After(params.DateEnd) {
+		validation("date-start", "must not be after date-end")
+	}
+
+	for _, field := range []string{"limit", "page"} {
+		value := query.Get(field)
+		if value == "" {
+			continue
+		}
+
+		number, e := strconv.ParseInt(value, 10, 64)
+		if e != nil || number < 1 || number > tinysrc.STAT_CHUNK_LIMIT {
+			validation(field, "must be a number from 1 to "+strconv.Itoa(tinysrc.STAT_CHUNK_LIMIT))
+			continue
+		}
+
+		if field == "limit" {
+			params.Limit = number
+		} else {
+			params.Page = number
+		}
+	}
+
+	return params, errorResponse
+}
+
+// Write cached response of key or load, encode and cache it
+func (gateway *Gateway) cached(w http.ResponseWriter, record *auditRecord, key string, load func() (interface{}, models.ErrorResponse)) {
+	now := gateway.time()
+
+	if gateway.CacheTTL >= 0 {
+		if body := gateway.cache.get(key, now); body != nil {
+			record.cached = true
+			writeBody(w, http.StatusOK, body)
+			return
+		}
+	}
+
+	value, errorResponse := load()
+	if failed(errorResponse) {
+		writeError(w, upstreamStatus(errorResponse), errorResponse)
+		return
+	}
+
+	body, e := json.Marshal(value)
+	if e != nil {
+		writeError(w, http.StatusInternalServerError, newErrorResponse(e.Error()))
+		return
+	}
+
+	if gateway.CacheTTL >= 0 {
+		ttl := gateway.CacheTTL
+		if ttl == 0 {
+			ttl = DEFAULT_CACHE_TTL
+		}
+		size := gateway.CacheSize
+		if size <= 0 {
+			size = DEFAULT_CACHE_SIZE
+		}
+		gateway.cache.put(key, body, now, ttl, size)
+	}
+
+	writeBody(w, http.StatusOK, body)
+}
+
+func (gateway *Gateway) time() time.Time {
+	if gateway.now != nil {
+		return gateway.now()
+	}
+
+	return time.Now()
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, e := time.Parse(time.RFC3339, value); e == nil {
+		return t, nil
+	}
+
+	return time.ParseInLocation(tinysrc.DATE_FORMAT, value, time.UTC)
+}
+
+func decodeBody(r *http.Request, value interface{}) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, MAX_BODY_SIZE))
+	decoder.DisallowUnknownFields()
+
+	if e := decoder.Decode(value); e != nil {
+		return errors.New("invalid JSON body: " + e.Error())
+	}
+
+	return nil
+}
+
+func failed(errorResponse models.ErrorResponse) bool {
+	return len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0
+}
+
+func newErrorResponse(message string) models.ErrorResponse {
+	return models.ErrorResponse{Errors: []string{message}}
+}
+
+// Status of API error returned to caller: client errors are passed through,
+// authorization and server errors of the API and transport errors are 502
+func upstreamStatus(errorResponse models.ErrorResponse) int {
+	switch status := errorResponse.Status; {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return http.StatusBadGateway
+	case status >= 400 && status < 500:
+		return status
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, errorResponse models.ErrorResponse) {
+	errorResponse.Status = status
+	if errorResponse.Errors == nil {
+		errorResponse.Errors = []string{}
+	}
+	if errorResponse.Validations == nil {
+		errorResponse.Validations = map[string][]string{}
+	}
+
+	writeJSON(w, status, errorResponse)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	body, e := json.Marshal(value)
+	if e != nil {
+		status, body = http.StatusInternalServerError, []byte(`{"validations":{},"errors":["Internal Server Error"],"status":500}`)
+	}
+
+	writeBody(w, status, body)
+}
+
+func writeBody(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// Cached bodies are shared, so newline is not appended to body
+	_, _ = w.Write(body)
+	_, _ = w.Write([]byte("\n"))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,267 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	mu       sync.Mutex
+	requests []string
+	active   int
+}
+
+func (server *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.URL.Path == "/v1/create":
+		request := models.LinkRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request.Url == "" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"validations":{"url":["required"]}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&models.LinkResponse{Url: "https://tinysrc.me/abc"})
+	case r.URL.Path == "/v1/client/url/abc":
+		_ = json.NewEncoder(w).Encode(&models.LinkUserResponse{Hash: "abc", Url: "https://test.com", Active: server.active})
+	case r.URL.Path == "/v1/client/abc" && r.Method == http.MethodPatch:
+		request := models.LinkActivationRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		server.active = 0
+		if request.Active {
+			server.active = 1
+		}
+		_ = json.NewEncoder(w).Encode(&models.LinkUserResponse{Hash: "abc"})
+	case r.URL.Path == "/v1/client/stat/abc":
+		_ = json.NewEncoder(w).Encode(&models.StatPaginatedResponse{Data: []*models.StatResponse{{Ip: "1.1.1.1"}}, Total: 1})
+	case r.URL.Path == "/v1/client/url/denied":
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+	}
+}
+
+func (server *testServer) count() int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return len(server.requests)
+}
+
+func newTestGateway(t *testing.T, callers ...*Caller) (*Gateway, *testServer, *bytes.Buffer) {
+	server := &testServer{active: 1}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	logs := &bytes.Buffer{}
+	gateway := New(client, callers...)
+	gateway.Logger = slog.New(slog.NewJSONHandler(logs, nil))
+
+	return gateway, server, logs
+}
+
+func call(gateway *Gateway, method string, target string, token string, body string) (*httptest.ResponseRecorder, models.ErrorResponse) {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	gateway.ServeHTTP(w, r)
+
+	errorResponse := models.ErrorResponse{}
+	if w.Code >= 400 {
+		_ = json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	}
+
+	return w, errorResponse
+}
+
+func TestGateway_ServeHTTP(t *testing.T) {
+	gateway, server, _ := newTestGateway(t, &Caller{Name: "billing", Token: "token-1"}, &Caller{Name: "crm", Token: "token-2"})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "test_health", method: http.MethodGet, target: "/healthz", wantStatus: http.StatusOK, wantBody: `{"status":"ok"}`},
+		{name: "test_missing_token", method: http.MethodGet, target: "/v1/links/abc", wantStatus: http.StatusUnauthorized, wantBody: `"errors":["Unauthorized"]`},
+		{name: "test_unknown_token", method: http.MethodGet, target: "/v1/links/abc", token: "other", wantStatus: http.StatusUnauthorized},
+		{name: "test_create", method: http.MethodPost, target: "/v1/links", token: "token-2", body: `{"url":"https://test.com"}`, wantStatus: http.StatusCreated, wantBody: `"url":"https://tinysrc.me/abc"`},
+		{name: "test_create_validation", method: http.MethodPost, target: "/v1/links", token: "token-1", body: `{"url":""}`, wantStatus: http.StatusUnprocessableEntity, wantBody: `{"validations":{"url":["required"]},"errors":[],"status":422}`},
+		{name: "test_create_invalid_body", method: http.MethodPost, target: "/v1/links", token: "token-1", body: `{"link":1}`, wantStatus: http.StatusBadRequest, wantBody: `invalid JSON body`},
+		{name: "test_get", method: http.MethodGet, target: "/v1/links/abc", token: "token-1", wantStatus: http.StatusOK, wantBody: `"hash":"abc"`},
+		{name: "test_get_not_found", method: http.MethodGet, target: "/v1/links/missing", token: "token-1", wantStatus: http.StatusNotFound, wantBody: `"errors":["Not Found"],"status":404`},
+		{name: "test_api_unauthorized", method: http.MethodGet, target: "/v1/links/denied", token: "token-1", wantStatus: http.StatusBadGateway, wantBody: `"errors":["Unauthorized"],"status":502`},
+		{name: "test_stats", method: http.MethodGet, target: "/v1/links/abc/stats?date-start=2022-04-01T00:00:00Z&date-end=2022-04-01+12:00&limit=10", token: "token-1", wantStatus: http.StatusOK, wantBody: `"total":1`},
+		{name: "test_stats_validation", method: http.MethodGet, target: "/v1/links/abc/stats?date-start=yesterday&limit=5000", token: "token-1", wantStatus: http.StatusUnprocessableEntity, wantBody: `"limit":["must be a number from 1 to 1000"]`},
+		{name: "test_method_not_allowed", method: http.MethodDelete, target: "/v1/links/abc", token: "token-1", wantStatus: http.StatusMethodNotAllowed},
+		{name: "test_unknown_path", method: http.MethodGet, target: "/v1/links/abc/clicks", token: "token-1", wantStatus: http.StatusNotFound},
+		{name: "test_invalid_hash", method: http.MethodGet, target: "/v1/links/a.b", token: "token-1", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := call(gateway, tt.method, tt.target, tt.token, tt.body)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("ServeHTTP() = %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %s", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	w, _ := call(gateway, http.MethodDelete, "/v1/links/abc", "token-1", "")
+	if w.Header().Get("Allow") != "GET, PATCH" {
+		t.Errorf("Allow = %s", w.Header().Get("Allow"))
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for _, request := range server.requests {
+		if strings.Contains(request, "a.b") || strings.Contains(request, "clicks") {
+			t.Errorf("request %s is sent to API", request)
+		}
+	}
+}
+
+func TestGateway_Cache(t *testing.T) {
+	gateway, server, logs := newTestGateway(t, &Caller{Name: "billing", Token: "token"})
+
+	now := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	gateway.now = func() time.Time { return now }
+
+	// Default date range of stats is the same within a minute
+	for i := 0; i < 2; i++ {
+		call(gateway, http.MethodGet, "/v1/links/abc", "token", "")
+		call(gateway, http.MethodGet, "/v1/links/abc/stats?limit=10", "token", "")
+		now = now.Add(10 * time.Second)
+	}
+	if server.count() != 2 {
+		t.Errorf("requests = %d, want 2", server.count())
+	}
+
+	// Activation drops cached responses of hash
+	w, _ := call(gateway, http.MethodPatch, "/v1/links/abc", "token", `{"active":false}`)
+	if w.Code != http.StatusOK || w.Body.String() != "{\"active\":false,\"hash\":\"abc\"}\n" {
+		t.Errorf("PATCH = %d %s", w.Code, w.Body.String())
+	}
+
+	w, _ = call(gateway, http.MethodGet, "/v1/links/abc", "token", "")
+	if !strings.Contains(w.Body.String(), `"active":0`) || server.count() != 4 {
+		t.Errorf("GET after PATCH = %s after %d requests", w.Body.String(), server.count())
+	}
+
+	// Expired entries are loaded again
+	now = now.Add(DEFAULT_CACHE_TTL)
+	call(gateway, http.MethodGet, "/v1/links/abc/stats?limit=10", "token", "")
+	if server.count() != 5 {
+		t.Errorf("requests = %d, want 5", server.count())
+	}
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		record := map[string]interface{}{}
+		_ = json.Unmarshal([]byte(line), &record)
+		records = append(records, record)
+	}
+
+	if len(records) != 7 {
+		t.Fatalf("audit records = %d, want 7", len(records))
+	}
+	record := records[1]
+	if record["caller"] != "billing" || record["hash"] != "abc" || record["status"] != float64(200) || record["cached"] != false || record["path"] != "/v1/links/abc/stats" {
+		t.Errorf("audit record = %v", record)
+	}
+	if records[2]["cached"] != true {
+		t.Errorf("audit record = %v", records[2])
+	}
+}
+
+func TestGateway_Quota(t *testing.T) {
+	gateway, _, _ := newTestGateway(t, &Caller{Name: "billing", Token: "token", Quota: 2}, &Caller{Name: "crm", Token: "other"})
+
+	now := time.Date(2022, 4, 1, 12, 30, 0, 0, time.UTC)
+	gateway.now = func() time.Time { return now }
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		w, _ := call(gateway, http.MethodGet, "/v1/links/abc", "token", "")
+		statuses = append(statuses, w.Code)
+	}
+
+	w, errorResponse := call(gateway, http.MethodGet, "/v1/links/abc", "token", "")
+	if !reflect.DeepEqual(statuses, []int{200, 200, 429}) || w.Header().Get("Retry-After") != "1800" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("statuses = %v, headers = %v", statuses, w.Header())
+	}
+	if !reflect.DeepEqual(errorResponse.Errors, []string{"Quota Exceeded"}) {
+		t.Errorf("error = %+v", errorResponse)
+	}
+
+	// Callers without quota are unlimited
+	if w, _ = call(gateway, http.MethodGet, "/v1/links/abc", "other", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("unlimited caller = %d %v", w.Code, w.Header())
+	}
+
+	// Quota is reset in the next window
+	now = now.Add(30 * time.Minute)
+	if w, _ = call(gateway, http.MethodGet, "/v1/links/abc", "token", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("next window = %d %v", w.Code, w.Header())
+	}
+}
+
+func TestLoadCallers(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		data    string
+		want    []*Caller
+		wantErr bool
+	}{
+		{name: "test_callers", data: `[{"name":"billing","token":"secret","quota":10},{"name":"crm","token":"other"}]`, want: []*Caller{{Name: "billing", Token: "secret", Quota: 10}, {Name: "crm", Token: "other"}}},
+		{name: "test_missing_token", data: `[{"name":"billing"}]`, wantErr: true},
+		{name: "test_duplicate", data: `[{"name":"a","token":"1"},{"name":"a","token":"2"}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			_ = os.WriteFile(path, []byte(tt.data), 0600)
+
+			got, e := LoadCallers(path)
+			if (e != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("LoadCallers() = %v, %v", got, e)
+			}
+		})
+	}
+}
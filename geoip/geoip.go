@@ -0,0 +1,298 @@
+// Package geoip enriches click statistics with location of visitors resolved
+// offline from MaxMind DB (MMDB) files, e.g. GeoLite2-City and GeoLite2-ASN.
+//
+//	city, e := geoip.Open("GeoLite2-City.mmdb")
+//	asn, e := geoip.Open("GeoLite2-ASN.mmdb")
+//	enricher := geoip.NewEnricher(city, asn)
+//	report := geoip.ByCountry(enricher.Enrich(stats.Data))
+package geoip
+
+import (
+	"container/list"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DEFAULT_CACHE_SIZE = 10000
+const DEFAULT_LANGUAGE = "en"
+
+// Country code of rows which could not be resolved
+const UNKNOWN = "ZZ"
+
+type Location struct {
+	CountryCode  string  `json:"country_code,omitempty"`
+	Country      string  `json:"country,omitempty"`
+	RegionCode   string  `json:"region_code,omitempty"`
+	Region       string  `json:"region,omitempty"`
+	City         string  `json:"city,omitempty"`
+	TimeZone     string  `json:"time_zone,omitempty"`
+	Latitude     float64 `json:"latitude,omitempty"`
+	Longitude    float64 `json:"longitude,omitempty"`
+	ASN          uint64  `json:"asn,omitempty"`
+	Organization string  `json:"organization,omitempty"`
+}
+
+// Stat row with location, Location is nil when address is invalid or not found
+type Stat struct {
+	*models.StatResponse
+	Location *Location `json:"location,omitempty"`
+}
+
+// Resolve addresses of stat rows, lookups are cached. Safe for concurrent use.
+type Enricher struct {
+	// City or Country database, optional
+	City *Reader
+	// ASN database, optional; ASN fields of City database are used when it is nil
+	ASN *Reader
+	// Language of names, falls back to English
+	Language string
+
+	mutex     sync.Mutex
+	cacheSize int
+	cache     map[netip.Addr]*list.Element
+	order     *list.List
+}
+
+type cacheEntry struct {
+	addr     netip.Addr
+	location *Location
+}
+
+func NewEnricher(city *Reader, asn *Reader) *Enricher {
+	return &Enricher{City: city, ASN: asn, Language: DEFAULT_LANGUAGE}
+}
+
+// Limit count of cached addresses, least recently used ones are evicted
+func (enricher *Enricher) SetCacheSize(size int) {
+	enricher.mutex.Lock()
+	defer enricher.mutex.Unlock()
+
+	enricher.cacheSize = size
+	enricher.evict()
+}
+
+// Location of address, nil for invalid or unknown address
+func (enricher *Enricher) Lookup(ip string) (*Location, error) {
+	addr, e := netip.ParseAddr(strings.TrimSpace(ip))
+	if e != nil {
+		return nil, nil
+	}
+	addr = addr.Unmap().WithZone("")
+
+	enricher.mutex.Lock()
+	if element, ok := enricher.cache[addr]; ok {
+		enricher.order.MoveToFront(element)
+		enricher.mutex.Unlock()
+		return element.Value.(*cacheEntry).location, nil
+	}
+	enricher.mutex.Unlock()
+
+	location, e := enricher.resolve(addr)
+	if e != nil {
+		return nil, e
+	}
+
+	enricher.mutex.Lock()
+	defer enricher.mutex.Unlock()
+
+	if enricher.cache == nil {
+		enricher.cache = make(map[netip.Addr]*list.Element)
+		enricher.order = list.New()
+	}
+	if _, ok := enricher.cache[addr]; !ok {
+		enricher.cache[addr] = enricher.order.PushFront(&cacheEntry{addr: addr, location: location})
+		enricher.evict()
+	}
+
+	return location, nil
+}
+
+func (enricher *Enricher) evict() {
+	size := enricher.cacheSize
+	if size <= 0 {
+		size = DEFAULT_CACHE_SIZE
+	}
+
+	for enricher.order != nil && enricher.order.Len() > size {
+		oldest := enricher.order.Back()
+		enricher.order.Remove(oldest)
+		delete(enricher.cache, oldest.Value.(*cacheEntry).addr)
+	}
+}
+
+func (enricher *Enricher) resolve(addr netip.Addr) (*Location, error) {
+	var location Location
+	found := false
+
+	if enricher.City != nil {
+		record, e := enricher.City.Lookup(addr)
+		if e != nil {
+			return nil, e
+		}
+
+		if fields, ok := record.(map[string]any); ok {
+			found = true
+			enricher.readCity(fields, &location)
+			readASN(fields, &location)
+		}
+	}
+
+	if enricher.ASN != nil {
+		record, e := enricher.ASN.Lookup(addr)
+		if e != nil {
+			return nil, e
+		}
+
+		if fields, ok := record.(map[string]any); ok {
+			found = true
+			readASN(fields, &location)
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &location, nil
+}
+
+func (enricher *Enricher) readCity(fields map[string]any, location *Location) {
+	country := field(fields, "country")
+	if country == nil {
+		country = field(fields, "registered_country")
+	}
+	location.CountryCode = stringValue(country["iso_code"])
+	location.Country = enricher.name(country)
+
+	if subdivisions, ok := fields["subdivisions"].([]any); ok && len(subdivisions) > 0 {
+		if region, ok := subdivisions[0].(map[string]any); ok {
+			location.RegionCode = stringValue(region["iso_code"])
+			location.Region = enricher.name(region)
+		}
+	}
+
+	location.City = enricher.name(field(fields, "city"))
+
+	position := field(fields, "location")
+	location.TimeZone = stringValue(position["time_zone"])
+	location.Latitude, _ = position["latitude"].(float64)
+	location.Longitude, _ = position["longitude"].(float64)
+}
+
+func readASN(fields map[string]any, location *Location) {
+	if number := uintValue(fields["autonomous_system_number"]); number != 0 {
+		location.ASN = number
+	}
+	if organization := stringValue(fields["autonomous_system_organization"]); organization != "" {
+		location.Organization = organization
+	}
+}
+
+// Name in enricher language, English as fallback
+func (enricher *Enricher) name(fields map[string]any) string {
+	names := field(fields, "names")
+
+	if name := stringValue(names[enricher.Language]); name != "" {
+		return name
+	}
+
+	return stringValue(names[DEFAULT_LANGUAGE])
+}
+
+func field(fields map[string]any, name string) map[string]any {
+	value, _ := fields[name].(map[string]any)
+	return value
+}
+
+// Attach location to every row, lookup errors leave Location empty
+func (enricher *Enricher) Enrich(stats []*models.StatResponse) []*Stat {
+	result := make([]*Stat, len(stats))
+
+	for i, stat := range stats {
+		location, _ := enricher.Lookup(stat.Ip)
+		result[i] = &Stat{StatResponse: stat, Location: location}
+	}
+
+	return result
+}
+
+type CountryBreakdown struct {
+	// UNKNOWN for unresolved addresses
+	CountryCode string `json:"country_code"`
+	Country     string `json:"country,omitempty"`
+	Clicks      int64  `json:"clicks"`
+	Bots        int64  `json:"bots"`
+	UniqueIps   int64  `json:"unique_ips"`
+	// Share of all clicks, 0..1
+	Share float64 `json:"share"`
+	// Top cities by clicks
+	Cities []*CityBreakdown `json:"cities,omitempty"`
+}
+
+type CityBreakdown struct {
+	City   string `json:"city"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Clicks by country sorted by clicks desc, then by country code
+func ByCountry(stats []*Stat) []*CountryBreakdown {
+	countries := make(map[string]*CountryBreakdown)
+	ips := make(map[string]map[string]bool)
+	cities := make(map[string]map[string]int64)
+
+	for _, stat := range stats {
+		code, name := UNKNOWN, ""
+		if stat.Location != nil && stat.Location.CountryCode != "" {
+			code, name = stat.Location.CountryCode, stat.Location.Country
+		}
+
+		country, ok := countries[code]
+		if !ok {
+			country = &CountryBreakdown{CountryCode: code, Country: name}
+			countries[code] = country
+			ips[code] = make(map[string]bool)
+			cities[code] = make(map[string]int64)
+		}
+
+		country.Clicks++
+		if stat.Bot {
+			country.Bots++
+		}
+		if !ips[code][stat.Ip] {
+			ips[code][stat.Ip] = true
+			country.UniqueIps++
+		}
+		if stat.Location != nil && stat.Location.City != "" {
+			cities[code][stat.Location.City]++
+		}
+	}
+
+	result := make([]*CountryBreakdown, 0, len(countries))
+	for code, country := range countries {
+		country.Share = float64(country.Clicks) / float64(len(stats))
+
+		for city, clicks := range cities[code] {
+			country.Cities = append(country.Cities, &CityBreakdown{City: city, Clicks: clicks})
+		}
+		sort.Slice(country.Cities, func(i, j int) bool {
+			if country.Cities[i].Clicks != country.Cities[j].Clicks {
+				return country.Cities[i].Clicks > country.Cities[j].Clicks
+			}
+			return country.Cities[i].City < country.Cities[j].City
+		})
+
+		result = append(result, country)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Clicks != result[j].Clicks {
+			return result[i].Clicks > result[j].Clicks
+		}
+		return result[i].CountryCode < result[j].CountryCode
+	})
+
+	return result
+}
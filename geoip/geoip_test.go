@@ -0,0 +1,148 @@
+package geoip
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testEnricher(t *testing.T) *Enricher {
+	shared, networks := testNetworks()
+
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	if e := os.WriteFile(path, buildDatabase(t, 6, 28, shared, networks), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	city, e := Open(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	asn, e := FromBytes(buildDatabase(t, 4, 24, nil, []testNetwork{
+		{prefix: "1.2.3.0/24", record: map[string]any{"autonomous_system_number": uint32(680), "autonomous_system_organization": "DFN"}},
+		{prefix: "8.0.0.0/8", record: map[string]any{"autonomous_system_number": uint32(15169), "autonomous_system_organization": "Google"}},
+	}))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return NewEnricher(city, asn)
+}
+
+func TestEnricher_Lookup(t *testing.T) {
+	enricher := testEnricher(t)
+	enricher.Language = "de"
+
+	tests := []struct {
+		name string
+		ip   string
+		want *Location
+	}{
+		{
+			name: "test_city_and_asn",
+			ip:   "1.2.3.4",
+			want: &Location{
+				CountryCode: "DE", Country: "Deutschland", RegionCode: "BE", Region: "Land Berlin", City: "Berlin",
+				TimeZone: "Europe/Berlin", Latitude: 52.5, Longitude: 13.4, ASN: 680, Organization: "DFN",
+			},
+		},
+		{
+			name: "test_asn_of_city_database",
+			ip:   "1.2.5.1",
+			want: &Location{CountryCode: "DE", Country: "Deutschland", City: "Munich", ASN: 3320, Organization: "Deutsche Telekom AG"},
+		},
+		{
+			name: "test_registered_country",
+			ip:   "8.8.8.8",
+			want: &Location{CountryCode: "US", Country: "United States", ASN: 15169, Organization: "Google"},
+		},
+		{
+			name: "test_asn_only",
+			ip:   " 8.1.1.1 ",
+			want: &Location{ASN: 15169, Organization: "Google"},
+		},
+		{
+			name: "test_not_found",
+			ip:   "10.0.0.1",
+		},
+		{
+			name: "test_invalid",
+			ip:   "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, e := enricher.Lookup(tt.ip)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %+v, want %+v", got, tt.want)
+			}
+
+			cached, _ := enricher.Lookup(tt.ip)
+			if cached != got {
+				t.Errorf("Lookup() is not cached")
+			}
+		})
+	}
+}
+
+func TestEnricher_SetCacheSize(t *testing.T) {
+	enricher := testEnricher(t)
+	enricher.SetCacheSize(2)
+
+	first, _ := enricher.Lookup("1.2.3.4")
+	_, _ = enricher.Lookup("1.2.5.1")
+	_, _ = enricher.Lookup("1.2.3.4")
+	_, _ = enricher.Lookup("8.8.8.8")
+
+	if len(enricher.cache) != 2 {
+		t.Fatalf("cache size = %d, want 2", len(enricher.cache))
+	}
+
+	if again, _ := enricher.Lookup("1.2.3.4"); again != first {
+		t.Errorf("recently used address was evicted")
+	}
+	if _, _ = enricher.Lookup("1.2.5.1"); len(enricher.cache) != 2 {
+		t.Errorf("cache size = %d, want 2", len(enricher.cache))
+	}
+}
+
+func TestByCountry(t *testing.T) {
+	enricher := testEnricher(t)
+
+	stats := enricher.Enrich([]*models.StatResponse{
+		{Ip: "1.2.3.4"},
+		{Ip: "1.2.3.4"},
+		{Ip: "1.2.3.5", Bot: true},
+		{Ip: "1.2.5.1"},
+		{Ip: "8.8.8.8"},
+		{Ip: "10.0.0.1"},
+		{Ip: ""},
+	})
+
+	if stats[0].Location == nil || stats[0].Ip != "1.2.3.4" || stats[5].Location != nil {
+		t.Fatalf("Enrich() = %+v", stats)
+	}
+
+	want := []*CountryBreakdown{
+		{
+			CountryCode: "DE", Country: "Germany", Clicks: 4, Bots: 1, UniqueIps: 3, Share: 4.0 / 7,
+			Cities: []*CityBreakdown{{City: "Berlin", Clicks: 3}, {City: "Munich", Clicks: 1}},
+		},
+		{CountryCode: UNKNOWN, Clicks: 2, UniqueIps: 2, Share: 2.0 / 7},
+		{CountryCode: "US", Country: "United States", Clicks: 1, UniqueIps: 1, Share: 1.0 / 7},
+	}
+
+	if got := ByCountry(stats); !reflect.DeepEqual(got, want) {
+		for _, country := range got {
+			t.Logf("%+v", country)
+		}
+		t.Errorf("ByCountry() mismatch")
+	}
+}
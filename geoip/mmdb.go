@@ -0,0 +1,406 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+)
+
+// Marker preceding metadata section of MaxMind DB file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+const metadataSearchLimit = 128 * 1024
+const dataSectionSeparator = 16
+
+var ErrInvalidDatabase = errors.New("geoip: invalid database")
+
+type Metadata struct {
+	DatabaseType string
+	IPVersion    int
+	RecordSize   int
+	NodeCount    int
+	BuildEpoch   uint64
+	Languages    []string
+	Description  map[string]string
+}
+
+// Reader of MaxMind DB (MMDB) files, database is kept in memory and safe for concurrent use
+type Reader struct {
+	Metadata Metadata
+
+	buffer    []byte
+	data      []byte
+	ipv4Start int
+	nodeSize  int
+}
+
+// Read database file into memory
+func Open(path string) (*Reader, error) {
+	buffer, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	return FromBytes(buffer)
+}
+
+// Parse database from buffer, buffer must not be modified later
+func FromBytes(buffer []byte) (*Reader, error) {
+	searchFrom := len(buffer) - metadataSearchLimit
+	if searchFrom < 0 {
+		searchFrom = 0
+	}
+
+	index := bytes.LastIndex(buffer[searchFrom:], metadataMarker)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+	metadataStart := searchFrom + index + len(metadataMarker)
+
+	value, _, e := (&decoder{buffer: buffer[metadataStart:]}).decode(0, 0)
+	if e != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidDatabase, e)
+	}
+
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	reader := &Reader{buffer: buffer}
+	reader.Metadata = Metadata{
+		DatabaseType: stringValue(fields["database_type"]),
+		IPVersion:    int(uintValue(fields["ip_version"])),
+		RecordSize:   int(uintValue(fields["record_size"])),
+		NodeCount:    int(uintValue(fields["node_count"])),
+		BuildEpoch:   uintValue(fields["build_epoch"]),
+		Description:  make(map[string]string),
+	}
+	if languages, ok := fields["languages"].([]any); ok {
+		for _, language := range languages {
+			reader.Metadata.Languages = append(reader.Metadata.Languages, stringValue(language))
+		}
+	}
+	if description, ok := fields["description"].(map[string]any); ok {
+		for language, text := range description {
+			reader.Metadata.Description[language] = stringValue(text)
+		}
+	}
+
+	switch reader.Metadata.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, reader.Metadata.RecordSize)
+	}
+
+	reader.nodeSize = reader.Metadata.RecordSize / 4
+	treeSize := reader.Metadata.NodeCount * reader.nodeSize
+	if treeSize+dataSectionSeparator > metadataStart-len(metadataMarker) {
+		return nil, fmt.Errorf("%w: search tree is out of file", ErrInvalidDatabase)
+	}
+	reader.data = buffer[treeSize+dataSectionSeparator : metadataStart-len(metadataMarker)]
+
+	// IPv4 addresses are stored in ::/96 of IPv6 databases
+	if reader.Metadata.IPVersion == 6 {
+		node := 0
+		for i := 0; i < 96 && node < reader.Metadata.NodeCount; i++ {
+			node = reader.record(node, 0)
+		}
+		reader.ipv4Start = node
+	}
+
+	return reader, nil
+}
+
+// Lookup record of address, nil when address is not in database
+func (reader *Reader) Lookup(addr netip.Addr) (any, error) {
+	offset, e := reader.lookupOffset(addr)
+	if e != nil || offset < 0 {
+		return nil, e
+	}
+
+	value, _, e := (&decoder{buffer: reader.data}).decode(offset, 0)
+	return value, e
+}
+
+// Offset of record in data section, -1 when address is not found
+func (reader *Reader) lookupOffset(addr netip.Addr) (int, error) {
+	addr = addr.Unmap()
+
+	var ip []byte
+	node := 0
+
+	switch {
+	case addr.Is4() && reader.Metadata.IPVersion == 6:
+		ip = addr.AsSlice()
+		node = reader.ipv4Start
+	case addr.Is4():
+		ip = addr.AsSlice()
+	case reader.Metadata.IPVersion == 6:
+		ip = addr.AsSlice()
+	default:
+		return -1, fmt.Errorf("geoip: can not lookup %s in IPv4 database", addr)
+	}
+
+	nodeCount := reader.Metadata.NodeCount
+	for i := 0; i < len(ip)*8 && node < nodeCount; i++ {
+		bit := int(ip[i>>3]>>(7-uint(i&7))) & 1
+		node = reader.record(node, bit)
+	}
+
+	switch {
+	case node == nodeCount:
+		return -1, nil
+	case node > nodeCount:
+		offset := node - nodeCount - dataSectionSeparator
+		if offset < 0 || offset >= len(reader.data) {
+			return -1, fmt.Errorf("%w: record pointer out of data section", ErrInvalidDatabase)
+		}
+		return offset, nil
+	}
+
+	return -1, fmt.Errorf("%w: search tree is too deep", ErrInvalidDatabase)
+}
+
+// Left (bit 0) or right (bit 1) record of node
+func (reader *Reader) record(node int, bit int) int {
+	b := reader.buffer[node*reader.nodeSize : (node+1)*reader.nodeSize]
+
+	switch reader.Metadata.RecordSize {
+	case 24:
+		b = b[bit*3:]
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	case 28:
+		if bit == 0 {
+			return int(b[3]&0xF0)<<20 | int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		}
+		return int(b[3]&0x0F)<<24 | int(b[4])<<16 | int(b[5])<<8 | int(b[6])
+	}
+
+	return int(binary.BigEndian.Uint32(b[bit*4:]))
+}
+
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// Nesting limit of maps, arrays and pointers
+const maxDepth = 64
+
+type decoder struct {
+	buffer []byte
+}
+
+// Decode value at offset, returns value and offset after it
+func (d *decoder) decode(offset int, depth int) (any, int, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("data is nested too deep")
+	}
+
+	kind, size, offset, e := d.control(offset)
+	if e != nil {
+		return nil, 0, e
+	}
+
+	if kind == typePointer {
+		pointer, next, e := d.pointer(size, offset)
+		if e != nil {
+			return nil, 0, e
+		}
+
+		value, _, e := d.decode(pointer, depth+1)
+		return value, next, e
+	}
+
+	if kind != typeMap && kind != typeArray && kind != typeBool && offset+size > len(d.buffer) {
+		return nil, 0, errors.New("value is out of buffer")
+	}
+
+	switch kind {
+	case typeString:
+		return string(d.buffer[offset : offset+size]), offset + size, nil
+	case typeBytes:
+		return append([]byte(nil), d.buffer[offset:offset+size]...), offset + size, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(d.buffer[offset:])), offset + size, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(d.buffer[offset:]))), offset + size, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("invalid unsigned integer size %d", size)
+		}
+		var value uint64
+		for _, b := range d.buffer[offset : offset+size] {
+			value = value<<8 | uint64(b)
+		}
+		return value, offset + size, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("invalid int32 size %d", size)
+		}
+		var value uint32
+		for _, b := range d.buffer[offset : offset+size] {
+			value = value<<8 | uint32(b)
+		}
+		if size == 4 {
+			return int64(int32(value)), offset + size, nil
+		}
+		return int64(value), offset + size, nil
+	case typeUint128:
+		return new(big.Int).SetBytes(d.buffer[offset : offset+size]), offset + size, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeMap:
+		result := make(map[string]any, size)
+		for i := 0; i < size; i++ {
+			key, next, e := d.decode(offset, depth+1)
+			if e != nil {
+				return nil, 0, e
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+
+			value, next, e := d.decode(next, depth+1)
+			if e != nil {
+				return nil, 0, e
+			}
+
+			result[name] = value
+			offset = next
+		}
+		return result, offset, nil
+	case typeArray:
+		result := make([]any, 0, size)
+		for i := 0; i < size; i++ {
+			value, next, e := d.decode(offset, depth+1)
+			if e != nil {
+				return nil, 0, e
+			}
+
+			result = append(result, value)
+			offset = next
+		}
+		return result, offset, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported data type %d", kind)
+}
+
+// Read control byte with extended type and size, returns offset of payload
+func (d *decoder) control(offset int) (kind int, size int, next int, e error) {
+	if offset >= len(d.buffer) {
+		return 0, 0, 0, errors.New("offset is out of buffer")
+	}
+
+	ctrl := d.buffer[offset]
+	offset++
+
+	kind = int(ctrl >> 5)
+	if kind == typeExtended {
+		if offset >= len(d.buffer) {
+			return 0, 0, 0, errors.New("extended type is out of buffer")
+		}
+		kind = 7 + int(d.buffer[offset])
+		offset++
+	}
+
+	if kind == typePointer {
+		return kind, int(ctrl & 0x1F), offset, nil
+	}
+
+	size = int(ctrl & 0x1F)
+	if size >= 29 {
+		length := size - 28
+		if offset+length > len(d.buffer) {
+			return 0, 0, 0, errors.New("size is out of buffer")
+		}
+
+		extra := 0
+		for _, b := range d.buffer[offset : offset+length] {
+			extra = extra<<8 | int(b)
+		}
+		offset += length
+
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	return kind, size, offset, nil
+}
+
+// Pointer target, size holds low 5 bits of control byte
+func (d *decoder) pointer(size int, offset int) (int, int, error) {
+	length := (size>>3)&3 + 1
+	if offset+length > len(d.buffer) {
+		return 0, 0, errors.New("pointer is out of buffer")
+	}
+
+	value := 0
+	if length < 4 {
+		value = size & 0x7
+	}
+	for _, b := range d.buffer[offset : offset+length] {
+		value = value<<8 | int(b)
+	}
+
+	switch length {
+	case 2:
+		value += 2048
+	case 3:
+		value += 526336
+	}
+
+	return value, offset + length, nil
+}
+
+func stringValue(value any) string {
+	text, _ := value.(string)
+	return text
+}
+
+func uintValue(value any) uint64 {
+	switch number := value.(type) {
+	case uint64:
+		return number
+	case int64:
+		if number > 0 {
+			return uint64(number)
+		}
+	}
+
+	return 0
+}
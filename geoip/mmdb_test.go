@@ -0,0 +1,319 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Offset in data section written as pointer
+type testPointer int
+
+type testNetwork struct {
+	prefix string
+	record any
+}
+
+// Write MaxMind DB with given networks, shared values are written first so records can point to them
+func buildDatabase(t *testing.T, ipVersion int, recordSize int, shared []any, networks []testNetwork) []byte {
+	t.Helper()
+
+	type node [2]int
+	const empty, dataBase = -1, -2
+
+	nodes := []node{{empty, empty}}
+	var data bytes.Buffer
+
+	for _, value := range shared {
+		writeValue(&data, value)
+	}
+
+	for _, network := range networks {
+		prefix := netip.MustParsePrefix(network.prefix)
+
+		var bits []int
+		ip := prefix.Addr().AsSlice()
+		length := prefix.Bits()
+		if prefix.Addr().Is4() && ipVersion == 6 {
+			bits = make([]int, 96)
+		}
+		for i := 0; i < length; i++ {
+			bits = append(bits, int(ip[i/8]>>(7-uint(i%8)))&1)
+		}
+
+		offset := data.Len()
+		writeValue(&data, network.record)
+
+		current := 0
+		for i, bit := range bits {
+			if i == len(bits)-1 {
+				nodes[current][bit] = dataBase - offset
+				break
+			}
+			if nodes[current][bit] < 0 {
+				nodes = append(nodes, node{empty, empty})
+				nodes[current][bit] = len(nodes) - 1
+			}
+			current = nodes[current][bit]
+		}
+	}
+
+	count := len(nodes)
+	var tree bytes.Buffer
+	for _, n := range nodes {
+		var records [2]int
+		for i, value := range n {
+			switch {
+			case value == empty:
+				records[i] = count
+			case value <= dataBase:
+				records[i] = count + dataSectionSeparator + (dataBase - value)
+			default:
+				records[i] = value
+			}
+		}
+
+		left, right := records[0], records[1]
+		switch recordSize {
+		case 24:
+			tree.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			tree.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(left>>24)<<4 | byte(right>>24)&0x0F, byte(right >> 16), byte(right >> 8), byte(right)})
+		case 32:
+			_ = binary.Write(&tree, binary.BigEndian, []uint32{uint32(left), uint32(right)})
+		}
+	}
+
+	var file bytes.Buffer
+	file.Write(tree.Bytes())
+	file.Write(make([]byte, dataSectionSeparator))
+	file.Write(data.Bytes())
+	file.Write(metadataMarker)
+	writeValue(&file, map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(recordSize),
+		"ip_version":                  uint16(ipVersion),
+		"database_type":               "Test-City",
+		"languages":                   []any{"en", "de"},
+		"description":                 map[string]any{"en": "Test database"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1650000000),
+	})
+
+	return file.Bytes()
+}
+
+func writeControl(buffer *bytes.Buffer, kind int, size int) {
+	var sizeBits int
+	var extra []byte
+
+	switch {
+	case size < 29:
+		sizeBits = size
+	case size < 285:
+		sizeBits, extra = 29, []byte{byte(size - 29)}
+	case size < 65821:
+		sizeBits, extra = 30, []byte{byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		size -= 65821
+		sizeBits, extra = 31, []byte{byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+
+	if kind > 7 {
+		buffer.WriteByte(byte(sizeBits))
+		buffer.WriteByte(byte(kind - 7))
+	} else {
+		buffer.WriteByte(byte(kind<<5 | sizeBits))
+	}
+	buffer.Write(extra)
+}
+
+func writeUint(buffer *bytes.Buffer, kind int, value uint64) {
+	var digits []byte
+	for ; value > 0; value >>= 8 {
+		digits = append([]byte{byte(value)}, digits...)
+	}
+
+	writeControl(buffer, kind, len(digits))
+	buffer.Write(digits)
+}
+
+func writeValue(buffer *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case string:
+		writeControl(buffer, typeString, len(v))
+		buffer.WriteString(v)
+	case uint16:
+		writeUint(buffer, typeUint16, uint64(v))
+	case uint32:
+		writeUint(buffer, typeUint32, uint64(v))
+	case uint64:
+		writeUint(buffer, typeUint64, v)
+	case int32:
+		writeControl(buffer, typeInt32, 4)
+		_ = binary.Write(buffer, binary.BigEndian, v)
+	case float64:
+		writeControl(buffer, typeDouble, 8)
+		_ = binary.Write(buffer, binary.BigEndian, math.Float64bits(v))
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeControl(buffer, typeBool, size)
+	case []any:
+		writeControl(buffer, typeArray, len(v))
+		for _, item := range v {
+			writeValue(buffer, item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeControl(buffer, typeMap, len(v))
+		for _, key := range keys {
+			writeValue(buffer, key)
+			writeValue(buffer, v[key])
+		}
+	case testPointer:
+		if v < 2048 {
+			buffer.Write([]byte{byte(typePointer<<5 | int(v)>>8), byte(v)})
+		} else {
+			p := int(v) - 2048
+			buffer.Write([]byte{byte(typePointer<<5 | 1<<3 | p>>16), byte(p >> 8), byte(p)})
+		}
+	default:
+		panic("unsupported test value")
+	}
+}
+
+// City records of test databases, "Germany" is shared and referenced by pointers
+func testNetworks() ([]any, []testNetwork) {
+	filler := strings.Repeat("x", 3000)
+	shared := []any{"Germany", filler, "Europe/Berlin"}
+	berlinTimeZone := testPointer(1 + len("Germany") + 3 + len(filler))
+
+	germany := map[string]any{"iso_code": "DE", "names": map[string]any{"en": testPointer(0), "de": "Deutschland"}}
+
+	return shared, []testNetwork{
+		{
+			prefix: "1.2.3.0/24",
+			record: map[string]any{
+				"country":      germany,
+				"subdivisions": []any{map[string]any{"iso_code": "BE", "names": map[string]any{"en": "Land Berlin"}}},
+				"city":         map[string]any{"names": map[string]any{"en": "Berlin"}},
+				"location":     map[string]any{"time_zone": berlinTimeZone, "latitude": 52.5, "longitude": 13.4},
+			},
+		},
+		{
+			prefix: "1.2.4.0/22",
+			record: map[string]any{
+				"country":                        germany,
+				"city":                           map[string]any{"names": map[string]any{"en": "Munich"}},
+				"autonomous_system_number":       uint32(3320),
+				"autonomous_system_organization": "Deutsche Telekom AG",
+			},
+		},
+		{
+			prefix: "8.8.8.0/24",
+			record: map[string]any{
+				"registered_country": map[string]any{"iso_code": "US", "names": map[string]any{"en": "United States"}},
+				"is_anycast":         true,
+				"offset":             int32(-5),
+			},
+		},
+	}
+}
+
+func TestReader_Lookup(t *testing.T) {
+	shared, networks := testNetworks()
+
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			database := buildDatabase(t, ipVersion, recordSize, shared, networks)
+
+			reader, e := FromBytes(database)
+			if e != nil {
+				t.Fatalf("FromBytes(%d, %d) error = %v", ipVersion, recordSize, e)
+			}
+
+			if reader.Metadata.DatabaseType != "Test-City" || reader.Metadata.IPVersion != ipVersion || reader.Metadata.RecordSize != recordSize ||
+				reader.Metadata.BuildEpoch != 1650000000 || !reflect.DeepEqual(reader.Metadata.Languages, []string{"en", "de"}) ||
+				reader.Metadata.Description["en"] != "Test database" {
+				t.Errorf("FromBytes(%d, %d) metadata = %+v", ipVersion, recordSize, reader.Metadata)
+			}
+
+			tests := []struct {
+				ip   string
+				want any
+			}{
+				{ip: "1.2.3.4", want: "Berlin"},
+				{ip: "::ffff:1.2.3.255", want: "Berlin"},
+				{ip: "1.2.7.1", want: "Munich"},
+				{ip: "1.2.8.1", want: nil},
+				{ip: "8.8.8.8", want: true},
+				{ip: "9.9.9.9", want: nil},
+			}
+
+			for _, tt := range tests {
+				record, e := reader.Lookup(netip.MustParseAddr(tt.ip))
+				if e != nil {
+					t.Fatalf("Lookup(%s) error = %v", tt.ip, e)
+				}
+
+				var got any
+				if fields, ok := record.(map[string]any); ok {
+					if city := field(field(fields, "city"), "names"); city != nil {
+						got = city["en"]
+					} else {
+						got = fields["is_anycast"]
+						if fields["offset"] != int64(-5) {
+							t.Errorf("Lookup(%s) offset = %v", tt.ip, fields["offset"])
+						}
+					}
+				}
+
+				if got != tt.want {
+					t.Errorf("Lookup(%d, %d, %s) = %v, want %v", ipVersion, recordSize, tt.ip, got, tt.want)
+				}
+			}
+
+			_, e = reader.Lookup(netip.MustParseAddr("2001:db8::1"))
+			if (e != nil) != (ipVersion == 4) {
+				t.Errorf("Lookup(%d, IPv6) error = %v", ipVersion, e)
+			}
+		}
+	}
+}
+
+func TestFromBytes_Invalid(t *testing.T) {
+	shared, networks := testNetworks()
+	database := buildDatabase(t, 6, 24, shared, networks)
+
+	tests := []struct {
+		name     string
+		database []byte
+	}{
+		{name: "test_empty", database: nil},
+		{name: "test_no_metadata", database: database[:100]},
+		{name: "test_truncated_metadata", database: database[:len(database)-10]},
+		{name: "test_tree_out_of_file", database: append(append([]byte{}, metadataMarker...), database[bytes.LastIndex(database, metadataMarker)+len(metadataMarker):]...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, e := FromBytes(tt.database); e == nil {
+				t.Errorf("FromBytes() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+// Package health probes destinations of TinySRC links and reports broken,
+// redirected and slow ones.
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOK         Status = "ok"
+	StatusRedirected Status = "redirected"
+	StatusBroken     Status = "broken"
+	// Probe was interrupted by context of caller, destination state is unknown
+	StatusCancelled Status = "cancelled"
+)
+
+type ErrorKind string
+
+const (
+	ErrorNone             ErrorKind = ""
+	ErrorStatus           ErrorKind = "status"
+	ErrorTimeout          ErrorKind = "timeout"
+	ErrorTLS              ErrorKind = "tls"
+	ErrorDNS              ErrorKind = "dns"
+	ErrorConnection       ErrorKind = "connection"
+	ErrorTooManyRedirects ErrorKind = "too_many_redirects"
+	ErrorOther            ErrorKind = "other"
+)
+
+const DEFAULT_CONCURRENCY = 8
+const DEFAULT_TIMEOUT = 10 * time.Second
+const DEFAULT_SLOW_THRESHOLD = 3 * time.Second
+const DEFAULT_MAX_REDIRECTS = 10
+const DEFAULT_HOST_DELAY = time.Second
+const DEFAULT_USER_AGENT = "tinysrc-health/1.0"
+
+// Result of one destination probe
+type Result struct {
+	Hash       string        `json:"hash"`
+	Url        string        `json:"url"`
+	Status     Status        `json:"status"`
+	StatusCode int           `json:"status_code,omitempty"`
+	FinalUrl   string        `json:"final_url,omitempty"`
+	Redirects  []string      `json:"redirects,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Slow       bool          `json:"slow"`
+	ErrorKind  ErrorKind     `json:"error_kind,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	// Consecutive broken checks of the hash
+	Failures    int  `json:"failures"`
+	Deactivated bool `json:"deactivated"`
+}
+
+type Report struct {
+	Results []*Result     `json:"results"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Checker probes all links of the account
+type Checker struct {
+	Client *tinysrc.Client
+	// Client used to probe destinations, redirects are followed by Checker itself
+	HTTPClient *http.Client
+	// Max destinations probed at the same time
+	Concurrency int
+	// Min delay between two requests to the same host
+	HostDelay time.Duration
+	// Timeout of one request
+	Timeout time.Duration
+	// Responding destinations answering slower are marked as slow
+	SlowThreshold time.Duration
+	MaxRedirects  int
+	UserAgent     string
+	// Deactivate links broken for DeactivateAfter consecutive checks, 0 disables deactivation
+	DeactivateAfter int
+
+	mu       sync.Mutex
+	failures map[string]int
+	hosts    map[string]*hostGate
+}
+
+type hostGate struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Constructor of Checker with default options
+func NewChecker(client *tinysrc.Client) *Checker {
+	return &Checker{
+		Client:        client,
+		HTTPClient:    &http.Client{},
+		Concurrency:   DEFAULT_CONCURRENCY,
+		HostDelay:     DEFAULT_HOST_DELAY,
+		Timeout:       DEFAULT_TIMEOUT,
+		SlowThreshold: DEFAULT_SLOW_THRESHOLD,
+		MaxRedirects:  DEFAULT_MAX_REDIRECTS,
+		UserAgent:     DEFAULT_USER_AGENT,
+	}
+}
+
+// Probe destinations of all links returned by GetListUrls, cancelled probes do not
+// count as failures of links
+func (checker *Checker) Check(ctx context.Context) (r *Report, errorResponse models.ErrorResponse) {
+	links, errorResponse := checker.Client.GetAllUrls("", 0)
+	if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return nil, errorResponse
+	}
+
+	report := &Report{Started: time.Now(), Results: make([]*Result, len(links))}
+
+	concurrency := checker.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+
+	var wg sync.WaitGroup
+	pool := make(chan struct{}, concurrency)
+
+	for i, link := range links {
+		wg.Add(1)
+		pool <- struct{}{}
+
+		go func(i int, link *models.LinkUserResponse) {
+			defer wg.Done()
+			defer func() { <-pool }()
+
+			result := checker.Probe(ctx, link.Url)
+			result.Hash = link.Hash
+			if result.Status != StatusCancelled {
+				checker.track(link, result)
+			}
+			report.Results[i] = result
+		}(i, link)
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(report.Started)
+
+	return report, errorResponse
+}
+
+// Probe one destination, HEAD is used first, GET when HEAD is answered with 4xx or 501
+// (many origins reject HEAD with 403 or 404 while GET works)
+func (checker *Checker) Probe(ctx context.Context, rawURL string) *Result {
+	result := &Result{Url: rawURL}
+	started := time.Now()
+
+	resp, e := checker.follow(ctx, http.MethodHead, rawURL, result)
+	if e == nil && ((resp.StatusCode >= 400 && resp.StatusCode < 500) || resp.StatusCode == http.StatusNotImplemented) {
+		result.Redirects = nil
+		resp, e = checker.follow(ctx, http.MethodGet, rawURL, result)
+	}
+
+	result.Duration = time.Since(started)
+
+	if e != nil {
+		result.Status = StatusBroken
+		if ctx.Err() != nil {
+			result.Status = StatusCancelled
+		}
+		result.ErrorKind = classify(e)
+		result.Error = e.Error()
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.Slow = checker.SlowThreshold > 0 && result.Duration > checker.SlowThreshold
+
+	switch {
+	case resp.StatusCode >= 400:
+		result.Status = StatusBroken
+		result.ErrorKind = ErrorStatus
+		result.Error = resp.Status
+	case len(result.Redirects) > 0:
+		result.Status = StatusRedirected
+	default:
+		result.Status = StatusOK
+	}
+
+	return result
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// Send request following redirects hop by hop
+func (checker *Checker) follow(ctx context.Context, method string, rawURL string, result *Result) (*http.Response, error) {
+	client := http.Client{}
+	if checker.HTTPClient != nil {
+		client = *checker.HTTPClient
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	current, e := url.Parse(rawURL)
+	if e != nil {
+		return nil, e
+	}
+
+	for hop := 0; ; hop++ {
+		if e = checker.wait(ctx, current.Host); e != nil {
+			return nil, e
+		}
+
+		resp, e := checker.send(ctx, &client, method, current)
+		if e != nil {
+			return nil, e
+		}
+
+		result.FinalUrl = current.String()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return resp, nil
+		}
+
+		if hop >= checker.MaxRedirects {
+			return nil, errTooManyRedirects
+		}
+
+		next, e := current.Parse(location)
+		if e != nil {
+			return nil, e
+		}
+
+		result.Redirects = append(result.Redirects, next.String())
+		current = next
+	}
+}
+
+func (checker *Checker) send(ctx context.Context, client *http.Client, method string, target *url.URL) (*http.Response, error) {
+	if checker.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, checker.Timeout)
+		defer cancel()
+	}
+
+	req, e := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if e != nil {
+		return nil, e
+	}
+
+	if checker.UserAgent != "" {
+		req.Header.Set("User-Agent", checker.UserAgent)
+	}
+
+	resp, e := client.Do(req)
+	if e != nil {
+		return nil, e
+	}
+
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// Wait until HostDelay passed since previous request to the host
+func (checker *Checker) wait(ctx context.Context, host string) error {
+	checker.mu.Lock()
+	if checker.hosts == nil {
+		checker.hosts = make(map[string]*hostGate)
+	}
+	gate, ok := checker.hosts[host]
+	if !ok {
+		gate = &hostGate{}
+		checker.hosts[host] = gate
+	}
+	checker.mu.Unlock()
+
+	gate.mu.Lock()
+	defer gate.mu.Unlock()
+
+	if delay := time.Until(gate.last.Add(checker.HostDelay)); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	gate.last = time.Now()
+	return nil
+}
+
+// Count consecutive failures and deactivate links broken for too long
+func (checker *Checker) track(link *models.LinkUserResponse, result *Result) {
+	checker.mu.Lock()
+	if checker.failures == nil {
+		checker.failures = make(map[string]int)
+	}
+	if result.Status == StatusBroken {
+		checker.failures[link.Hash]++
+	} else {
+		delete(checker.failures, link.Hash)
+	}
+	result.Failures = checker.failures[link.Hash]
+	checker.mu.Unlock()
+
+	if checker.DeactivateAfter <= 0 || result.Failures < checker.DeactivateAfter || link.Active != 1 {
+		return
+	}
+
+	status, errorResponse := checker.Client.SetActive(link.Hash, &models.LinkActivationRequest{Active: false})
+	if len(errorResponse.Errors) > 0 {
+		result.Error += "; deactivation failed: " + errorResponse.Errors[0]
+		return
+	}
+
+	result.Deactivated = status
+}
+
+// Classify transport error
+func classify(e error) ErrorKind {
+	var dnsError *net.DNSError
+	var netError net.Error
+	var opError *net.OpError
+	var recordHeaderError tls.RecordHeaderError
+	var certificateError *tls.CertificateVerificationError
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var invalidError x509.CertificateInvalidError
+
+	switch {
+	case errors.Is(e, errTooManyRedirects):
+		return ErrorTooManyRedirects
+	case errors.As(e, &dnsError):
+		return ErrorDNS
+	case errors.As(e, &certificateError), errors.As(e, &unknownAuthorityError),
+		errors.As(e, &hostnameError), errors.As(e, &invalidError), errors.As(e, &recordHeaderError):
+		return ErrorTLS
+	case errors.Is(e, context.DeadlineExceeded), errors.As(e, &netError) && netError.Timeout():
+		return ErrorTimeout
+	case errors.As(e, &opError):
+		return ErrorConnection
+	}
+
+	return ErrorOther
+}
+
+// Results with broken destinations
+func (report *Report) Broken() []*Result {
+	return report.filter(func(result *Result) bool { return result.Status == StatusBroken })
+}
+
+// Results with destinations answering by redirect
+func (report *Report) Redirected() []*Result {
+	return report.filter(func(result *Result) bool { return result.Status == StatusRedirected })
+}
+
+// Results with destinations answering slower than SlowThreshold
+func (report *Report) Slow() []*Result {
+	return report.filter(func(result *Result) bool { return result.Slow })
+}
+
+func (report *Report) filter(fn func(result *Result) bool) []*Result {
+	var results []*Result
+
+	for _, result := range report.Results {
+		if fn(result) {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
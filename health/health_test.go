@@ -0,0 +1,266 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChecker_Probe(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/redirect":
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/slow":
+			time.Sleep(30 * time.Millisecond)
+		case "/head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/head-forbidden":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		case "/timeout":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	tsTLS := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tsTLS.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tsTLS.StartTLS()
+
+	tsClosed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := tsClosed.URL
+	tsClosed.Close()
+
+	defer ts.Close()
+	defer tsTLS.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		timeout       time.Duration
+		wantStatus    Status
+		wantCode      int
+		wantKind      ErrorKind
+		wantRedirects []string
+		wantSlow      bool
+	}{
+		{
+			name:       "test_ok",
+			url:        ts.URL + "/ok",
+			wantStatus: StatusOK,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:          "test_redirect_chain",
+			url:           ts.URL + "/redirect",
+			wantStatus:    StatusRedirected,
+			wantCode:      http.StatusOK,
+			wantRedirects: []string{ts.URL + "/moved", ts.URL + "/ok"},
+		},
+		{
+			name:       "test_not_found",
+			url:        ts.URL + "/missing",
+			wantStatus: StatusBroken,
+			wantCode:   http.StatusNotFound,
+			wantKind:   ErrorStatus,
+		},
+		{
+			name:       "test_head_not_allowed",
+			url:        ts.URL + "/head",
+			wantStatus: StatusOK,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "test_head_forbidden",
+			url:        ts.URL + "/head-forbidden",
+			wantStatus: StatusOK,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "test_slow",
+			url:        ts.URL + "/slow",
+			wantStatus: StatusOK,
+			wantCode:   http.StatusOK,
+			wantSlow:   true,
+		},
+		{
+			name:       "test_timeout",
+			url:        ts.URL + "/timeout",
+			timeout:    100 * time.Millisecond,
+			wantStatus: StatusBroken,
+			wantKind:   ErrorTimeout,
+		},
+		{
+			name:       "test_too_many_redirects",
+			url:        ts.URL + "/loop",
+			wantStatus: StatusBroken,
+			wantKind:   ErrorTooManyRedirects,
+			wantRedirects: []string{
+				ts.URL + "/loop", ts.URL + "/loop", ts.URL + "/loop",
+			},
+		},
+		{
+			name:       "test_tls",
+			url:        tsTLS.URL,
+			wantStatus: StatusBroken,
+			wantKind:   ErrorTLS,
+		},
+		{
+			name:       "test_connection",
+			url:        closedURL,
+			wantStatus: StatusBroken,
+			wantKind:   ErrorConnection,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(nil)
+			checker.HostDelay = 0
+			checker.SlowThreshold = 20 * time.Millisecond
+			checker.MaxRedirects = 3
+			// Only the timeout case has a short timeout, TLS handshake is slow under -race
+			checker.Timeout = 5 * time.Second
+			if tt.timeout > 0 {
+				checker.Timeout = tt.timeout
+			}
+
+			got := checker.Probe(context.Background(), tt.url)
+			if got.Status != tt.wantStatus {
+				t.Errorf("Probe() Status = %v, want %v (%s)", got.Status, tt.wantStatus, got.Error)
+			}
+			if got.StatusCode != tt.wantCode {
+				t.Errorf("Probe() StatusCode = %v, want %v", got.StatusCode, tt.wantCode)
+			}
+			if got.ErrorKind != tt.wantKind {
+				t.Errorf("Probe() ErrorKind = %v, want %v (%s)", got.ErrorKind, tt.wantKind, got.Error)
+			}
+			if !reflect.DeepEqual(got.Redirects, tt.wantRedirects) {
+				t.Errorf("Probe() Redirects = %v, want %v", got.Redirects, tt.wantRedirects)
+			}
+			if got.Slow != tt.wantSlow {
+				t.Errorf("Probe() Slow = %v, want %v", got.Slow, tt.wantSlow)
+			}
+		})
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+		}
+	}))
+
+	defer destination.Close()
+
+	var mu sync.Mutex
+	var deactivated []string
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/client/url":
+			_ = json.NewEncoder(w).Encode(&models.PaginatedLinkUserResponse{
+				Data: []*models.LinkUserResponse{
+					{Hash: "ok", Url: destination.URL + "/ok", Active: 1},
+					{Hash: "broken", Url: destination.URL + "/broken", Active: 1},
+					{Hash: "inactive", Url: destination.URL + "/inactive", Active: 0},
+				},
+				Total: 3,
+			})
+		case r.Method == http.MethodPatch:
+			mu.Lock()
+			deactivated = append(deactivated, strings.TrimPrefix(r.URL.Path, "/v1/client/"))
+			mu.Unlock()
+			_, _ = w.Write([]byte("{}"))
+		}
+	}))
+
+	defer api.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(api.URL + "/v1")
+
+	checker := NewChecker(client)
+	checker.HostDelay = time.Millisecond
+	checker.DeactivateAfter = 2
+
+	tests := []struct {
+		name            string
+		wantBroken      []string
+		wantFailures    int
+		wantDeactivated []string
+	}{
+		{
+			name:         "test_first_check",
+			wantBroken:   []string{"broken", "inactive"},
+			wantFailures: 1,
+		},
+		{
+			name:            "test_second_check",
+			wantBroken:      []string{"broken", "inactive"},
+			wantFailures:    2,
+			wantDeactivated: []string{"broken"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, errorResponse := checker.Check(context.Background())
+			if len(errorResponse.Errors) > 0 {
+				t.Fatalf("Check() errorResponse = %v", errorResponse)
+			}
+
+			var gotBroken []string
+			for _, result := range report.Broken() {
+				gotBroken = append(gotBroken, result.Hash)
+				if result.Failures != tt.wantFailures {
+					t.Errorf("Check() %s Failures = %v, want %v", result.Hash, result.Failures, tt.wantFailures)
+				}
+			}
+
+			if !reflect.DeepEqual(gotBroken, tt.wantBroken) {
+				t.Errorf("Check() Broken() = %v, want %v", gotBroken, tt.wantBroken)
+			}
+			if !reflect.DeepEqual(deactivated, tt.wantDeactivated) {
+				t.Errorf("Check() deactivated = %v, want %v", deactivated, tt.wantDeactivated)
+			}
+		})
+	}
+
+	// Probes cancelled by caller neither count as failures nor deactivate links
+	cancelled := NewChecker(client)
+	cancelled.DeactivateAfter = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, _ := cancelled.Check(ctx)
+	for _, result := range report.Results {
+		if result.Status != StatusCancelled || result.Failures != 0 {
+			t.Errorf("Check() cancelled %s = %v, failures %v", result.Hash, result.Status, result.Failures)
+		}
+	}
+	if !reflect.DeepEqual(deactivated, []string{"broken"}) {
+		t.Errorf("Check() cancelled deactivated = %v", deactivated)
+	}
+}
@@ -0,0 +1,72 @@
+package idempotent
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonical form of destination URL:
+// scheme and host in lower case, default port removed, dot segments resolved,
+// empty path replaced by "/", trailing slash removed from other paths,
+// query sorted by key and value, empty query and empty fragment removed.
+func Canonicalize(rawURL string) (string, error) {
+	parsed, e := url.Parse(strings.TrimSpace(rawURL))
+	if e != nil {
+		return "", e
+	}
+
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("idempotent: url %q is not absolute", rawURL)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+
+	host := strings.ToLower(parsed.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := parsed.Port(); port != "" && port != defaultPorts[parsed.Scheme] {
+		host += ":" + port
+	}
+	parsed.Host = host
+
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	} else {
+		parsed.Path = path.Clean(parsed.Path)
+	}
+	parsed.RawPath = ""
+
+	query := parsed.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	parsed.RawQuery = strings.Join(pairs, "&")
+	parsed.ForceQuery = false
+
+	if parsed.Fragment == "" {
+		parsed.RawFragment = ""
+	}
+
+	return parsed.String(), nil
+}
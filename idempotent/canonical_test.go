@@ -0,0 +1,37 @@
+package idempotent
+
+import "testing"
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{name: "test_case", url: "HTTPS://Test.COM/Path", want: "https://test.com/Path"},
+		{name: "test_default_port", url: "http://test.com:80/a", want: "http://test.com/a"},
+		{name: "test_custom_port", url: "https://test.com:8443/a", want: "https://test.com:8443/a"},
+		{name: "test_empty_path", url: "https://test.com", want: "https://test.com/"},
+		{name: "test_trailing_slash", url: "https://test.com/a/b/", want: "https://test.com/a/b"},
+		{name: "test_dot_segments", url: "https://test.com/a/./b/../c", want: "https://test.com/a/c"},
+		{name: "test_sorted_query", url: "https://test.com/?b=2&a=3&a=1", want: "https://test.com/?a=1&a=3&b=2"},
+		{name: "test_empty_query", url: "https://test.com/a?", want: "https://test.com/a"},
+		{name: "test_fragment", url: "https://test.com/a#Top", want: "https://test.com/a#Top"},
+		{name: "test_empty_fragment", url: "https://test.com/a#", want: "https://test.com/a"},
+		{name: "test_ipv6", url: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "test_relative", url: "/a/b", wantErr: true},
+		{name: "test_wrong_url", url: "http://[::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Canonicalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Canonicalize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package idempotent creates short links only once per destination.
+//
+// Destination URLs are canonicalized, an index of already created links is
+// consulted (and optionally the account itself via GetListUrls) before
+// CreateShortLink is called.
+package idempotent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Creator struct {
+	Client *tinysrc.Client
+	Index  Index
+	// Look for existing link with GetListUrls when index has no entry
+	SearchRemote bool
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+// Constructor of Creator, in-memory index is used when index is nil
+func NewCreator(client *tinysrc.Client, index Index) *Creator {
+	if index == nil {
+		index = NewMemoryIndex()
+	}
+
+	return &Creator{Client: client, Index: index}
+}
+
+// Idempotency key of request: canonical destination, auth flag and HMAC of password
+// keyed by secret of index. Links with another password or auth flag are never reused.
+func Key(request models.LinkRequest, secret []byte) (string, error) {
+	canonical, e := Canonicalize(request.Url)
+	if e != nil {
+		return "", e
+	}
+
+	key := canonical + " auth=" + strconv.Itoa(request.AuthRequired)
+	if request.Password != "" {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(request.Password.Reveal()))
+		key += " password=" + hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+
+	return key, nil
+}
+
+// Create a New Link unless link with the same key exists, reused reports existing link
+func (creator *Creator) Create(request models.LinkRequest) (r *models.LinkResponse, reused bool, errorResponse models.ErrorResponse) {
+	secret, e := creator.Index.Secret()
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+		return nil, false, errorResponse
+	}
+
+	key, e := Key(request, secret)
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+		return nil, false, errorResponse
+	}
+
+	unlock := creator.lock(key)
+	defer unlock()
+
+	entry, e := creator.Index.Get(key)
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+		return nil, false, errorResponse
+	}
+
+	if entry != nil {
+		// Index may not keep passwords, password of link is the one of request
+		link := entry.Link
+		if link.Password == "" {
+			link.Password = request.Password
+		}
+		return &link, true, errorResponse
+	}
+
+	if creator.SearchRemote {
+		found, searchErrors := creator.searchRemote(key, secret, request)
+		if len(searchErrors.Errors) > 0 || len(searchErrors.Validations) > 0 {
+			return nil, false, searchErrors
+		}
+
+		if found != nil {
+			if e = creator.Index.Put(found); e != nil {
+				errorResponse.Errors = append(errorResponse.Errors, e.Error())
+				return nil, false, errorResponse
+			}
+
+			link := found.Link
+			return &link, true, errorResponse
+		}
+	}
+
+	r, errorResponse = creator.Client.CreateShortLink(request)
+	if r == nil {
+		return nil, false, errorResponse
+	}
+
+	e = creator.Index.Put(&Entry{Key: key, Hash: tinysrc.HashFromUrl(r.Url), Link: *r, Created: time.Now()})
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+	}
+
+	return r, false, errorResponse
+}
+
+// Look for active link with the same key, destination host is used as search query
+func (creator *Creator) searchRemote(key string, secret []byte, request models.LinkRequest) (*Entry, models.ErrorResponse) {
+	var query string
+	if parsed, e := url.Parse(request.Url); e == nil {
+		query = parsed.Hostname()
+	}
+
+	links, errorResponse := creator.Client.GetAllUrls(query, 0)
+	if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return nil, errorResponse
+	}
+
+	for _, link := range links {
+		if link.Active != 1 {
+			continue
+		}
+
+		linkKey, e := Key(models.LinkRequest{Url: link.Url, AuthRequired: link.AuthRequired, Password: link.Password}, secret)
+		if e != nil || linkKey != key {
+			continue
+		}
+
+		entry := &Entry{
+			Key:  key,
+			Hash: link.Hash,
+			Link: models.LinkResponse{
+				Url:          tinysrc.ShortUrl(link.Hash),
+				StatUrl:      link.StatUrl,
+				StatPassword: link.StatPassword,
+				Password:     link.Password,
+				AuthRequired: link.AuthRequired,
+			},
+			Created: time.Now(),
+		}
+
+		if link.Created != nil {
+			entry.Created = *link.Created
+		}
+
+		return entry, errorResponse
+	}
+
+	return nil, errorResponse
+}
+
+// Serialize creation of the same key
+func (creator *Creator) lock(key string) func() {
+	creator.mu.Lock()
+	if creator.locks == nil {
+		creator.locks = make(map[string]*keyLock)
+	}
+	lock, ok := creator.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		creator.locks[key] = lock
+	}
+	lock.waiters++
+	creator.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		creator.mu.Lock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(creator.locks, key)
+		}
+		creator.mu.Unlock()
+	}
+}
@@ -0,0 +1,184 @@
+package idempotent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCreator_Create(t *testing.T) {
+	var mu sync.Mutex
+	created := 0
+	var queries []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/create":
+			request := models.LinkRequest{}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			created++
+			_ = json.NewEncoder(w).Encode(&models.LinkResponse{Url: tinysrc.ShortUrl("new" + string(rune('0'+created))), Password: request.Password, StatPassword: "stat-secret"})
+		case "/v1/client/url":
+			queries = append(queries, r.URL.Query().Get("query"))
+			_ = json.NewEncoder(w).Encode(&models.PaginatedLinkUserResponse{
+				Data: []*models.LinkUserResponse{
+					{Hash: "old", Url: "https://remote.com/a?x=1&y=2", Active: 1, StatUrl: "https://tinysrc.me/stat/old"},
+					{Hash: "inactive", Url: "https://inactive.com/", Active: 0},
+				},
+				Total: 2,
+			})
+		}
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	index, err := OpenFileIndex(filepath.Join(t.TempDir(), "index.json"))
+	if err != nil {
+		t.Fatalf("OpenFileIndex() error = %v", err)
+	}
+
+	creator := NewCreator(client, index)
+	creator.SearchRemote = true
+
+	tests := []struct {
+		name         string
+		request      models.LinkRequest
+		wantUrl      string
+		wantPassword models.Secret
+		wantReused   bool
+		wantCreated  int
+	}{
+		{
+			name:        "test_create",
+			request:     models.LinkRequest{Url: "https://test.com/a/"},
+			wantUrl:     tinysrc.ShortUrl("new1"),
+			wantCreated: 1,
+		},
+		{
+			name:        "test_reuse_canonical",
+			request:     models.LinkRequest{Url: "HTTPS://test.com:443/a"},
+			wantUrl:     tinysrc.ShortUrl("new1"),
+			wantReused:  true,
+			wantCreated: 1,
+		},
+		{
+			name:        "test_password_is_other_link",
+			request:     models.LinkRequest{Url: "https://test.com/a", Password: "secret"},
+			wantUrl:     tinysrc.ShortUrl("new2"),
+			wantCreated: 2,
+		},
+		{
+			name:         "test_reuse_password",
+			request:      models.LinkRequest{Url: "https://test.com/a", Password: "secret"},
+			wantUrl:      tinysrc.ShortUrl("new2"),
+			wantPassword: "secret",
+			wantReused:   true,
+			wantCreated:  2,
+		},
+		{
+			name:        "test_reuse_remote",
+			request:     models.LinkRequest{Url: "https://remote.com/a/?y=2&x=1"},
+			wantUrl:     tinysrc.ShortUrl("old"),
+			wantReused:  true,
+			wantCreated: 2,
+		},
+		{
+			name:        "test_inactive_remote_is_not_reused",
+			request:     models.LinkRequest{Url: "https://inactive.com"},
+			wantUrl:     tinysrc.ShortUrl("new3"),
+			wantCreated: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotR, gotReused, gotErrorResponse := creator.Create(tt.request)
+			if len(gotErrorResponse.Errors) > 0 {
+				t.Fatalf("Create() gotErrorResponse = %v", gotErrorResponse)
+			}
+			if gotR.Url != tt.wantUrl {
+				t.Errorf("Create() Url = %v, want %v", gotR.Url, tt.wantUrl)
+			}
+			if tt.wantPassword != "" && gotR.Password != tt.wantPassword {
+				t.Errorf("Create() Password = %v, want %v", gotR.Password, tt.wantPassword)
+			}
+			if gotReused != tt.wantReused {
+				t.Errorf("Create() reused = %v, want %v", gotReused, tt.wantReused)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("Create() created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+
+	if data, _ := os.ReadFile(index.path); strings.Contains(string(data), `"secret"`) || strings.Contains(string(data), "stat-secret") {
+		t.Errorf("FileIndex stores passwords:\n%s", data)
+	}
+
+	reopened, _ := OpenFileIndex(index.path)
+	secret, _ := reopened.Secret()
+	key, _ := Key(models.LinkRequest{Url: "https://remote.com/a?x=1&y=2"}, secret)
+	if entry, _ := reopened.Get(key); entry == nil || entry.Hash != "old" {
+		t.Errorf("OpenFileIndex() entry = %v, want hash old", entry)
+	}
+
+	// Password digest depends on secret kept with the index
+	key, _ = Key(models.LinkRequest{Url: "https://test.com/a", Password: "secret"}, secret)
+	if entry, _ := reopened.Get(key); entry == nil || entry.Hash != "new2" || entry.Link.Password != "" || entry.Link.StatPassword != "" {
+		t.Errorf("OpenFileIndex() password entry = %v, want hash new2 without passwords", entry)
+	}
+	other, _ := NewMemoryIndex().Secret()
+	if otherKey, _ := Key(models.LinkRequest{Url: "https://test.com/a", Password: "secret"}, other); otherKey == key {
+		t.Errorf("Key() = %v does not depend on secret", key)
+	}
+
+	if queries[0] != "test.com" {
+		t.Errorf("Create() search query = %v, want test.com", queries[0])
+	}
+}
+
+func TestCreator_CreateConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	created := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		created++
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(&models.LinkResponse{Url: tinysrc.ShortUrl("new")})
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	creator := NewCreator(client, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = creator.Create(models.LinkRequest{Url: "https://test.com"})
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("Create() created = %v, want 1", created)
+	}
+}
@@ -0,0 +1,184 @@
+package idempotent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Short link created for idempotency key
+type Entry struct {
+	Key     string              `json:"key"`
+	Hash    string              `json:"hash"`
+	Link    models.LinkResponse `json:"link"`
+	Created time.Time           `json:"created"`
+}
+
+const SECRET_SIZE = 32
+
+// Storage of created links, Get returns nil entry when key is unknown.
+// Secret is random key of password digests in keys, it is created once and kept with the index.
+type Index interface {
+	Get(key string) (*Entry, error)
+	Put(entry *Entry) error
+	Secret() ([]byte, error)
+}
+
+// Index kept in memory
+type MemoryIndex struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+	secret  []byte
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{entries: make(map[string]*Entry)}
+}
+
+func (index *MemoryIndex) Get(key string) (*Entry, error) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	return index.entries[key], nil
+}
+
+func (index *MemoryIndex) Put(entry *Entry) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.entries[entry.Key] = entry
+	return nil
+}
+
+func (index *MemoryIndex) Secret() ([]byte, error) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.secret == nil {
+		secret, e := newSecret()
+		if e != nil {
+			return nil, e
+		}
+		index.secret = secret
+	}
+
+	return index.secret, nil
+}
+
+// Index persisted to JSON file, file is rewritten on every Put. Secret is kept
+// in file with ".key" suffix next to the index, both files are readable by owner only (0600).
+//
+// Password and StatPassword of links are never stored, so entries of Get have them empty.
+type FileIndex struct {
+	path    string
+	mu      sync.RWMutex
+	entries map[string]*Entry
+	secret  []byte
+}
+
+// Open index file, missing file is created on first Put
+func OpenFileIndex(path string) (*FileIndex, error) {
+	index := &FileIndex{path: path, entries: make(map[string]*Entry)}
+
+	secret, e := os.ReadFile(index.secretPath())
+	if e != nil && !errors.Is(e, os.ErrNotExist) {
+		return nil, e
+	}
+	if e == nil {
+		if index.secret, e = hex.DecodeString(string(secret)); e != nil || len(index.secret) == 0 {
+			return nil, fmt.Errorf("idempotent: %s: invalid secret", index.secretPath())
+		}
+	}
+
+	data, e := os.ReadFile(path)
+	if errors.Is(e, os.ErrNotExist) {
+		return index, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	if e = json.Unmarshal(data, &index.entries); e != nil {
+		return nil, fmt.Errorf("idempotent: %s: %w", path, e)
+	}
+
+	return index, nil
+}
+
+func (index *FileIndex) Get(key string) (*Entry, error) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	return index.entries[key], nil
+}
+
+func (index *FileIndex) Put(entry *Entry) error {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	stored := *entry
+	stored.Link.Password, stored.Link.StatPassword = "", ""
+	index.entries[entry.Key] = &stored
+
+	data, e := json.MarshalIndent(index.entries, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	return index.write(index.path, data)
+}
+
+// Secret is created and written on first use
+func (index *FileIndex) Secret() ([]byte, error) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	if index.secret != nil {
+		return index.secret, nil
+	}
+
+	secret, e := newSecret()
+	if e != nil {
+		return nil, e
+	}
+
+	if e = index.write(index.secretPath(), []byte(hex.EncodeToString(secret))); e != nil {
+		return nil, e
+	}
+
+	index.secret = secret
+	return secret, nil
+}
+
+func (index *FileIndex) secretPath() string {
+	return index.path + ".key"
+}
+
+// Replace file atomically, readable by owner only
+func (index *FileIndex) write(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if e := os.MkdirAll(filepath.Dir(path), 0o755); e != nil {
+		return e
+	}
+	if e := os.WriteFile(tmp, data, 0o600); e != nil {
+		return e
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func newSecret() ([]byte, error) {
+	secret := make([]byte, SECRET_SIZE)
+	if _, e := rand.Read(secret); e != nil {
+		return nil, e
+	}
+
+	return secret, nil
+}
@@ -6,10 +6,37 @@ import (
 	"github.com/dmitrypro77/tinysrc-api-sdk/models"
 	"net/http"
 	"net/url"
+	"path"
+	"strconv"
+	"strings"
 )
 
+// Short Url of hash
+func ShortUrl(hash string) string {
+	return SHORT_URL + "/" + hash
+}
+
+// Hash of short Url, last path segment
+func HashFromUrl(shortUrl string) string {
+	parsed, e := url.Parse(shortUrl)
+	if e != nil {
+		return ""
+	}
+
+	trimmed := strings.Trim(parsed.Path, "/")
+	if trimmed == "" {
+		return ""
+	}
+
+	return path.Base(trimmed)
+}
+
 // Create a New Link
 func (client *Client) CreateShortLink(requestData models.LinkRequest) (r *models.LinkResponse, errorResponse models.ErrorResponse) {
+	if requestData.ExpiresAt != nil {
+		requestData.ExpirationTime = client.FormatTime(*requestData.ExpiresAt)
+	}
+
 	body, e := json.Marshal(requestData)
 	if e != nil {
 		errorResponse.Errors = append(errorResponse.Errors, e.Error())
@@ -43,8 +70,8 @@ func (client *Client) CreateShortLink(requestData models.LinkRequest) (r *models
 func (client *Client) GetListUrls(params models.ListUrlsRequest) (r *models.PaginatedLinkUserResponse, errorResponse models.ErrorResponse) {
 	values := url.Values{}
 
-	values.Add("limit", string(rune(params.Limit)))
-	values.Add("page", string(rune(params.Page)))
+	values.Add("limit", strconv.Itoa(params.Limit))
+	values.Add("page", strconv.Itoa(params.Page))
 	values.Add("query", params.Query)
 
 	resp, e := client.sendRequest(http.MethodGet, "/client/url"+"?"+values.Encode(), nil)
@@ -68,9 +95,38 @@ func (client *Client) GetListUrls(params models.ListUrlsRequest) (r *models.Pagi
 		return nil, errorResponse
 	}
 
+	for _, link := range listUrls.Data {
+		client.localizeLink(link)
+	}
+
 	return &listUrls, errorResponse
 }
 
+// Get All My Urls reading list page by page
+func (client *Client) GetAllUrls(query string, pageSize int) (r []*models.LinkUserResponse, errorResponse models.ErrorResponse) {
+	if pageSize <= 0 {
+		pageSize = LIST_PAGE_SIZE
+	}
+
+	for page := 1; ; page++ {
+		listUrls, listErrors := client.GetListUrls(models.ListUrlsRequest{
+			Limit: pageSize,
+			Page:  page,
+			Query: query,
+		})
+
+		if len(listErrors.Errors) > 0 || len(listErrors.Validations) > 0 {
+			return nil, listErrors
+		}
+
+		r = append(r, listUrls.Data...)
+
+		if len(listUrls.Data) == 0 || int64(len(r)) >= listUrls.Total {
+			return r, errorResponse
+		}
+	}
+}
+
 // Get List My Urls
 func (client *Client) GetUrlByHash(hash string) (r *models.LinkUserResponse, errorResponse models.ErrorResponse) {
 	resp, e := client.sendRequest(http.MethodGet, "/client/url/"+hash, nil)
@@ -95,6 +151,8 @@ func (client *Client) GetUrlByHash(hash string) (r *models.LinkUserResponse, err
 		return nil, errorResponse
 	}
 
+	client.localizeLink(&urlInfo)
+
 	return &urlInfo, errorResponse
 }
 
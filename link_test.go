@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -298,3 +299,91 @@ func TestClient_SetActive(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetAllUrls(t *testing.T) {
+	var links []*models.LinkUserResponse
+	for i := 0; i < 5; i++ {
+		links = append(links, &models.LinkUserResponse{Hash: strconv.Itoa(i), Url: "http://test.com/" + strconv.Itoa(i)})
+	}
+
+	var gotQueries []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQueries = append(gotQueries, r.URL.RawQuery)
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+
+		resp := models.PaginatedLinkUserResponse{Total: int64(len(links))}
+		for i := (page - 1) * limit; i < page*limit && i < len(links); i++ {
+			resp.Data = append(resp.Data, links[i])
+		}
+
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+
+	defer ts.Close()
+
+	testClient, _ := NewClient(context.Background(), "test", nil)
+
+	testClient.baseURL = &url.URL{
+		Path: ts.URL,
+	}
+
+	tests := []struct {
+		name        string
+		pageSize    int
+		wantQueries []string
+	}{
+		{
+			name:     "test_pages",
+			pageSize: 2,
+			wantQueries: []string{
+				"limit=2&page=1&query=test",
+				"limit=2&page=2&query=test",
+				"limit=2&page=3&query=test",
+			},
+		},
+		{
+			name:        "test_default_page_size",
+			pageSize:    0,
+			wantQueries: []string{"limit=100&page=1&query=test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotQueries = nil
+
+			gotR, gotErrorResponse := testClient.GetAllUrls("test", tt.pageSize)
+			if !reflect.DeepEqual(gotR, links) {
+				t.Errorf("GetAllUrls() gotR = %v, want %v", gotR, links)
+			}
+			if len(gotErrorResponse.Errors) > 0 {
+				t.Errorf("GetAllUrls() gotErrorResponse = %v", gotErrorResponse)
+			}
+			if !reflect.DeepEqual(gotQueries, tt.wantQueries) {
+				t.Errorf("GetAllUrls() queries = %v, want %v", gotQueries, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestHashFromUrl(t *testing.T) {
+	tests := []struct {
+		name     string
+		shortUrl string
+		want     string
+	}{
+		{name: "test_short_url", shortUrl: ShortUrl("abc"), want: "abc"},
+		{name: "test_trailing_slash", shortUrl: "https://tinysrc.me/abc/", want: "abc"},
+		{name: "test_empty_path", shortUrl: "https://tinysrc.me", want: ""},
+		{name: "test_wrong_url", shortUrl: "http://[::1", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashFromUrl(tt.shortUrl); got != tt.want {
+				t.Errorf("HashFromUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
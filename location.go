@@ -0,0 +1,67 @@
+package tinysrc
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"time"
+)
+
+// Set time zone of TinySRC server. Outgoing times (StatRequest dates,
+// LinkRequest.ExpiresAt) are converted into it before DATE_FORMAT is applied.
+// When not set, times are formatted in their own location.
+func (client *Client) SetServerLocation(loc *time.Location) {
+	client.serverLocation = loc
+}
+
+// Set time zone of decoded Created and ExpirationTime values.
+// When not set, decoded values are returned as is.
+func (client *Client) SetLocation(loc *time.Location) {
+	client.location = loc
+}
+
+// Format time for TinySRC API, see SetServerLocation
+func (client *Client) FormatTime(t time.Time) string {
+	if client.serverLocation != nil {
+		t = t.In(client.serverLocation)
+	}
+
+	return t.Format(DATE_FORMAT)
+}
+
+// Parse DATE_FORMAT time sent by TinySRC API, see SetServerLocation
+func (client *Client) ParseTime(value string) (time.Time, error) {
+	loc := client.serverLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t, e := time.ParseInLocation(DATE_FORMAT, value, loc)
+	if e != nil {
+		return t, e
+	}
+
+	return client.localTime(t), nil
+}
+
+func (client *Client) localTime(t time.Time) time.Time {
+	if client.location == nil || t.IsZero() {
+		return t
+	}
+
+	return t.In(client.location)
+}
+
+func (client *Client) localizeLink(link *models.LinkUserResponse) {
+	if link.Created != nil {
+		created := client.localTime(*link.Created)
+		link.Created = &created
+	}
+
+	if link.ExpirationTime != nil {
+		expirationTime := client.localTime(*link.ExpirationTime)
+		link.ExpirationTime = &expirationTime
+	}
+}
+
+func (client *Client) localizeStat(stat *models.StatResponse) {
+	stat.Created = client.localTime(stat.Created)
+}
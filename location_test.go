@@ -0,0 +1,172 @@
+package tinysrc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, e := time.LoadLocation(name)
+	if e != nil {
+		t.Fatalf("LoadLocation(%s) error = %v", name, e)
+	}
+
+	return loc
+}
+
+func TestClient_FormatTime(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name   string
+		server *time.Location
+		time   time.Time
+		want   string
+	}{
+		{
+			name:   "test_without_server_location",
+			server: nil,
+			time:   time.Date(2022, 3, 27, 3, 30, 0, 0, berlin),
+			want:   "2022-03-27 03:30",
+		},
+		{
+			// Berlin is already on summer time, New York is on summer time since March 13
+			name:   "test_berlin_after_dst_to_new_york",
+			server: newYork,
+			time:   time.Date(2022, 3, 27, 3, 30, 0, 0, berlin),
+			want:   "2022-03-26 21:30",
+		},
+		{
+			// New York switched to summer time, Berlin did not yet
+			name:   "test_berlin_between_dst_to_new_york",
+			server: newYork,
+			time:   time.Date(2022, 3, 20, 12, 0, 0, 0, berlin),
+			want:   "2022-03-20 07:00",
+		},
+		{
+			// 01:30 happens twice in New York on November 6, this is the second one
+			name:   "test_utc_to_new_york_repeated_hour",
+			server: newYork,
+			time:   time.Date(2022, 11, 6, 6, 30, 0, 0, time.UTC),
+			want:   "2022-11-06 01:30",
+		},
+		{
+			name:   "test_new_york_to_utc",
+			server: time.UTC,
+			time:   time.Date(2022, 11, 6, 3, 0, 0, 0, newYork),
+			want:   "2022-11-06 08:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testClient, _ := NewClient(context.Background(), "test", nil)
+			testClient.SetServerLocation(tt.server)
+
+			if got := testClient.FormatTime(tt.time); got != tt.want {
+				t.Errorf("FormatTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_ParseTime(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	testClient, _ := NewClient(context.Background(), "test", nil)
+	testClient.SetServerLocation(newYork)
+	testClient.SetLocation(berlin)
+
+	got, e := testClient.ParseTime("2022-03-20 07:00")
+	if e != nil {
+		t.Fatalf("ParseTime() error = %v", e)
+	}
+
+	want := time.Date(2022, 3, 20, 12, 0, 0, 0, berlin)
+	if !got.Equal(want) || got.Location() != berlin {
+		t.Errorf("ParseTime() = %v, want %v", got, want)
+	}
+
+	if _, e = testClient.ParseTime("20-03-2022"); e == nil {
+		t.Errorf("ParseTime() error = nil, want error")
+	}
+}
+
+func TestClient_LocationRoundTrip(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	created := time.Date(2022, 10, 30, 1, 30, 0, 0, time.UTC)
+
+	var gotExpirationTime, gotDateStart string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/create":
+			request := models.LinkRequest{}
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			gotExpirationTime = request.ExpirationTime
+			_, _ = io.WriteString(w, `{"url":"http://test.com"}`)
+		case "/client/url/test":
+			_ = json.NewEncoder(w).Encode(&models.LinkUserResponse{Hash: "test", Created: &created})
+		case "/client/stat/test":
+			gotDateStart = r.URL.Query().Get("date-start")
+			_ = json.NewEncoder(w).Encode(&models.StatPaginatedResponse{
+				Data:  []*models.StatResponse{{Ip: "8.8.8.8", Created: created}},
+				Total: 1,
+			})
+		}
+	}))
+
+	defer ts.Close()
+
+	testClient, _ := NewClient(context.Background(), "test", nil)
+	testClient.baseURL = &url.URL{
+		Path: ts.URL,
+	}
+	testClient.SetServerLocation(newYork)
+	testClient.SetLocation(berlin)
+
+	// Berlin left summer time on October 30, New York leaves it on November 6
+	expiresAt := time.Date(2022, 10, 30, 12, 0, 0, 0, berlin)
+	_, errorResponse := testClient.CreateShortLink(models.LinkRequest{Url: "http://test.com", ExpiresAt: &expiresAt})
+	if len(errorResponse.Errors) > 0 {
+		t.Fatalf("CreateShortLink() errorResponse = %v", errorResponse)
+	}
+	if want := "2022-10-30 07:00"; gotExpirationTime != want {
+		t.Errorf("CreateShortLink() expiration_time = %v, want %v", gotExpirationTime, want)
+	}
+
+	link, errorResponse := testClient.GetUrlByHash("test")
+	if len(errorResponse.Errors) > 0 {
+		t.Fatalf("GetUrlByHash() errorResponse = %v", errorResponse)
+	}
+	if !link.Created.Equal(created) || link.Created.Location() != berlin {
+		t.Errorf("GetUrlByHash() Created = %v, want %v in Europe/Berlin", link.Created, created)
+	}
+
+	stat, errorResponse := testClient.GetStatByHash("test", models.StatRequest{
+		Limit:     10,
+		Page:      1,
+		DateStart: time.Date(2022, 10, 30, 2, 30, 0, 0, time.UTC),
+		DateEnd:   time.Date(2022, 11, 30, 2, 30, 0, 0, time.UTC),
+	})
+	if len(errorResponse.Errors) > 0 {
+		t.Fatalf("GetStatByHash() errorResponse = %v", errorResponse)
+	}
+	if want := "2022-10-29 22:30"; gotDateStart != want {
+		t.Errorf("GetStatByHash() date-start = %v, want %v", gotDateStart, want)
+	}
+	if got := stat.Data[0].Created; !got.Equal(created) || got.Format("15:04 MST") != "02:30 CET" {
+		t.Errorf("GetStatByHash() Created = %v, want 02:30 CET", got)
+	}
+}
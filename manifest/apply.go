@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+)
+
+// Execute plan, state is saved after every applied action and every created link.
+// Applying stops on the first failed action, returned actions are applied ones.
+func (engine *Engine) Apply(plan *Plan, state *State) (applied []*Action, errorResponse models.ErrorResponse) {
+	for _, action := range plan.Actions {
+		switch action.Type {
+		case ActionCreate:
+			errorResponse = engine.create(action, state)
+		case ActionRecreate:
+			errorResponse = engine.create(action, state)
+			if len(errorResponse.Errors) == 0 {
+				errorResponse = engine.setActive(action.Hash, false)
+			}
+		case ActionActivate:
+			errorResponse = engine.setActive(action.Hash, true)
+		case ActionDeactivate:
+			errorResponse = engine.setActive(action.Hash, false)
+			if len(errorResponse.Errors) == 0 && action.Link == nil {
+				delete(state.Hashes, action.Key)
+			}
+		case ActionDrift:
+			continue
+		}
+
+		if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			return applied, errorResponse
+		}
+
+		applied = append(applied, action)
+
+		if e := state.Save(); e != nil {
+			errorResponse.Errors = append(errorResponse.Errors, e.Error())
+			return applied, errorResponse
+		}
+	}
+
+	return applied, errorResponse
+}
+
+func (engine *Engine) create(action *Action, state *State) models.ErrorResponse {
+	request := models.LinkRequest{
+		Url:            action.Link.Url,
+		Password:       action.Link.Password,
+		ExpirationTime: action.Link.ExpirationTime,
+	}
+	if action.Link.AuthRequired {
+		request.AuthRequired = 1
+	}
+
+	link, errorResponse := engine.Client.CreateShortLink(request)
+	if link == nil {
+		if len(errorResponse.Errors) == 0 {
+			errorResponse.Errors = append(errorResponse.Errors, "unexpected response of create request")
+		}
+		return errorResponse
+	}
+
+	// Created link is saved at once, so failed follow-up request does not create it again
+	hash := tinysrc.HashFromUrl(link.Url)
+	state.Hashes[action.Key] = hash
+	if e := state.Save(); e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+		return errorResponse
+	}
+
+	if !action.Link.IsActive() {
+		return engine.setActive(hash, false)
+	}
+
+	return errorResponse
+}
+
+func (engine *Engine) setActive(hash string, active bool) models.ErrorResponse {
+	_, errorResponse := engine.Client.SetActive(hash, &models.LinkActivationRequest{Active: active})
+	return errorResponse
+}
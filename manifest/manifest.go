@@ -0,0 +1,150 @@
+// Package manifest manages links as code: a JSON manifest describes wanted
+// links, Plan diffs it with the account and Apply executes the actions.
+//
+//	{
+//	  "links": {
+//	    "docs": {"url": "https://test.com/docs", "active": true},
+//	    "beta": {"url": "https://test.com/beta", "auth_required": true, "password": "secret"}
+//	  }
+//	}
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/idempotent"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Wanted link
+type Link struct {
+	Url          string        `json:"url"`
+	AuthRequired bool          `json:"auth_required,omitempty"`
+	Password     models.Secret `json:"password,omitempty"`
+	// Expiration in tinysrc.DATE_FORMAT, server time zone
+	ExpirationTime string `json:"expiration_time,omitempty"`
+	// Active when omitted
+	Active *bool `json:"active,omitempty"`
+}
+
+type Manifest struct {
+	Links map[string]*Link `json:"links"`
+}
+
+// Mapping of manifest keys to hashes
+type State struct {
+	Hashes map[string]string `json:"hashes"`
+
+	path string
+}
+
+// Load and validate manifest file
+func Load(path string) (*Manifest, error) {
+	data, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	manifest := &Manifest{}
+	if e = json.Unmarshal(data, manifest); e != nil {
+		return nil, fmt.Errorf("manifest: %s: %w", path, e)
+	}
+
+	if e = manifest.Validate(); e != nil {
+		return nil, e
+	}
+
+	return manifest, nil
+}
+
+// Check every link of manifest
+func (manifest *Manifest) Validate() error {
+	var errs []error
+
+	for _, key := range manifest.Keys() {
+		link := manifest.Links[key]
+		if link == nil {
+			errs = append(errs, fmt.Errorf("manifest: %s: link is empty", key))
+			continue
+		}
+
+		if _, e := idempotent.Canonicalize(link.Url); e != nil {
+			errs = append(errs, fmt.Errorf("manifest: %s: %w", key, e))
+		}
+
+		if link.ExpirationTime != "" {
+			if _, e := time.Parse(tinysrc.DATE_FORMAT, link.ExpirationTime); e != nil {
+				errs = append(errs, fmt.Errorf("manifest: %s: expiration_time: %w", key, e))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Manifest keys in stable order
+func (manifest *Manifest) Keys() []string {
+	keys := make([]string, 0, len(manifest.Links))
+	for key := range manifest.Links {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Check if link should be active
+func (link *Link) IsActive() bool {
+	return link.Active == nil || *link.Active
+}
+
+// Open state file, missing file means empty state
+func OpenState(path string) (*State, error) {
+	state := &State{Hashes: make(map[string]string), path: path}
+
+	data, e := os.ReadFile(path)
+	if errors.Is(e, os.ErrNotExist) {
+		return state, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	if e = json.Unmarshal(data, state); e != nil {
+		return nil, fmt.Errorf("manifest: %s: %w", path, e)
+	}
+
+	if state.Hashes == nil {
+		state.Hashes = make(map[string]string)
+	}
+
+	return state, nil
+}
+
+// Write state file, in-memory states (empty path) are not written
+func (state *State) Save() error {
+	if state.path == "" {
+		return nil
+	}
+
+	data, e := json.MarshalIndent(state, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	tmp := state.path + ".tmp"
+	if e = os.MkdirAll(filepath.Dir(state.path), 0o755); e != nil {
+		return e
+	}
+	if e = os.WriteFile(tmp, data, 0o644); e != nil {
+		return e
+	}
+
+	return os.Rename(tmp, state.path)
+}
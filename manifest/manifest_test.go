@@ -0,0 +1,80 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantKeys []string
+		wantErr  bool
+	}{
+		{
+			name:     "test_success",
+			content:  `{"links":{"b":{"url":"https://test.com/b","active":false},"a":{"url":"https://test.com/a","expiration_time":"2022-01-01 10:00"}}}`,
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:    "test_relative_url",
+			content: `{"links":{"a":{"url":"/a"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "test_wrong_expiration",
+			content: `{"links":{"a":{"url":"https://test.com","expiration_time":"tomorrow"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "test_empty_link",
+			content: `{"links":{"a":null}}`,
+			wantErr: true,
+		},
+		{
+			name:    "test_not_json",
+			content: `links: []`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "links.json")
+			_ = os.WriteFile(path, []byte(tt.content), 0o644)
+
+			got, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got.Keys(), tt.wantKeys) {
+				t.Errorf("Load() keys = %v, want %v", got.Keys(), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestOpenState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "links.state.json")
+
+	state, err := OpenState(path)
+	if err != nil {
+		t.Fatalf("OpenState() error = %v", err)
+	}
+
+	state.Hashes["docs"] = "abc"
+	if err = state.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reopened, err := OpenState(path)
+	if err != nil {
+		t.Fatalf("OpenState() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(reopened.Hashes, map[string]string{"docs": "abc"}) {
+		t.Errorf("OpenState() Hashes = %v", reopened.Hashes)
+	}
+}
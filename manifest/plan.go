@@ -0,0 +1,209 @@
+package manifest
+
+import (
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/idempotent"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type ActionType string
+
+const (
+	ActionCreate     ActionType = "create"
+	ActionActivate   ActionType = "activate"
+	ActionDeactivate ActionType = "deactivate"
+	// Link can not be changed in place, new link is created and old one is deactivated
+	ActionRecreate ActionType = "recreate"
+	// Link differs from manifest but Engine.Recreate is disabled, nothing is applied
+	ActionDrift ActionType = "drift"
+)
+
+var actionSymbols = map[ActionType]string{
+	ActionCreate:     "+",
+	ActionActivate:   "~",
+	ActionDeactivate: "-",
+	ActionRecreate:   "-/+",
+	ActionDrift:      "!",
+}
+
+type Action struct {
+	Type ActionType `json:"type"`
+	Key  string     `json:"key"`
+	// Current hash, empty for new links
+	Hash   string `json:"hash,omitempty"`
+	Link   *Link  `json:"link,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type Plan struct {
+	Actions []*Action `json:"actions"`
+}
+
+// Plan and apply manifest
+type Engine struct {
+	Client *tinysrc.Client
+	// Deactivate links which are in state but not in manifest
+	Prune bool
+	// Recreate links whose destination, auth, password or expiration differ from manifest
+	Recreate bool
+}
+
+// Diff manifest with current state of the account
+func (engine *Engine) Plan(manifest *Manifest, state *State) (r *Plan, errorResponse models.ErrorResponse) {
+	links, errorResponse := engine.Client.GetAllUrls("", 0)
+	if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return nil, errorResponse
+	}
+
+	current := make(map[string]*models.LinkUserResponse, len(links))
+	for _, link := range links {
+		current[link.Hash] = link
+	}
+
+	plan := &Plan{}
+
+	for _, key := range manifest.Keys() {
+		wanted := manifest.Links[key]
+		hash := state.Hashes[key]
+
+		var link *models.LinkUserResponse
+		if hash != "" {
+			link, errorResponse = engine.lookup(current, hash)
+			if len(errorResponse.Errors) > 0 {
+				return nil, errorResponse
+			}
+		}
+
+		if link == nil {
+			plan.Actions = append(plan.Actions, &Action{Type: ActionCreate, Key: key, Link: wanted})
+			continue
+		}
+
+		if reasons := engine.drift(wanted, link); len(reasons) > 0 {
+			actionType := ActionDrift
+			if engine.Recreate {
+				actionType = ActionRecreate
+			}
+
+			plan.Actions = append(plan.Actions, &Action{Type: actionType, Key: key, Hash: hash, Link: wanted, Reason: strings.Join(reasons, ", ")})
+			continue
+		}
+
+		active := link.Active == 1
+		switch {
+		case wanted.IsActive() && !active:
+			plan.Actions = append(plan.Actions, &Action{Type: ActionActivate, Key: key, Hash: hash, Link: wanted})
+		case !wanted.IsActive() && active:
+			plan.Actions = append(plan.Actions, &Action{Type: ActionDeactivate, Key: key, Hash: hash, Link: wanted})
+		}
+	}
+
+	if engine.Prune {
+		var orphans []string
+		for key := range state.Hashes {
+			if _, ok := manifest.Links[key]; !ok {
+				orphans = append(orphans, key)
+			}
+		}
+		sort.Strings(orphans)
+
+		for _, key := range orphans {
+			hash := state.Hashes[key]
+			if link, ok := current[hash]; ok && link.Active == 1 {
+				plan.Actions = append(plan.Actions, &Action{Type: ActionDeactivate, Key: key, Hash: hash, Reason: "not in manifest"})
+			}
+		}
+	}
+
+	return plan, errorResponse
+}
+
+// Find link in list, fallback to GetUrlByHash; nil when link does not exist
+func (engine *Engine) lookup(current map[string]*models.LinkUserResponse, hash string) (*models.LinkUserResponse, models.ErrorResponse) {
+	if link, ok := current[hash]; ok {
+		return link, models.ErrorResponse{}
+	}
+
+	link, errorResponse := engine.Client.GetUrlByHash(hash)
+	if errorResponse.Status == http.StatusNotFound {
+		return nil, models.ErrorResponse{}
+	}
+
+	return link, errorResponse
+}
+
+// Fields which can not be changed with SetActive
+func (engine *Engine) drift(wanted *Link, link *models.LinkUserResponse) []string {
+	var reasons []string
+
+	wantedUrl, _ := idempotent.Canonicalize(wanted.Url)
+	currentUrl, _ := idempotent.Canonicalize(link.Url)
+	if wantedUrl != currentUrl {
+		reasons = append(reasons, fmt.Sprintf("url %s != %s", link.Url, wanted.Url))
+	}
+
+	if wanted.AuthRequired != (link.AuthRequired == 1) {
+		reasons = append(reasons, "auth_required changed")
+	}
+
+	if wanted.Password != "" && link.Password != "" && wanted.Password != link.Password {
+		reasons = append(reasons, "password changed")
+	}
+
+	var currentExpiration string
+	if link.ExpirationTime != nil {
+		currentExpiration = engine.Client.FormatTime(*link.ExpirationTime)
+	}
+	if wanted.ExpirationTime != currentExpiration {
+		reasons = append(reasons, fmt.Sprintf("expiration_time %q != %q", currentExpiration, wanted.ExpirationTime))
+	}
+
+	return reasons
+}
+
+// Check if plan has actions to apply
+func (plan *Plan) Empty() bool {
+	for _, action := range plan.Actions {
+		if action.Type != ActionDrift {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Human readable plan, one action per line
+func (plan *Plan) String() string {
+	if len(plan.Actions) == 0 {
+		return "No changes.\n"
+	}
+
+	var builder strings.Builder
+	counts := make(map[ActionType]int)
+
+	for _, action := range plan.Actions {
+		counts[action.Type]++
+
+		line := fmt.Sprintf("%3s %s %s", actionSymbols[action.Type], action.Type, action.Key)
+		if action.Hash != "" {
+			line += " [" + action.Hash + "]"
+		}
+		if action.Link != nil && (action.Type == ActionCreate || action.Type == ActionRecreate) {
+			line += " " + action.Link.Url
+		}
+		if action.Reason != "" {
+			line += " (" + action.Reason + ")"
+		}
+
+		builder.WriteString(line + "\n")
+	}
+
+	builder.WriteString(fmt.Sprintf("\nPlan: %d to create, %d to recreate, %d to activate, %d to deactivate, %d drifted.\n",
+		counts[ActionCreate], counts[ActionRecreate], counts[ActionActivate], counts[ActionDeactivate], counts[ActionDrift]))
+
+	return builder.String()
+}
@@ -0,0 +1,228 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// In-memory TinySRC account
+type fakeAccount struct {
+	links     map[string]*models.LinkUserResponse
+	calls     []string
+	created   int
+	failPatch bool
+}
+
+func (account *fakeAccount) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/create":
+		request := models.LinkRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		account.created++
+		hash := "new" + strconv.Itoa(account.created)
+		account.links[hash] = &models.LinkUserResponse{Hash: hash, Url: request.Url, AuthRequired: request.AuthRequired, Active: 1}
+		account.calls = append(account.calls, "create "+request.Url)
+
+		_ = json.NewEncoder(w).Encode(&models.LinkResponse{Url: tinysrc.ShortUrl(hash)})
+	case r.URL.Path == "/v1/client/url":
+		response := models.PaginatedLinkUserResponse{}
+		for _, link := range account.links {
+			response.Data = append(response.Data, link)
+		}
+		response.Total = int64(len(response.Data))
+		_ = json.NewEncoder(w).Encode(&response)
+	case strings.HasPrefix(r.URL.Path, "/v1/client/url/"):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+	case r.Method == http.MethodPatch && account.failPatch:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errors":["Internal Server Error"]}`))
+	case r.Method == http.MethodPatch:
+		request := models.LinkActivationRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		hash := strings.TrimPrefix(r.URL.Path, "/v1/client/")
+		account.links[hash].Active = 0
+		action := "deactivate "
+		if request.Active {
+			account.links[hash].Active = 1
+			action = "activate "
+		}
+		account.calls = append(account.calls, action+hash)
+
+		_, _ = w.Write([]byte("{}"))
+	}
+}
+
+func TestEngine_PlanApply(t *testing.T) {
+	expiration := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	account := &fakeAccount{links: map[string]*models.LinkUserResponse{
+		"docs":   {Hash: "docs", Url: "https://test.com/docs/", Active: 0},
+		"old":    {Hash: "old", Url: "https://test.com/old", Active: 1},
+		"moved":  {Hash: "moved", Url: "https://test.com/moved", Active: 1},
+		"expire": {Hash: "expire", Url: "https://test.com/expire", Active: 1, ExpirationTime: &expiration},
+	}}
+
+	ts := httptest.NewServer(account)
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+	client.SetServerLocation(time.UTC)
+
+	inactive := false
+	manifest := &Manifest{Links: map[string]*Link{
+		"docs":   {Url: "https://test.com/docs"},
+		"beta":   {Url: "https://test.com/beta", AuthRequired: true, Password: "secret", Active: &inactive},
+		"moved":  {Url: "https://test.com/new-place"},
+		"expire": {Url: "https://test.com/expire", ExpirationTime: "2030-01-01 10:00"},
+		"gone":   {Url: "https://test.com/gone"},
+	}}
+
+	state, _ := OpenState(filepath.Join(t.TempDir(), "state.json"))
+	state.Hashes = map[string]string{
+		"docs":    "docs",
+		"moved":   "moved",
+		"expire":  "expire",
+		"gone":    "deleted",
+		"removed": "old",
+	}
+
+	tests := []struct {
+		name        string
+		engine      *Engine
+		wantActions []string
+		wantCalls   []string
+	}{
+		{
+			name:   "test_drift_without_recreate",
+			engine: &Engine{Client: client},
+			wantActions: []string{
+				"create beta",
+				"activate docs",
+				"create gone",
+				"drift moved",
+			},
+			wantCalls: []string{
+				"create https://test.com/beta",
+				"deactivate new1",
+				"activate docs",
+				"create https://test.com/gone",
+			},
+		},
+		{
+			name:   "test_recreate_and_prune",
+			engine: &Engine{Client: client, Recreate: true, Prune: true},
+			wantActions: []string{
+				"recreate moved",
+				"deactivate removed",
+			},
+			wantCalls: []string{
+				"create https://test.com/new-place",
+				"deactivate moved",
+				"deactivate old",
+			},
+		},
+		{
+			name:   "test_no_changes",
+			engine: &Engine{Client: client, Recreate: true, Prune: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account.calls = nil
+
+			plan, errorResponse := tt.engine.Plan(manifest, state)
+			if len(errorResponse.Errors) > 0 {
+				t.Fatalf("Plan() errorResponse = %v", errorResponse)
+			}
+
+			var gotActions []string
+			for _, action := range plan.Actions {
+				gotActions = append(gotActions, string(action.Type)+" "+action.Key)
+			}
+			if !reflect.DeepEqual(gotActions, tt.wantActions) {
+				t.Errorf("Plan() actions = %v, want %v\n%s", gotActions, tt.wantActions, plan)
+			}
+
+			_, errorResponse = tt.engine.Apply(plan, state)
+			if len(errorResponse.Errors) > 0 {
+				t.Fatalf("Apply() errorResponse = %v", errorResponse)
+			}
+			if !reflect.DeepEqual(account.calls, tt.wantCalls) {
+				t.Errorf("Apply() calls = %v, want %v", account.calls, tt.wantCalls)
+			}
+		})
+	}
+
+	reopened, _ := OpenState(state.path)
+	want := map[string]string{"docs": "docs", "beta": "new1", "moved": "new3", "expire": "expire", "gone": "new2"}
+	if !reflect.DeepEqual(reopened.Hashes, want) {
+		t.Errorf("Apply() state = %v, want %v", reopened.Hashes, want)
+	}
+}
+
+func TestEngine_ApplyFailedActivation(t *testing.T) {
+	account := &fakeAccount{links: map[string]*models.LinkUserResponse{}, failPatch: true}
+
+	ts := httptest.NewServer(account)
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	inactive := false
+	manifest := &Manifest{Links: map[string]*Link{"beta": {Url: "https://test.com/beta", Active: &inactive}}}
+	engine := &Engine{Client: client}
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, _ := OpenState(path)
+	plan, _ := engine.Plan(manifest, state)
+	if _, errorResponse := engine.Apply(plan, state); errorResponse.Status != http.StatusInternalServerError {
+		t.Fatalf("Apply() errorResponse = %v, want status 500", errorResponse)
+	}
+
+	// Link created before failed PATCH is kept in state and is not created again
+	account.failPatch = false
+	account.calls = nil
+
+	state, _ = OpenState(path)
+	plan, _ = engine.Plan(manifest, state)
+	if _, errorResponse := engine.Apply(plan, state); len(errorResponse.Errors) > 0 {
+		t.Fatalf("Apply() errorResponse = %v", errorResponse)
+	}
+	if !reflect.DeepEqual(account.calls, []string{"deactivate new1"}) || account.created != 1 {
+		t.Errorf("Apply() calls = %v, created = %d", account.calls, account.created)
+	}
+}
+
+func TestPlan_String(t *testing.T) {
+	plan := &Plan{Actions: []*Action{
+		{Type: ActionCreate, Key: "docs", Link: &Link{Url: "https://test.com/docs"}},
+		{Type: ActionDeactivate, Key: "old", Hash: "abc", Reason: "not in manifest"},
+	}}
+
+	want := "  + create docs https://test.com/docs\n" +
+		"  - deactivate old [abc] (not in manifest)\n" +
+		"\nPlan: 1 to create, 0 to recreate, 0 to activate, 1 to deactivate, 0 drifted.\n"
+
+	if got := plan.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	if got := (&Plan{}).String(); got != "No changes.\n" {
+		t.Errorf("String() = %q", got)
+	}
+}
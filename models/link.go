@@ -5,8 +5,10 @@ import "time"
 type LinkRequest struct {
 	Url            string `json:"url"`
 	AuthRequired   int    `json:"auth_required"`
-	Password       string `json:"password,omitempty"`
+	Password       Secret `json:"password,omitempty"`
 	ExpirationTime string `json:"expiration_time,omitempty"`
+	// Overrides ExpirationTime, converted into server time zone by client
+	ExpiresAt *time.Time `json:"-"`
 }
 
 type ListUrlsRequest struct {
@@ -22,8 +24,8 @@ type LinkActivationRequest struct {
 type LinkResponse struct {
 	Url          string `json:"url"`
 	StatUrl      string `json:"stat_url,omitempty"`
-	StatPassword string `json:"stat_password,omitempty"`
-	Password     string `json:"password,omitempty"`
+	StatPassword Secret `json:"stat_password,omitempty"`
+	Password     Secret `json:"password,omitempty"`
 	AuthRequired int    `json:"auth_required"`
 }
 
@@ -31,8 +33,8 @@ type LinkUserResponse struct {
 	Url            string     `json:"url"`
 	Hash           string     `json:"hash"`
 	AuthRequired   int        `json:"auth_required"`
-	Password       string     `json:"password,omitempty"`
-	StatPassword   string     `json:"stat_password"`
+	Password       Secret     `json:"password,omitempty"`
+	StatPassword   Secret     `json:"stat_password"`
 	QRCode         string     `json:"qr_code"`
 	Active         int        `json:"active"`
 	Clicks         int64      `json:"clicks"`
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+// Value printed instead of secrets
+const REDACTED = "[REDACTED]"
+
+// Password which is redacted in fmt and slog output but marshaled to JSON as plain string.
+// Use Reveal to get the value.
+type Secret string
+
+// Plain value of secret
+func (secret Secret) Reveal() string {
+	return string(secret)
+}
+
+func (secret Secret) redacted() string {
+	if secret == "" {
+		return ""
+	}
+
+	return REDACTED
+}
+
+func (secret Secret) String() string {
+	return secret.redacted()
+}
+
+func (secret Secret) GoString() string {
+	return strconv.Quote(secret.redacted())
+}
+
+// Every verb prints redacted value, %q quotes it
+func (secret Secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'q' || (verb == 'v' && f.Flag('#')):
+		_, _ = fmt.Fprint(f, strconv.Quote(secret.redacted()))
+	default:
+		_, _ = fmt.Fprint(f, secret.redacted())
+	}
+}
+
+func (secret Secret) LogValue() slog.Value {
+	return slog.StringValue(secret.redacted())
+}
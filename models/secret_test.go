@@ -0,0 +1,67 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	link := LinkRequest{Url: "https://test.com", AuthRequired: 1, Password: "link-secret"}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "test_print", got: fmt.Sprint(link.Password), want: REDACTED},
+		{name: "test_v", got: fmt.Sprintf("%v", link), want: "{https://test.com 1 " + REDACTED + "  <nil>}"},
+		{name: "test_plus_v", got: fmt.Sprintf("%+v", link), want: "{Url:https://test.com AuthRequired:1 Password:" + REDACTED + " ExpirationTime: ExpiresAt:<nil>}"},
+		{name: "test_go_syntax", got: fmt.Sprintf("%#v", link.Password), want: `"` + REDACTED + `"`},
+		{name: "test_s", got: fmt.Sprintf("%s|%q|%x", link.Password, link.Password, link.Password), want: REDACTED + `|"` + REDACTED + `"|` + REDACTED},
+		{name: "test_empty", got: fmt.Sprintf("%v|%q", Secret(""), Secret("")), want: `|""`},
+		{name: "test_reveal", got: link.Password.Reveal(), want: "link-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Secret = %s, want %s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecret_LogValue(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buffer, nil))
+
+	logger.Info("created", "password", Secret("link-secret"), "link", LinkResponse{Password: "link-secret"})
+
+	if strings.Contains(buffer.String(), "link-secret") || !strings.Contains(buffer.String(), "password="+REDACTED) {
+		t.Errorf("LogValue() = %s", buffer.String())
+	}
+}
+
+func TestSecret_MarshalJSON(t *testing.T) {
+	data, e := json.Marshal(LinkRequest{Url: "https://test.com", Password: "link-secret"})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if want := `{"url":"https://test.com","auth_required":0,"password":"link-secret"}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var link LinkUserResponse
+	if e = json.Unmarshal([]byte(`{"password":"a","stat_password":"b"}`), &link); e != nil {
+		t.Fatal(e)
+	}
+
+	if link.Password.Reveal() != "a" || link.StatPassword.Reveal() != "b" {
+		t.Errorf("Unmarshal() = %q %q", link.Password.Reveal(), link.StatPassword.Reveal())
+	}
+}
@@ -0,0 +1,245 @@
+// Package password generates crypto-random passwords and passphrases for
+// protected links.
+//
+//	secret, e := password.Generate(password.Policy{Length: 20, Symbols: true})
+//	request := models.LinkRequest{Url: "https://test.com", AuthRequired: 1, Password: secret}
+package password
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const DEFAULT_LENGTH = 16
+const DEFAULT_WORDS = 5
+const DEFAULT_SEPARATOR = "-"
+
+const LOWER = "abcdefghijklmnopqrstuvwxyz"
+const UPPER = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const DIGITS = "0123456789"
+const SYMBOLS = "!#$%&*+-=?@^_~"
+
+// Characters easily confused when password is typed from print
+const AMBIGUOUS = "0O1lI|"
+
+// Source of randomness, replaced in tests
+var randomReader io.Reader = rand.Reader
+
+// Character password policy. Without any class enabled lower, upper and digits are used.
+// Every enabled class appears in password at least once.
+type Policy struct {
+	Length  int
+	Lower   bool
+	Upper   bool
+	Digits  bool
+	Symbols bool
+	// Overrides SYMBOLS
+	SymbolSet        string
+	ExcludeAmbiguous bool
+}
+
+// Passphrase policy, words are picked from Wordlist or built-in list
+type PassphrasePolicy struct {
+	Words     int
+	Separator string
+	// Capitalize first letter of every word
+	Capitalize bool
+	// Append random digit to one of words
+	Digit    bool
+	Wordlist []string
+}
+
+func (policy Policy) classes() []string {
+	lower, upper, digits, symbols := policy.Lower, policy.Upper, policy.Digits, policy.Symbols
+	if !lower && !upper && !digits && !symbols {
+		lower, upper, digits = true, true, true
+	}
+
+	symbolSet := SYMBOLS
+	if policy.SymbolSet != "" {
+		symbolSet = policy.SymbolSet
+	}
+
+	var classes []string
+	for _, class := range []struct {
+		enabled bool
+		chars   string
+	}{{lower, LOWER}, {upper, UPPER}, {digits, DIGITS}, {symbols, symbolSet}} {
+		if !class.enabled {
+			continue
+		}
+
+		chars := class.chars
+		if policy.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(AMBIGUOUS, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+
+		if chars != "" {
+			classes = append(classes, chars)
+		}
+	}
+
+	return classes
+}
+
+func (policy Policy) length() int {
+	if policy.Length <= 0 {
+		return DEFAULT_LENGTH
+	}
+
+	return policy.Length
+}
+
+// Upper bound of password entropy in bits
+func (policy Policy) Entropy() float64 {
+	return float64(policy.length()) * math.Log2(float64(utf8.RuneCountInString(strings.Join(policy.classes(), ""))))
+}
+
+// Generate password matching policy
+func Generate(policy Policy) (models.Secret, error) {
+	classes := policy.classes()
+	length := policy.length()
+
+	if len(classes) == 0 {
+		return "", errors.New("password: policy has no characters")
+	}
+	if length < len(classes) {
+		return "", fmt.Errorf("password: length %d is shorter than %d required classes", length, len(classes))
+	}
+
+	all := strings.Join(classes, "")
+	result := make([]rune, 0, length)
+
+	for _, class := range classes {
+		c, e := pick(class)
+		if e != nil {
+			return "", e
+		}
+		result = append(result, c)
+	}
+
+	for len(result) < length {
+		c, e := pick(all)
+		if e != nil {
+			return "", e
+		}
+		result = append(result, c)
+	}
+
+	// Fisher-Yates, required characters must not stay in front
+	for i := len(result) - 1; i > 0; i-- {
+		j, e := randomInt(i + 1)
+		if e != nil {
+			return "", e
+		}
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return models.Secret(result), nil
+}
+
+// Upper bound of passphrase entropy in bits
+func (policy PassphrasePolicy) Entropy() float64 {
+	words := policy.words()
+
+	entropy := float64(policy.count()) * math.Log2(float64(len(words)))
+	if policy.Digit {
+		entropy += math.Log2(10 * float64(policy.count()))
+	}
+
+	return entropy
+}
+
+func (policy PassphrasePolicy) words() []string {
+	if len(policy.Wordlist) > 0 {
+		return policy.Wordlist
+	}
+
+	return wordlist
+}
+
+func (policy PassphrasePolicy) count() int {
+	if policy.Words <= 0 {
+		return DEFAULT_WORDS
+	}
+
+	return policy.Words
+}
+
+// Generate passphrase matching policy
+func Passphrase(policy PassphrasePolicy) (models.Secret, error) {
+	words := policy.words()
+	count := policy.count()
+
+	separator := policy.Separator
+	if separator == "" {
+		separator = DEFAULT_SEPARATOR
+	}
+
+	result := make([]string, count)
+	for i := range result {
+		n, e := randomInt(len(words))
+		if e != nil {
+			return "", e
+		}
+
+		word := words[n]
+		if policy.Capitalize && word != "" {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+
+		result[i] = word
+	}
+
+	if policy.Digit {
+		n, e := randomInt(count)
+		if e != nil {
+			return "", e
+		}
+		digit, e := pick(DIGITS)
+		if e != nil {
+			return "", e
+		}
+
+		result[n] += string(digit)
+	}
+
+	return models.Secret(strings.Join(result, separator)), nil
+}
+
+// Random character, chars may contain any UTF-8 characters
+func pick(chars string) (rune, error) {
+	runes := []rune(chars)
+
+	n, e := randomInt(len(runes))
+	if e != nil {
+		return 0, e
+	}
+
+	return runes[n], nil
+}
+
+// Uniform random number in [0, n)
+func randomInt(n int) (int, error) {
+	value, e := rand.Int(randomReader, big.NewInt(int64(n)))
+	if e != nil {
+		return 0, fmt.Errorf("password: %w", e)
+	}
+
+	return int(value.Int64()), nil
+}
@@ -0,0 +1,172 @@
+package password
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     Policy
+		wantLength int
+		wantChars  string
+		wantErr    bool
+	}{
+		{
+			name:       "test_default",
+			policy:     Policy{},
+			wantLength: DEFAULT_LENGTH,
+			wantChars:  LOWER + UPPER + DIGITS,
+		},
+		{
+			name:       "test_symbols",
+			policy:     Policy{Length: 24, Lower: true, Symbols: true},
+			wantLength: 24,
+			wantChars:  LOWER + SYMBOLS,
+		},
+		{
+			name:       "test_custom_symbols_without_ambiguous",
+			policy:     Policy{Length: 40, Digits: true, Upper: true, Symbols: true, SymbolSet: "|.", ExcludeAmbiguous: true},
+			wantLength: 40,
+			wantChars:  "23456789ABCDEFGHJKLMNPQRSTUVWXYZ.",
+		},
+		{
+			name:       "test_unicode_symbols",
+			policy:     Policy{Length: 8, Digits: true, Symbols: true, SymbolSet: "€£"},
+			wantLength: 8,
+			wantChars:  DIGITS + "€£",
+		},
+		{
+			name:    "test_too_short",
+			policy:  Policy{Length: 2, Lower: true, Upper: true, Digits: true},
+			wantErr: true,
+		},
+		{
+			name:    "test_no_characters",
+			policy:  Policy{Symbols: true, SymbolSet: "|", ExcludeAmbiguous: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				got, e := Generate(tt.policy)
+				if (e != nil) != tt.wantErr {
+					t.Fatalf("Generate() error = %v, wantErr %v", e, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				value := got.Reveal()
+				if !utf8.ValidString(value) || utf8.RuneCountInString(value) != tt.wantLength {
+					t.Fatalf("Generate() = %q, want length %d", value, tt.wantLength)
+				}
+				if strings.Trim(value, tt.wantChars) != "" {
+					t.Fatalf("Generate() = %q, want only %q", value, tt.wantChars)
+				}
+
+				for _, class := range tt.policy.classes() {
+					if !strings.ContainsAny(value, class) {
+						t.Fatalf("Generate() = %q, want one of %q", value, class)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate_Random(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		got, _ := Generate(Policy{Length: 12})
+		if seen[got.Reveal()] {
+			t.Fatalf("Generate() repeated %q", got.Reveal())
+		}
+		seen[got.Reveal()] = true
+	}
+
+	reader := randomReader
+	randomReader = bytes.NewReader(nil)
+	defer func() { randomReader = reader }()
+
+	if _, e := Generate(Policy{}); e == nil {
+		t.Errorf("Generate() error = nil, want error of exhausted reader")
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    PassphrasePolicy
+		wantWords int
+		check     func(word string) bool
+	}{
+		{
+			name:      "test_default",
+			wantWords: DEFAULT_WORDS,
+			check: func(word string) bool {
+				return word == strings.ToLower(word) && strings.Trim(word, LOWER) == ""
+			},
+		},
+		{
+			name:      "test_capitalized_with_separator",
+			policy:    PassphrasePolicy{Words: 3, Separator: " ", Capitalize: true},
+			wantWords: 3,
+			check: func(word string) bool {
+				return strings.ContainsAny(word[:1], UPPER)
+			},
+		},
+		{
+			name:      "test_wordlist",
+			policy:    PassphrasePolicy{Words: 4, Wordlist: []string{"alpha", "beta"}},
+			wantWords: 4,
+			check: func(word string) bool {
+				return word == "alpha" || word == "beta"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, e := Passphrase(tt.policy)
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			separator := tt.policy.Separator
+			if separator == "" {
+				separator = DEFAULT_SEPARATOR
+			}
+
+			words := strings.Split(got.Reveal(), separator)
+			if len(words) != tt.wantWords {
+				t.Fatalf("Passphrase() = %q, want %d words", got.Reveal(), tt.wantWords)
+			}
+			for _, word := range words {
+				if !tt.check(word) {
+					t.Errorf("Passphrase() word = %q", word)
+				}
+			}
+		})
+	}
+
+	got, _ := Passphrase(PassphrasePolicy{Digit: true})
+	if strings.Count(got.Reveal(), "-") != DEFAULT_WORDS-1 || !strings.ContainsAny(got.Reveal(), DIGITS) {
+		t.Errorf("Passphrase() = %q, want digit", got.Reveal())
+	}
+}
+
+func TestPolicy_Entropy(t *testing.T) {
+	if got := (Policy{Length: 10, Digits: true}).Entropy(); got < 33.2 || got > 33.3 {
+		t.Errorf("Entropy() = %v", got)
+	}
+
+	if got := (PassphrasePolicy{Words: 2, Wordlist: []string{"a", "b", "c", "d"}}).Entropy(); got != 4 {
+		t.Errorf("Entropy() = %v", got)
+	}
+}
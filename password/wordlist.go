@@ -0,0 +1,31 @@
+package password
+
+// Short common words, easy to type from print
+var wordlist = []string{
+	"able", "acid", "aged", "also", "area", "army", "away", "baby", "back", "ball", "band", "bank",
+	"base", "bath", "bear", "beat", "beef", "bell", "belt", "best", "bike", "bird", "blue", "boat",
+	"body", "bold", "bone", "book", "boot", "born", "boss", "both", "bowl", "bulk", "burn", "bush",
+	"busy", "cafe", "cake", "calm", "camp", "card", "care", "cart", "case", "cash", "cast", "cave",
+	"chef", "chin", "city", "clay", "club", "coal", "coat", "code", "coin", "cold", "cook", "cool",
+	"copy", "corn", "cost", "crew", "crop", "cube", "cure", "dark", "data", "dawn", "deal", "deck",
+	"deep", "deer", "desk", "dial", "dice", "diet", "dish", "dock", "door", "dose", "down", "draw",
+	"drum", "duck", "dune", "dust", "duty", "earn", "east", "easy", "echo", "edge", "epic", "even",
+	"exit", "face", "fact", "fair", "farm", "fast", "fern", "file", "film", "fine", "fire", "fish",
+	"flag", "flat", "fog", "fold", "folk", "food", "foot", "fork", "form", "fort", "frog", "fuel",
+	"full", "game", "gate", "gift", "glad", "glow", "goat", "gold", "golf", "good", "gray", "grid",
+	"grip", "hair", "half", "hall", "hand", "harp", "hawk", "heat", "herb", "hero", "hill", "hint",
+	"hive", "hold", "home", "hood", "hook", "hope", "horn", "host", "huge", "iron", "item", "jazz",
+	"jump", "jury", "keen", "kelp", "kind", "king", "kite", "knee", "knot", "lake", "lamb", "lamp",
+	"land", "lane", "lava", "lawn", "leaf", "lens", "lift", "lime", "line", "lion", "list", "loaf",
+	"lock", "loft", "long", "loop", "lord", "luck", "lung", "mail", "main", "mango", "map", "mask",
+	"meal", "melt", "mild", "milk", "mill", "mint", "mist", "moon", "moss", "moth", "mule", "nail",
+	"navy", "neat", "nest", "news", "nice", "noon", "nose", "note", "oak", "oath", "open", "oval",
+	"oven", "pace", "page", "palm", "park", "path", "peak", "pear", "pier", "pine", "pink", "plan",
+	"plum", "poem", "pond", "pony", "pool", "port", "quiz", "race", "raft", "rain", "ramp", "reed",
+	"rice", "ring", "road", "rock", "roof", "room", "rope", "rose", "ruby", "rule", "safe", "sage",
+	"sail", "salt", "sand", "seal", "seed", "ship", "shoe", "silk", "sing", "site", "slow", "snow",
+	"soap", "sock", "sofa", "soil", "song", "soup", "star", "step", "stone", "sun", "swan", "tail",
+	"tank", "taxi", "team", "tent", "tide", "tile", "time", "tool", "town", "tree", "tube", "tuna",
+	"vase", "vest", "view", "wall", "wave", "west", "whale", "wind", "wing", "wolf", "wood", "yard",
+	"yarn", "year", "yoga", "zebra", "zero", "zone",
+}
@@ -0,0 +1,70 @@
+// Package qrcode decodes QR codes returned by API and renders QR codes of
+// short links locally as PNG or SVG.
+//
+//	code, e := qrcode.Encode(link.Url, qrcode.Medium)
+//	e = code.PNG(file, qrcode.Options{Size: 600})
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+var ErrEmptyPayload = errors.New("qrcode: payload is empty")
+
+// Decode QR code payload of API (data URI or plain base64) to image
+func Decode(payload string) (image.Image, error) {
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		return nil, ErrEmptyPayload
+	}
+
+	if strings.HasPrefix(payload, "http://") || strings.HasPrefix(payload, "https://") {
+		return nil, errors.New("qrcode: payload is url, download it and decode with image.Decode")
+	}
+
+	if rest, ok := strings.CutPrefix(payload, "data:"); ok {
+		meta, data, found := strings.Cut(rest, ",")
+		if !found {
+			return nil, errors.New("qrcode: malformed data uri")
+		}
+		if !strings.HasSuffix(meta, ";base64") {
+			return nil, errors.New("qrcode: only base64 data uri is supported")
+		}
+		if strings.HasPrefix(meta, "image/svg") {
+			return nil, errors.New("qrcode: svg payload can not be decoded to image")
+		}
+		payload = data
+	}
+
+	data, e := decodeBase64(payload)
+	if e != nil {
+		return nil, e
+	}
+
+	img, _, e := image.Decode(bytes.NewReader(data))
+	return img, e
+}
+
+// Standard and url-safe alphabets, with or without padding
+func decodeBase64(payload string) ([]byte, error) {
+	payload = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, payload)
+
+	encoding := base64.StdEncoding
+	if strings.ContainsAny(payload, "-_") {
+		encoding = base64.URLEncoding
+	}
+
+	return encoding.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(payload, "="))
+}
@@ -0,0 +1,535 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error correction level
+type Level int
+
+const (
+	// Recovers 7% of data
+	Low Level = iota
+	// Recovers 15% of data
+	Medium
+	// Recovers 25% of data
+	Quartile
+	// Recovers 30% of data
+	High
+)
+
+const MIN_VERSION = 1
+const MAX_VERSION = 40
+
+var ErrTooLong = errors.New("qrcode: content is too long")
+
+// Format bits of levels
+var levelBits = [4]int{1, 0, 3, 2}
+
+// Error correction codewords per block, indexed by level and version
+var eccCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// Error correction blocks, indexed by level and version
+var eccBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Encoded QR code, content is encoded in byte mode
+type Code struct {
+	Version int
+	Level   Level
+	Mask    int
+
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Encode content with the smallest version fitting it, mask is chosen by penalty score
+func Encode(content string, level Level) (*Code, error) {
+	return encode([]byte(content), level, -1)
+}
+
+func encode(data []byte, level Level, mask int) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("qrcode: unknown level %d", level)
+	}
+
+	version := MIN_VERSION
+	for ; ; version++ {
+		if version > MAX_VERSION {
+			return nil, ErrTooLong
+		}
+
+		if 4+countBits(version)+len(data)*8 <= dataCodewords(version, level)*8 {
+			break
+		}
+	}
+
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := dataCodewords(version, level) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i>>3] |= 1 << (7 - uint(i&7))
+		}
+	}
+
+	code := &Code{Version: version, Level: level, size: version*4 + 17}
+	code.modules = newGrid(code.size)
+	code.isFunction = newGrid(code.size)
+
+	code.drawFunctionPatterns()
+	code.drawCodewords(code.addEccAndInterleave(codewords))
+
+	if mask < 0 {
+		minPenalty := -1
+		for candidate := 0; candidate < 8; candidate++ {
+			code.applyMask(candidate)
+			code.drawFormatBits(candidate)
+
+			if penalty := code.penalty(); minPenalty < 0 || penalty < minPenalty {
+				mask = candidate
+				minPenalty = penalty
+			}
+
+			code.applyMask(candidate)
+		}
+	}
+
+	code.Mask = mask
+	code.applyMask(mask)
+	code.drawFormatBits(mask)
+	code.isFunction = nil
+
+	return code, nil
+}
+
+// Count of modules on every side, without margin
+func (code *Code) Size() int {
+	return code.size
+}
+
+// Check if module at column x and row y is dark
+func (code *Code) Black(x int, y int) bool {
+	return x >= 0 && y >= 0 && x < code.size && y < code.size && code.modules[y][x]
+}
+
+type bitBuffer []bool
+
+func (buffer *bitBuffer) append(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*buffer = append(*buffer, (value>>uint(i))&1 != 0)
+	}
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+
+	return grid
+}
+
+// Length of character count field in byte mode
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+
+	return 16
+}
+
+// Modules available for data and error correction
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+
+	if version >= 2 {
+		align := version/7 + 2
+		result -= (25*align-10)*align - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+
+	return result
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// Centers of alignment patterns
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	align := version/7 + 2
+	step := (version*8 + align*3 + 5) / (align*4 - 4) * 2
+
+	result := make([]int, align)
+	result[0] = 6
+	for i, position := align-1, version*4+10; i >= 1; i, position = i-1, position-step {
+		result[i] = position
+	}
+
+	return result
+}
+
+func (code *Code) setFunction(x int, y int, dark bool) {
+	code.modules[y][x] = dark
+	code.isFunction[y][x] = true
+}
+
+func (code *Code) drawFunctionPatterns() {
+	for i := 0; i < code.size; i++ {
+		code.setFunction(6, i, i%2 == 0)
+		code.setFunction(i, 6, i%2 == 0)
+	}
+
+	code.drawFinder(3, 3)
+	code.drawFinder(code.size-4, 3)
+	code.drawFinder(3, code.size-4)
+
+	positions := alignmentPositions(code.Version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			code.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve format area, real bits are drawn after masking
+	code.drawFormatBits(0)
+	code.drawVersion()
+}
+
+func (code *Code) drawFinder(x int, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			distance := chebyshev(dx, dy)
+			xx, yy := x+dx, y+dy
+			if xx >= 0 && xx < code.size && yy >= 0 && yy < code.size {
+				code.setFunction(xx, yy, distance != 2 && distance != 4)
+			}
+		}
+	}
+}
+
+func (code *Code) drawAlignment(x int, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			code.setFunction(x+dx, y+dy, chebyshev(dx, dy) != 1)
+		}
+	}
+}
+
+func (code *Code) drawFormatBits(mask int) {
+	data := levelBits[code.Level]<<3 | mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		code.setFunction(8, i, bit(i))
+	}
+	code.setFunction(8, 7, bit(6))
+	code.setFunction(8, 8, bit(7))
+	code.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		code.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		code.setFunction(code.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		code.setFunction(8, code.size-15+i, bit(i))
+	}
+	code.setFunction(8, code.size-8, true)
+}
+
+func (code *Code) drawVersion() {
+	if code.Version < 7 {
+		return
+	}
+
+	remainder := code.Version
+	for i := 0; i < 12; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+	}
+	bits := code.Version<<12 | remainder
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 != 0
+		a, b := code.size-11+i%3, i/3
+		code.setFunction(a, b, dark)
+		code.setFunction(b, a, dark)
+	}
+}
+
+// Split data into blocks, add Reed-Solomon codewords and interleave
+func (code *Code) addEccAndInterleave(data []byte) []byte {
+	blocks := eccBlocks[code.Level][code.Version]
+	eccLength := eccCodewordsPerBlock[code.Level][code.Version]
+	raw := rawDataModules(code.Version) / 8
+	shortBlocks := blocks - raw%blocks
+	shortLength := raw / blocks
+
+	divisor := reedSolomonDivisor(eccLength)
+
+	all := make([][]byte, blocks)
+	for i, k := 0, 0; i < blocks; i++ {
+		length := shortLength - eccLength
+		if i >= shortBlocks {
+			length++
+		}
+
+		block := append([]byte(nil), data[k:k+length]...)
+		k += length
+
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < shortBlocks {
+			block = append(block, 0)
+		}
+
+		all[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := range all[0] {
+		for j, block := range all {
+			if i != shortLength-eccLength || j >= shortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	return result
+}
+
+// Place codewords in zigzag order
+func (code *Code) drawCodewords(data []byte) {
+	i := 0
+
+	for right := code.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		for vertical := 0; vertical < code.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = code.size - 1 - vertical
+				}
+
+				if !code.isFunction[y][x] && i < len(data)*8 {
+					code.modules[y][x] = (data[i>>3]>>(7-uint(i&7)))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// XOR mask with data modules, applying mask twice restores modules
+func (code *Code) applyMask(mask int) {
+	for y := 0; y < code.size; y++ {
+		for x := 0; x < code.size; x++ {
+			var invert bool
+
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+
+			if invert && !code.isFunction[y][x] {
+				code.modules[y][x] = !code.modules[y][x]
+			}
+		}
+	}
+}
+
+// Penalty score of ISO/IEC 18004 used to choose mask
+func (code *Code) penalty() int {
+	result := 0
+	size := code.size
+
+	at := func(x int, y int, horizontal bool) bool {
+		if horizontal {
+			return code.modules[y][x]
+		}
+		return code.modules[x][y]
+	}
+
+	finderLike := [2][11]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	for _, horizontal := range []bool{true, false} {
+		for line := 0; line < size; line++ {
+			run := 1
+			for i := 1; i <= size; i++ {
+				if i < size && at(i, line, horizontal) == at(i-1, line, horizontal) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += 3 + run - 5
+				}
+				run = 1
+			}
+
+			for i := 0; i+11 <= size; i++ {
+				for _, pattern := range finderLike {
+					matched := true
+					for k, dark := range pattern {
+						if at(i+k, line, horizontal) != dark {
+							matched = false
+							break
+						}
+					}
+					if matched {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if code.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				color := code.modules[y][x]
+				if color == code.modules[y][x+1] && color == code.modules[y+1][x] && color == code.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+
+	total := size * size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		result += k * 10
+	}
+
+	return result
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+
+	return result
+}
+
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+
+	return result
+}
+
+// Multiplication in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x byte, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+
+	return byte(z)
+}
+
+func chebyshev(dx int, dy int) int {
+	if abs(dx) > abs(dy) {
+		return abs(dx)
+	}
+
+	return abs(dy)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
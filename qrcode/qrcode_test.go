@@ -0,0 +1,304 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// Read level and mask back from format bits around top-left finder
+func readFormat(code *Code) (Level, int) {
+	bits := 0
+	for i := 0; i <= 5; i++ {
+		if code.Black(8, i) {
+			bits |= 1 << uint(i)
+		}
+	}
+	if code.Black(8, 7) {
+		bits |= 1 << 6
+	}
+	if code.Black(8, 8) {
+		bits |= 1 << 7
+	}
+	if code.Black(7, 8) {
+		bits |= 1 << 8
+	}
+	for i := 9; i < 15; i++ {
+		if code.Black(14-i, 8) {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	data := (bits ^ 0x5412) >> 10
+	for level, value := range levelBits {
+		if value == data>>3 {
+			return Level(level), data & 7
+		}
+	}
+
+	return -1, -1
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		level       Level
+		wantVersion int
+		wantErr     bool
+	}{
+		{
+			name:        "test_short_url",
+			content:     "https://tinysrc.me/abc",
+			level:       Medium,
+			wantVersion: 2,
+		},
+		{
+			name:        "test_high_level_grows_version",
+			content:     "https://tinysrc.me/abc",
+			level:       High,
+			wantVersion: 3,
+		},
+		{
+			name:        "test_version_with_version_bits",
+			content:     strings.Repeat("a", 200),
+			level:       Low,
+			wantVersion: 9,
+		},
+		{
+			name:        "test_long_count_field",
+			content:     strings.Repeat("a", 1000),
+			level:       Quartile,
+			wantVersion: 31,
+		},
+		{
+			name:    "test_too_long",
+			content: strings.Repeat("a", 3000),
+			level:   Low,
+			wantErr: true,
+		},
+		{
+			name:    "test_unknown_level",
+			content: "a",
+			level:   Level(4),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, e := Encode(tt.content, tt.level)
+			if (e != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", e, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if code.Version != tt.wantVersion {
+				t.Errorf("Encode() version = %d, want %d", code.Version, tt.wantVersion)
+			}
+			if code.Size() != tt.wantVersion*4+17 {
+				t.Errorf("Encode() size = %d", code.Size())
+			}
+
+			level, mask := readFormat(code)
+			if level != tt.level || mask != code.Mask {
+				t.Errorf("Encode() format = %d/%d, want %d/%d", level, mask, tt.level, code.Mask)
+			}
+
+			// Finder pattern corners and dark module
+			size := code.Size()
+			for _, point := range [][2]int{{0, 0}, {6, 6}, {size - 1, 0}, {0, size - 1}, {3, 3}, {8, size - 8}} {
+				if !code.Black(point[0], point[1]) {
+					t.Errorf("Encode() module %v is light", point)
+				}
+			}
+			for _, point := range [][2]int{{1, 1}, {7, 7}, {size - 8, 0}, {7, size - 1}} {
+				if code.Black(point[0], point[1]) {
+					t.Errorf("Encode() module %v is dark", point)
+				}
+			}
+		})
+	}
+}
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// Version 1-M codewords of "01234567" in numeric mode, ISO/IEC 18004 annex I
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder() = % X, want % X", got, want)
+	}
+}
+
+func TestCode_Image(t *testing.T) {
+	code, e := Encode("https://tinysrc.me/abc", Medium)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	red := color.RGBA{R: 0xff, A: 0xff}
+
+	tests := []struct {
+		name       string
+		options    Options
+		wantSize   int
+		wantOrigin image.Point
+	}{
+		{
+			name:       "test_default",
+			wantSize:   (25 + 8) * 8,
+			wantOrigin: image.Pt(32, 32),
+		},
+		{
+			name:       "test_fit_size",
+			options:    Options{Size: 100, Margin: -1, Foreground: red},
+			wantSize:   100,
+			wantOrigin: image.Pt(0, 0),
+		},
+		{
+			name:       "test_center_in_size",
+			options:    Options{Size: 40, Margin: 2},
+			wantSize:   40,
+			wantOrigin: image.Pt(7, 7),
+		},
+		{
+			name:       "test_size_smaller_than_code",
+			options:    Options{Size: 10},
+			wantSize:   33,
+			wantOrigin: image.Pt(4, 4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if e := code.PNG(&buffer, tt.options); e != nil {
+				t.Fatal(e)
+			}
+
+			img, e := png.Decode(&buffer)
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if img.Bounds().Dx() != tt.wantSize || img.Bounds().Dy() != tt.wantSize {
+				t.Errorf("PNG() size = %v, want %d", img.Bounds(), tt.wantSize)
+			}
+
+			foreground, background := tt.options.colors()
+			if !sameColor(img.At(tt.wantOrigin.X, tt.wantOrigin.Y), foreground) {
+				t.Errorf("PNG() origin %v = %v, want foreground", tt.wantOrigin, img.At(tt.wantOrigin.X, tt.wantOrigin.Y))
+			}
+			if tt.wantOrigin.X > 0 && !sameColor(img.At(tt.wantOrigin.X-1, tt.wantOrigin.Y), background) {
+				t.Errorf("PNG() margin = %v, want background", img.At(tt.wantOrigin.X-1, tt.wantOrigin.Y))
+			}
+		})
+	}
+}
+
+func TestCode_SVG(t *testing.T) {
+	code, e := Encode("https://tinysrc.me/abc", Low)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var buffer bytes.Buffer
+	options := Options{Size: 300, Margin: 1, Foreground: color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}, Background: color.Transparent}
+	if e := code.SVG(&buffer, options); e != nil {
+		t.Fatal(e)
+	}
+
+	svg := buffer.String()
+	for _, want := range []string{
+		`width="300" height="300" viewBox="0 0 27 27"`,
+		`fill="#112233"/>`,
+		`fill="#000000" fill-opacity="0"`,
+		// Top row of top-left finder
+		`<path d="M1 1h7v1h-7z`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG() = %s, want %s", svg, want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	code, e := Encode("https://tinysrc.me/abc", Medium)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var buffer bytes.Buffer
+	if e := code.PNG(&buffer, Options{}); e != nil {
+		t.Fatal(e)
+	}
+	encoded := base64.StdEncoding.EncodeToString(buffer.Bytes())
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{
+			name:    "test_data_uri",
+			payload: "data:image/png;base64," + encoded,
+		},
+		{
+			name:    "test_plain_base64",
+			payload: encoded,
+		},
+		{
+			name:    "test_wrapped_url_safe_without_padding",
+			payload: strings.TrimRight(base64.URLEncoding.EncodeToString(buffer.Bytes()), "=")[:60] + "\n" + strings.TrimRight(base64.URLEncoding.EncodeToString(buffer.Bytes()), "=")[60:],
+		},
+		{
+			name:    "test_empty",
+			payload: " ",
+			wantErr: true,
+		},
+		{
+			name:    "test_url",
+			payload: "https://tinysrc.me/qr/abc.png",
+			wantErr: true,
+		},
+		{
+			name:    "test_svg",
+			payload: "data:image/svg+xml;base64,PHN2Zy8+",
+			wantErr: true,
+		},
+		{
+			name:    "test_not_image",
+			payload: base64.StdEncoding.EncodeToString([]byte("text")),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, e := Decode(tt.payload)
+			if (e != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", e, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if img.Bounds().Dx() != (code.Size()+8)*8 {
+				t.Errorf("Decode() bounds = %v", img.Bounds())
+			}
+		})
+	}
+}
+
+func sameColor(a color.Color, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
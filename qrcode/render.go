@@ -0,0 +1,145 @@
+package qrcode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+const DEFAULT_MARGIN = 4
+const DEFAULT_MODULE_SIZE = 8
+
+// Rendering options, zero value renders black on white with 4 modules of quiet zone
+type Options struct {
+	// Width and height of image in pixels, modules are scaled to fit it.
+	// Zero means DEFAULT_MODULE_SIZE pixels per module.
+	Size int
+	// Quiet zone in modules, zero means DEFAULT_MARGIN, negative disables it
+	Margin     int
+	Foreground color.Color
+	Background color.Color
+}
+
+func (options Options) margin() int {
+	switch {
+	case options.Margin < 0:
+		return 0
+	case options.Margin == 0:
+		return DEFAULT_MARGIN
+	}
+
+	return options.Margin
+}
+
+func (options Options) colors() (color.Color, color.Color) {
+	foreground, background := options.Foreground, options.Background
+	if foreground == nil {
+		foreground = color.Black
+	}
+	if background == nil {
+		background = color.White
+	}
+
+	return foreground, background
+}
+
+// Pixels per module, image size and offset of first module
+func (code *Code) layout(options Options) (scale int, size int, offset int) {
+	modules := code.size + 2*options.margin()
+
+	if options.Size <= 0 {
+		scale = DEFAULT_MODULE_SIZE
+		size = modules * scale
+	} else {
+		scale = options.Size / modules
+		if scale < 1 {
+			scale = 1
+		}
+		size = options.Size
+		if size < modules {
+			size = modules
+		}
+	}
+
+	offset = (size-modules*scale)/2 + options.margin()*scale
+	return scale, size, offset
+}
+
+// Render code as paletted image
+func (code *Code) Image(options Options) image.Image {
+	foreground, background := options.colors()
+	scale, size, offset := code.layout(options)
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{background, foreground})
+	for y := 0; y < code.size; y++ {
+		for x := 0; x < code.size; x++ {
+			if !code.modules[y][x] {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				row := (offset+y*scale+dy)*img.Stride + offset + x*scale
+				for dx := 0; dx < scale; dx++ {
+					img.Pix[row+dx] = 1
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// Write code as PNG image
+func (code *Code) PNG(w io.Writer, options Options) error {
+	return png.Encode(w, code.Image(options))
+}
+
+// Write code as SVG image, dark modules of every row are merged into one path
+func (code *Code) SVG(w io.Writer, options Options) error {
+	foreground, background := options.colors()
+	margin := options.margin()
+	modules := code.size + 2*margin
+
+	size := options.Size
+	if size <= 0 {
+		size = modules * DEFAULT_MODULE_SIZE
+	}
+
+	var path strings.Builder
+	for y := 0; y < code.size; y++ {
+		for x := 0; x < code.size; {
+			if !code.modules[y][x] {
+				x++
+				continue
+			}
+
+			start := x
+			for x < code.size && code.modules[y][x] {
+				x++
+			}
+			_, _ = fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+margin, y+margin, x-start, x-start)
+		}
+	}
+
+	_, e := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n"+
+		`<rect width="100%%" height="100%%"%s/>`+"\n"+
+		`<path d="%s"%s/>`+"\n"+
+		`</svg>`+"\n",
+		size, size, modules, modules, svgFill(background), path.String(), svgFill(foreground))
+
+	return e
+}
+
+func svgFill(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, rgba.R, rgba.G, rgba.B)
+	if rgba.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%.3g"`, float64(rgba.A)/0xff)
+	}
+
+	return fill
+}
@@ -0,0 +1,248 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/anomaly"
+	"github.com/dmitrypro77/tinysrc-api-sdk/traffic"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CHART_WIDTH = 720
+const CHART_HEIGHT = 180
+const BAR_HEIGHT = 18
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"timeline": timelineChart,
+	"bars":     barChart,
+	"date":     formatDate,
+	"percent":  formatPercent,
+	"interval": formatInterval,
+	"yesno": func(value int) string {
+		if value != 0 {
+			return "yes"
+		}
+		return "no"
+	},
+}).Parse(pageTemplate))
+
+// Write report as HTML page
+func (report *Report) Write(w io.Writer) error {
+	var b bytes.Buffer
+	if e := page.Execute(&b, report); e != nil {
+		return e
+	}
+
+	_, e := w.Write(b.Bytes())
+	return e
+}
+
+// Write report as HTML file
+func (report *Report) WriteFile(path string) error {
+	var b bytes.Buffer
+	if e := report.Write(&b); e != nil {
+		return e
+	}
+
+	return os.WriteFile(path, b.Bytes(), 0644)
+}
+
+func formatDate(t any) string {
+	switch value := t.(type) {
+	case time.Time:
+		return value.Format("2006-01-02 15:04")
+	case *time.Time:
+		if value != nil {
+			return value.Format("2006-01-02 15:04")
+		}
+	}
+
+	return "-"
+}
+
+func formatPercent(share float64) string {
+	return strconv.FormatFloat(share*100, 'f', 1, 64) + "%"
+}
+
+func formatInterval(interval time.Duration) string {
+	if interval%(24*time.Hour) == 0 {
+		if days := int(interval / (24 * time.Hour)); days != 1 {
+			return strconv.Itoa(days) + " days"
+		}
+		return "day"
+	}
+	if interval == time.Hour {
+		return "hour"
+	}
+
+	return interval.String()
+}
+
+// Column chart of clicks per bucket, every column has tooltip
+func timelineChart(points []anomaly.Point, interval time.Duration) template.HTML {
+	const left, bottom, top = 40, 20, 10
+	width, height := float64(CHART_WIDTH-left), float64(CHART_HEIGHT-bottom-top)
+
+	var peak float64
+	for _, point := range points {
+		if point.Count > peak {
+			peak = point.Count
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="timeline" viewBox="0 0 %d %d" width="100%%" role="img" xmlns="http://www.w3.org/2000/svg">`, CHART_WIDTH, CHART_HEIGHT)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, CHART_HEIGHT-bottom, CHART_WIDTH, CHART_HEIGHT-bottom)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="end">%.0f</text>`, left-6, top+10, peak)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="end">0</text>`, left-6, CHART_HEIGHT-bottom)
+
+	if len(points) > 0 {
+		step := width / float64(len(points))
+		gap := step * 0.15
+
+		for i, point := range points {
+			barHeight := 0.0
+			if peak > 0 {
+				barHeight = point.Count / peak * height
+			}
+
+			fmt.Fprintf(&b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" class="bar"><title>%s: %.0f</title></rect>`,
+				float64(left)+float64(i)*step+gap/2, float64(top)+height-barHeight, step-gap, barHeight,
+				template.HTMLEscapeString(bucketLabel(point.Time, interval)), point.Count)
+		}
+
+		first, last := points[0], points[len(points)-1]
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="label">%s</text>`, left, CHART_HEIGHT-4, template.HTMLEscapeString(bucketLabel(first.Time, interval)))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="end">%s</text>`, CHART_WIDTH, CHART_HEIGHT-4, template.HTMLEscapeString(bucketLabel(last.Time, interval)))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func bucketLabel(t time.Time, interval time.Duration) string {
+	if interval >= 24*time.Hour {
+		return t.Format("2006-01-02")
+	}
+
+	return t.Format("2006-01-02 15:04")
+}
+
+// Horizontal bars of counts with clicks and share
+func barChart(counts []*traffic.Count) template.HTML {
+	if len(counts) == 0 {
+		return `<p class="empty">No clicks</p>`
+	}
+
+	const label, value = 140, 110
+	width := float64(CHART_WIDTH/2 - label - value)
+
+	var peak int64
+	for _, count := range counts {
+		if count.Clicks > peak {
+			peak = count.Clicks
+		}
+	}
+
+	height := len(counts) * (BAR_HEIGHT + 4)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="bars" viewBox="0 0 %d %d" width="100%%" role="img" xmlns="http://www.w3.org/2000/svg">`, CHART_WIDTH/2, height)
+
+	for i, count := range counts {
+		y := i * (BAR_HEIGHT + 4)
+		barWidth := 0.0
+		if peak > 0 {
+			barWidth = float64(count.Clicks) / float64(peak) * width
+		}
+
+		name := template.HTMLEscapeString(count.Name)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="label" text-anchor="end">%s</text>`, label-6, y+BAR_HEIGHT-5, name)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.2f" height="%d" class="bar"><title>%s: %d</title></rect>`, label, y, barWidth, BAR_HEIGHT, name, count.Clicks)
+		fmt.Fprintf(&b, `<text x="%.2f" y="%d" class="label">%d (%s)</text>`, float64(label)+barWidth+6, y+BAR_HEIGHT-5, count.Clicks, formatPercent(count.Share))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+const pageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font: 14px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; background: #f6f8fa; margin: 0; }
+main { max-width: 960px; margin: 0 auto; padding: 24px; }
+header p, .muted { color: #59636e; }
+section.link { background: #fff; border: 1px solid #d1d9e0; border-radius: 8px; padding: 16px 24px; margin: 24px 0; }
+h2 { margin: 0 0 8px; word-break: break-all; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eaeef2; }
+td.number, th.number { text-align: right; }
+.meta { display: flex; gap: 24px; align-items: flex-start; }
+.meta table { flex: 1; }
+.meta img { width: 120px; height: 120px; image-rendering: pixelated; }
+.totals { display: flex; gap: 32px; margin: 16px 0; }
+.totals div { font-size: 24px; font-weight: 600; }
+.totals span { display: block; font-size: 12px; font-weight: 400; color: #59636e; }
+.grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(300px, 1fr)); gap: 16px; }
+svg .bar { fill: #2f81f7; }
+svg .axis { stroke: #d1d9e0; }
+svg .label { fill: #59636e; font-size: 11px; }
+.empty { color: #59636e; font-style: italic; }
+@media print { body { background: #fff; } section.link { break-inside: avoid; border: none; } }
+</style>
+</head>
+<body>
+<main>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{date .Start}} &ndash; {{date .End}} &middot; {{len .Links}} links &middot; generated {{date .Generated}}</p>
+</header>
+{{range .Links}}
+<section class="link" id="link-{{.Hash}}">
+<h2>{{.Hash}}</h2>
+<div class="meta">
+<table>
+{{with .Info}}<tr><th>Destination</th><td>{{.Url}}</td></tr>
+<tr><th>Active</th><td>{{yesno .Active}}</td></tr>
+<tr><th>Password protected</th><td>{{yesno .AuthRequired}}</td></tr>
+<tr><th>Created</th><td>{{date .Created}}</td></tr>
+<tr><th>Expires</th><td>{{date .ExpirationTime}}</td></tr>
+<tr><th>Clicks all time</th><td>{{.Clicks}} ({{.Bots}} bots)</td></tr>{{end}}
+</table>
+{{if .QRCode}}<img src="{{.QRCode}}" alt="QR code of {{.Hash}}">{{end}}
+</div>
+<div class="totals">
+<div>{{.Clicks}}<span>clicks</span></div>
+<div>{{.UniqueIps}}<span>unique IPs</span></div>
+<div>{{.Bots}}<span>bots</span></div>
+</div>
+<h3>Clicks by {{interval $.Interval}}</h3>
+{{timeline .Timeline $.Interval}}
+<div class="grid">
+<div><h3>Devices</h3>{{bars .Traffic.Devices}}</div>
+<div><h3>Browsers</h3>{{bars .Traffic.Browsers}}</div>
+<div><h3>Operating systems</h3>{{bars .Traffic.Os}}</div>
+<div><h3>Sources</h3>{{bars .Traffic.Kinds}}</div>
+</div>
+<h3>Referers</h3>
+{{if .Traffic.Domains}}<table>
+<tr><th>Domain</th><th class="number">Clicks</th><th class="number">Share</th></tr>
+{{range .Traffic.Domains}}<tr><td>{{.Name}}</td><td class="number">{{.Clicks}}</td><td class="number">{{percent .Share}}</td></tr>
+{{end}}</table>{{else}}<p class="empty">No referers</p>{{end}}
+</section>
+{{else}}
+<p class="empty">No links</p>
+{{end}}
+</main>
+</body>
+</html>
+`
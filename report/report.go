@@ -0,0 +1,202 @@
+// Package report generates a shareable analytics report of links as one
+// static HTML file: link metadata, clicks timeline, device, browser and OS
+// breakdowns and referers. CSS and SVG charts are embedded, so the file has
+// no external assets and can be sent to clients who do not use TinySRC.
+//
+//	generator := report.NewGenerator(client)
+//	generator.Title = "Spring sale"
+//	r, errorResponse := generator.Build([]string{"test"}, start, end)
+//	e := r.WriteFile("report.html")
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/anomaly"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/qrcode"
+	"github.com/dmitrypro77/tinysrc-api-sdk/traffic"
+	"html/template"
+	"image/png"
+	"time"
+)
+
+const DEFAULT_TITLE = "Links Report"
+const DEFAULT_TOP = 10
+
+// Ranges up to MAX_HOURLY_RANGE are shown by hour, longer ranges by day
+const MAX_HOURLY_RANGE = 72 * time.Hour
+
+type Report struct {
+	Title     string    `json:"title"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Generated time.Time `json:"generated"`
+	// Bucket of timelines
+	Interval time.Duration `json:"interval"`
+	Links    []*Link       `json:"links"`
+}
+
+// Metadata and statistic of one link in the report range
+type Link struct {
+	Hash string                   `json:"hash"`
+	Info *models.LinkUserResponse `json:"info"`
+	// PNG data URI of QR code, empty when server returned no QR code
+	QRCode    template.URL    `json:"-"`
+	Clicks    int64           `json:"clicks"`
+	Bots      int64           `json:"bots"`
+	UniqueIps int64           `json:"unique_ips"`
+	Timeline  []anomaly.Point `json:"timeline"`
+	Traffic   *traffic.Report `json:"traffic"`
+}
+
+type Generator struct {
+	Client *tinysrc.Client
+	// Title of report, DEFAULT_TITLE when empty
+	Title string
+	// Bucket of timelines, chosen by length of range when zero
+	Interval time.Duration
+	// Rows of breakdowns and referers, DEFAULT_TOP when zero
+	Top int
+	// Time zone of dates in the report, UTC when nil
+	Location *time.Location
+	// Options of statistic requests
+	Options tinysrc.StatChunkOptions
+}
+
+func NewGenerator(client *tinysrc.Client) *Generator {
+	return &Generator{Client: client, Title: DEFAULT_TITLE, Top: DEFAULT_TOP}
+}
+
+// Load metadata with GetUrlByHash and statistic with GetStatByHash for every hash in [start, end).
+// Loading stops at the first failed hash.
+func (generator *Generator) Build(hashes []string, start time.Time, end time.Time) (r *Report, errorResponse models.ErrorResponse) {
+	location := generator.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	report := &Report{
+		Title:     generator.Title,
+		Start:     start.In(location),
+		End:       end.In(location),
+		Generated: time.Now().In(location),
+		Interval:  generator.Interval,
+	}
+
+	if report.Title == "" {
+		report.Title = DEFAULT_TITLE
+	}
+
+	if report.Interval <= 0 {
+		report.Interval = 24 * time.Hour
+		if end.Sub(start) <= MAX_HOURLY_RANGE {
+			report.Interval = time.Hour
+		}
+	}
+
+	for _, hash := range hashes {
+		info, errorResponse := generator.Client.GetUrlByHash(hash)
+		if info == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			return nil, errorResponse
+		}
+
+		stats, errorResponse := generator.Client.GetStatByHashChunked(hash, models.StatRequest{DateStart: start, DateEnd: end}, generator.Options)
+		if stats == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			return nil, errorResponse
+		}
+
+		report.Links = append(report.Links, generator.Link(hash, info, stats.Data, report.Start, report.End, report.Interval))
+	}
+
+	return report, errorResponse
+}
+
+// Statistic of one link from rows which are already loaded, rows outside [start, end) are ignored
+func (generator *Generator) Link(hash string, info *models.LinkUserResponse, stats []*models.StatResponse, start time.Time, end time.Time, interval time.Duration) *Link {
+	link := &Link{Hash: hash, Info: inLocation(info, start.Location()), QRCode: qrCodeURI(info)}
+
+	var rows []*models.StatResponse
+	ips := make(map[string]bool)
+
+	for _, stat := range stats {
+		if stat.Created.Before(start) || !stat.Created.Before(end) {
+			continue
+		}
+
+		rows = append(rows, stat)
+		ips[stat.Ip] = true
+		if stat.Bot {
+			link.Bots++
+		}
+	}
+
+	top := generator.Top
+	if top <= 0 {
+		top = DEFAULT_TOP
+	}
+
+	link.Clicks = int64(len(rows))
+	link.UniqueIps = int64(len(ips))
+	link.Timeline = timeline(rows, start, end, interval)
+	link.Traffic = traffic.Analyze(traffic.Enrich(rows), top)
+
+	return link
+}
+
+// Buckets aligned to midnight of location of start
+func timeline(stats []*models.StatResponse, start time.Time, end time.Time, interval time.Duration) []anomaly.Point {
+	_, offset := start.Zone()
+	shift := time.Duration(offset) * time.Second
+
+	shifted := make([]*models.StatResponse, len(stats))
+	for i, stat := range stats {
+		copied := *stat
+		copied.Created = stat.Created.Add(shift)
+		shifted[i] = &copied
+	}
+
+	points := anomaly.Buckets(shifted, start.Add(shift), end.Add(shift), interval)
+	for i := range points {
+		points[i].Time = points[i].Time.Add(-shift).In(start.Location())
+	}
+
+	return points
+}
+
+// QR code of server re-encoded as PNG data URI, so report does not load images
+func qrCodeURI(info *models.LinkUserResponse) template.URL {
+	if info == nil || info.QRCode == "" {
+		return ""
+	}
+
+	img, e := qrcode.Decode(info.QRCode)
+	if e != nil {
+		return ""
+	}
+
+	var b bytes.Buffer
+	if e = png.Encode(&b, img); e != nil {
+		return ""
+	}
+
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()))
+}
+
+// Copy of link metadata with dates in time zone of the report
+func inLocation(info *models.LinkUserResponse, location *time.Location) *models.LinkUserResponse {
+	if info == nil {
+		return nil
+	}
+
+	copied := *info
+	for _, t := range []**time.Time{&copied.Created, &copied.ExpirationTime} {
+		if *t != nil {
+			converted := (*t).In(location)
+			*t = &converted
+		}
+	}
+
+	return &copied
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/qrcode"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Build(t *testing.T) {
+	start := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	code, _ := qrcode.Encode("https://tinysrc.me/test", qrcode.Medium)
+	var qr bytes.Buffer
+	_ = code.PNG(&qr, qrcode.Options{})
+
+	created := time.Date(2022, 3, 31, 23, 30, 0, 0, time.UTC)
+	expiration := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	link := models.LinkUserResponse{
+		Url:            "https://test.com/?a=1&b=<2>",
+		Hash:           "test",
+		Active:         1,
+		Clicks:         42,
+		QRCode:         base64.StdEncoding.EncodeToString(qr.Bytes()),
+		Created:        &created,
+		ExpirationTime: &expiration,
+	}
+
+	rows := []*models.StatResponse{
+		{Ip: "1.1.1.1", Browser: "Chrome", Os: "Windows", Referer: "https://www.google.com/", Created: start.Add(time.Hour)},
+		{Ip: "1.1.1.2", Browser: "Safari", Os: "iOS", Mobile: true, Referer: "https://t.co/x", Created: start.Add(time.Hour + time.Minute)},
+		{Ip: "1.1.1.2", Browser: "Safari", Os: "iOS", Mobile: true, Created: start.Add(5 * time.Hour)},
+		{Ip: "1.1.1.3", Browser: "Googlebot", Bot: true, Created: start.Add(23 * time.Hour)},
+		// DateEnd is inclusive, row is outside of report
+		{Ip: "1.1.1.4", Browser: "Chrome", Created: end},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/client/url/test":
+			_ = json.NewEncoder(w).Encode(&link)
+		case "/v1/client/stat/test":
+			resp := models.StatPaginatedResponse{}
+			if r.URL.Query().Get("page") == "1" {
+				resp.Data = rows
+			}
+			resp.Total = int64(len(resp.Data))
+			_ = json.NewEncoder(w).Encode(&resp)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+		}
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	generator := NewGenerator(client)
+
+	if _, errorResponse := generator.Build([]string{"test", "missing"}, start, end); len(errorResponse.Errors) == 0 {
+		t.Errorf("Build() expected errors")
+	}
+
+	report, errorResponse := generator.Build([]string{"test"}, start, end)
+	if len(errorResponse.Errors) > 0 {
+		t.Fatalf("Build() errors = %v", errorResponse.Errors)
+	}
+
+	got := report.Links[0]
+	if report.Interval != time.Hour || len(got.Timeline) != 24 || got.Clicks != 4 || got.UniqueIps != 3 || got.Bots != 1 {
+		t.Fatalf("Build() = %+v", got)
+	}
+	if got.Timeline[1].Count != 2 || got.Timeline[5].Count != 1 || got.Timeline[23].Count != 1 {
+		t.Errorf("Timeline = %v", got.Timeline)
+	}
+
+	var b bytes.Buffer
+	if e := report.Write(&b); e != nil {
+		t.Fatalf("Write() error = %v", e)
+	}
+	html := b.String()
+
+	for _, want := range []string{
+		`<title>Links Report</title>`,
+		`<td>https://test.com/?a=1&amp;b=&lt;2&gt;</td>`,
+		`<img src="data:image/png;base64,`,
+		`<td>google.com</td><td class="number">1</td><td class="number">25.0%</td>`,
+		`<title>2022-04-01 01:00: 2</title>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Write() does not contain %s", want)
+		}
+	}
+
+	// Self-contained page, only data URIs are loaded
+	if assets := regexp.MustCompile(`(?i)<script|<link|(src|href)="(https?:)?//`).FindAllString(html, -1); len(assets) > 0 {
+		t.Errorf("Write() has external assets %v", assets)
+	}
+
+	if columns := strings.Count(html, `class="bar"><title>2022-04-01`); columns != 24 {
+		t.Errorf("Write() timeline has %d columns", columns)
+	}
+
+	// Dates of link are shown in time zone of the report too
+	generator.Location = time.FixedZone("UTC+3", 3*60*60)
+	report, _ = generator.Build([]string{"test"}, start, end)
+	b.Reset()
+	_ = report.Write(&b)
+	for _, want := range []string{
+		`<tr><th>Created</th><td>2022-04-01 02:30</td></tr>`,
+		`<tr><th>Expires</th><td>2022-05-01 03:00</td></tr>`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Write() does not contain %s", want)
+		}
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	location := time.FixedZone("UTC+3", 3*60*60)
+	start := time.Date(2022, 4, 1, 0, 0, 0, 0, location)
+
+	stats := []*models.StatResponse{
+		// 23:30 of the first day in location
+		{Created: time.Date(2022, 4, 1, 20, 30, 0, 0, time.UTC)},
+		{Created: time.Date(2022, 4, 1, 21, 30, 0, 0, time.UTC)},
+	}
+
+	points := timeline(stats, start, start.AddDate(0, 0, 2), 24*time.Hour)
+	if len(points) != 2 || !points[0].Time.Equal(start) || points[0].Count != 1 || points[1].Count != 1 {
+		t.Errorf("timeline() = %v", points)
+	}
+}
@@ -0,0 +1,269 @@
+// Package rewriter replaces long links of plain text, Markdown and HTML with
+// short links. URLs are filtered by domain rules, every unique URL is shortened
+// once with CreateShortLink and already shortened URLs are cached between calls.
+//
+//	rw := rewriter.New(client)
+//	rw.Deny = []string{"unsubscribe.test.com"}
+//	result, errorResponse := rw.Rewrite(newsletter, rewriter.FormatHTML)
+//	fmt.Println(result.Content)
+//	for _, mapping := range result.Links {
+//		fmt.Println(mapping.Url, mapping.ShortUrl)
+//	}
+package rewriter
+
+import (
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/idempotent"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"html"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const DEFAULT_CONCURRENCY = 4
+
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+// Parse name of format, e.g. value of command line flag
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatText, FormatMarkdown, FormatHTML:
+		return format, nil
+	case "txt":
+		return FormatText, nil
+	case "md":
+		return FormatMarkdown, nil
+	case "htm":
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("rewriter: unknown format %q", name)
+	}
+}
+
+// Original URL and its short link
+type Mapping struct {
+	Url      string `json:"url"`
+	ShortUrl string `json:"short_url"`
+	// Occurrences of URL in content
+	Count int `json:"count"`
+	// Short link was taken from cache
+	Cached bool `json:"cached"`
+}
+
+type Result struct {
+	Content string `json:"content"`
+	// Shortened URLs in order of the first occurrence
+	Links []*Mapping `json:"links"`
+}
+
+type Rewriter struct {
+	Client *tinysrc.Client
+	// Domains of shortened URLs with subdomains, all domains when empty
+	Allow []string
+	// Domains which are never shortened, Deny wins over Allow. Short links are never shortened again.
+	Deny []string
+	// Links are created only once per destination across runs when set
+	Creator *idempotent.Creator
+	// URLs shortened at the same time, DEFAULT_CONCURRENCY when zero
+	Concurrency int
+
+	mutex sync.Mutex
+	cache map[string]string
+}
+
+func New(client *tinysrc.Client) *Rewriter {
+	return &Rewriter{Client: client, Concurrency: DEFAULT_CONCURRENCY}
+}
+
+// Domain rules accept URL
+func (rewriter *Rewriter) Accepts(rawURL string) bool {
+	parsed, e := url.Parse(rawURL)
+	if e != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "" {
+		return false
+	}
+
+	if short, e := url.Parse(tinysrc.SHORT_URL); e == nil && host == short.Hostname() {
+		return false
+	}
+
+	for _, domain := range rewriter.Deny {
+		if matchDomain(host, domain) {
+			return false
+		}
+	}
+
+	if len(rewriter.Allow) == 0 {
+		return true
+	}
+
+	for _, domain := range rewriter.Allow {
+		if matchDomain(host, domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Replace accepted URLs of content with short links. URLs which failed are left
+// unchanged and their errors are returned together with the result.
+func (rewriter *Rewriter) Rewrite(content string, format Format) (r *Result, errorResponse models.ErrorResponse) {
+	var spans []span
+	switch format {
+	case FormatText:
+		spans = scanText(content, 0, nil)
+	case FormatMarkdown:
+		spans = scanMarkdown(content)
+	case FormatHTML:
+		spans = scanHTML(content)
+	default:
+		errorResponse.Errors = append(errorResponse.Errors, fmt.Sprintf("rewriter: unknown format %q", format))
+		return nil, errorResponse
+	}
+
+	var urls []string
+	mappings := map[string]*Mapping{}
+	for _, s := range spans {
+		if !rewriter.Accepts(s.url) {
+			continue
+		}
+
+		if mapping := mappings[s.url]; mapping != nil {
+			mapping.Count++
+			continue
+		}
+
+		mappings[s.url] = &Mapping{Url: s.url, Count: 1}
+		urls = append(urls, s.url)
+	}
+
+	errorResponse = rewriter.shorten(urls, mappings)
+
+	r = &Result{Links: []*Mapping{}}
+	for _, rawURL := range urls {
+		if mappings[rawURL].ShortUrl != "" {
+			r.Links = append(r.Links, mappings[rawURL])
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		mapping := mappings[s.url]
+		if mapping == nil || mapping.ShortUrl == "" {
+			continue
+		}
+
+		b.WriteString(content[last:s.start])
+		if s.escape {
+			b.WriteString(html.EscapeString(mapping.ShortUrl))
+		} else {
+			b.WriteString(mapping.ShortUrl)
+		}
+		last = s.end
+	}
+	b.WriteString(content[last:])
+	r.Content = b.String()
+
+	return r, errorResponse
+}
+
+// Set short links of mappings, cached URLs are not sent to API
+func (rewriter *Rewriter) shorten(urls []string, mappings map[string]*Mapping) (errorResponse models.ErrorResponse) {
+	var pending []string
+
+	rewriter.mutex.Lock()
+	for _, rawURL := range urls {
+		if short, found := rewriter.cache[rawURL]; found {
+			mappings[rawURL].ShortUrl, mappings[rawURL].Cached = short, true
+			continue
+		}
+		pending = append(pending, rawURL)
+	}
+	rewriter.mutex.Unlock()
+
+	concurrency := rewriter.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+
+	links := make([]*models.LinkResponse, len(pending))
+	failures := make([]models.ErrorResponse, len(pending))
+
+	var wg sync.WaitGroup
+	pool := make(chan struct{}, concurrency)
+
+	for i, rawURL := range pending {
+		wg.Add(1)
+		pool <- struct{}{}
+
+		go func(i int, rawURL string) {
+			defer wg.Done()
+			defer func() { <-pool }()
+
+			request := models.LinkRequest{Url: rawURL}
+			if rewriter.Creator != nil {
+				links[i], _, failures[i] = rewriter.Creator.Create(request)
+			} else {
+				links[i], failures[i] = rewriter.Client.CreateShortLink(request)
+			}
+		}(i, rawURL)
+	}
+
+	wg.Wait()
+
+	rewriter.mutex.Lock()
+	defer rewriter.mutex.Unlock()
+
+	if rewriter.cache == nil {
+		rewriter.cache = make(map[string]string)
+	}
+
+	for i, rawURL := range pending {
+		failure := failures[i]
+		if len(failure.Errors) == 0 && len(failure.Validations) == 0 && (links[i] == nil || links[i].Url == "") {
+			failure.Errors = append(failure.Errors, "empty response")
+		}
+
+		if len(failure.Errors) == 0 && len(failure.Validations) == 0 {
+			rewriter.cache[rawURL] = links[i].Url
+			mappings[rawURL].ShortUrl = links[i].Url
+			continue
+		}
+
+		for _, e := range failure.Errors {
+			errorResponse.Errors = append(errorResponse.Errors, rawURL+": "+e)
+		}
+		for field, messages := range failure.Validations {
+			if errorResponse.Validations == nil {
+				errorResponse.Validations = make(map[string][]string)
+			}
+			errorResponse.Validations[field] = append(errorResponse.Validations[field], messages...)
+		}
+		if failure.Status != 0 {
+			errorResponse.Status = failure.Status
+		}
+	}
+
+	return errorResponse
+}
+
+// Host is domain or its subdomain
+func matchDomain(host string, domain string) bool {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
@@ -0,0 +1,152 @@
+package rewriter
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestRewriter(t *testing.T) (*Rewriter, func() []string) {
+	var mu sync.Mutex
+	var created []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := models.LinkRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		if strings.Contains(request.Url, "invalid") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"validations":{"url":["invalid url"]}}`))
+			return
+		}
+
+		mu.Lock()
+		created = append(created, request.Url)
+		hash := "h" + strconv.Itoa(len(created))
+		mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(&models.LinkResponse{Url: tinysrc.ShortUrl(hash)})
+	}))
+	t.Cleanup(ts.Close)
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	return New(client), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), created...)
+	}
+}
+
+func TestRewriter_Accepts(t *testing.T) {
+	rewriter := &Rewriter{Allow: []string{"test.com", "Shop.example.org."}, Deny: []string{"unsubscribe.test.com"}}
+
+	tests := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{name: "test_domain", url: "https://test.com/a", want: true},
+		{name: "test_subdomain", url: "https://www.test.com:8080/a", want: true},
+		{name: "test_case", url: "https://SHOP.example.org/a", want: true},
+		{name: "test_denied", url: "https://unsubscribe.test.com/a", want: false},
+		{name: "test_not_allowed", url: "https://example.org/a", want: false},
+		{name: "test_suffix", url: "https://notatest.com/a", want: false},
+		{name: "test_short_link", url: tinysrc.ShortUrl("abc"), want: false},
+		{name: "test_scheme", url: "ftp://test.com/a", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriter.Accepts(tt.url); got != tt.want {
+				t.Errorf("Accepts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriter_Rewrite(t *testing.T) {
+	rewriter, created := newTestRewriter(t)
+	rewriter.Deny = []string{"unsubscribe.test.com"}
+	// Hashes of test server are created in order of requests
+	rewriter.Concurrency = 1
+
+	content := `<p>Hello</p><a href="https://test.com/a?x=1&amp;y=2">A</a> <a href="https://test.com/b">B</a>` +
+		`<a href="https://test.com/a?x=1&y=2">A again</a><a href="https://unsubscribe.test.com/">U</a>` +
+		`<a href="` + tinysrc.ShortUrl("old") + `">S</a>`
+
+	result, errorResponse := rewriter.Rewrite(content, FormatHTML)
+	if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		t.Fatalf("Rewrite() error = %+v", errorResponse)
+	}
+
+	want := `<p>Hello</p><a href="https://tinysrc.me/h1">A</a> <a href="https://tinysrc.me/h2">B</a>` +
+		`<a href="https://tinysrc.me/h1">A again</a><a href="https://unsubscribe.test.com/">U</a>` +
+		`<a href="` + tinysrc.ShortUrl("old") + `">S</a>`
+	if result.Content != want {
+		t.Errorf("Rewrite() content = %s, want %s", result.Content, want)
+	}
+
+	wantLinks := []*Mapping{
+		{Url: "https://test.com/a?x=1&y=2", ShortUrl: "https://tinysrc.me/h1", Count: 2},
+		{Url: "https://test.com/b", ShortUrl: "https://tinysrc.me/h2", Count: 1},
+	}
+	if !reflect.DeepEqual(result.Links, wantLinks) {
+		t.Errorf("Rewrite() links = %+v", result.Links)
+	}
+
+	// Already shortened URLs are taken from cache
+	result, _ = rewriter.Rewrite("Again: https://test.com/b and https://test.com/c.", FormatText)
+	if result.Content != "Again: https://tinysrc.me/h2 and https://tinysrc.me/h3." {
+		t.Errorf("Rewrite() content = %s", result.Content)
+	}
+	if !result.Links[0].Cached || result.Links[1].Cached {
+		t.Errorf("Rewrite() links = %+v, %+v", result.Links[0], result.Links[1])
+	}
+	if got := created(); len(got) != 3 {
+		t.Errorf("created = %v", got)
+	}
+}
+
+func TestRewriter_RewriteFailed(t *testing.T) {
+	rewriter, _ := newTestRewriter(t)
+	rewriter.Concurrency = 1
+
+	content := "[ok](https://test.com/ok) and [bad](https://test.com/invalid) `https://test.com/code`"
+	result, errorResponse := rewriter.Rewrite(content, FormatMarkdown)
+
+	if result.Content != "[ok](https://tinysrc.me/h1) and [bad](https://test.com/invalid) `https://test.com/code`" {
+		t.Errorf("Rewrite() content = %s", result.Content)
+	}
+	if len(result.Links) != 1 || result.Links[0].Url != "https://test.com/ok" {
+		t.Errorf("Rewrite() links = %+v", result.Links)
+	}
+	if !reflect.DeepEqual(errorResponse.Validations, map[string][]string{"url": {"invalid url"}}) || errorResponse.Status != http.StatusUnprocessableEntity {
+		t.Errorf("Rewrite() error = %+v", errorResponse)
+	}
+
+	if _, errorResponse = rewriter.Rewrite(content, "pdf"); len(errorResponse.Errors) != 1 {
+		t.Errorf("Rewrite() unknown format error = %+v", errorResponse)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"text": FormatText, "MD": FormatMarkdown, " html ": FormatHTML, "htm": FormatHTML} {
+		if got, e := ParseFormat(name); e != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v", name, got, e)
+		}
+	}
+
+	if _, e := ParseFormat("pdf"); e == nil {
+		t.Errorf("ParseFormat() expected error")
+	}
+}
@@ -0,0 +1,324 @@
+package rewriter
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// URL found in content at content[start:end]
+type span struct {
+	start int
+	end   int
+	url   string
+	// Replacement is HTML escaped, value of attribute
+	escape bool
+}
+
+// Bare http and https URLs of plain text
+func scanText(content string, offset int, spans []span) []span {
+	for i := 0; i < len(content); {
+		index := indexFold(content[i:], "http")
+		if index < 0 {
+			break
+		}
+		start := i + index
+
+		scheme := 0
+		switch {
+		case hasPrefixFold(content[start:], "https://"):
+			scheme = len("https://")
+		case hasPrefixFold(content[start:], "http://"):
+			scheme = len("http://")
+		}
+
+		// URL starts at word boundary, e.g. not inside "xhttp://"
+		if scheme == 0 || (start > 0 && isWord(content[start-1])) {
+			i = start + 4
+			continue
+		}
+
+		end := start + scheme
+		for end < len(content) && !isTerminator(content[end]) {
+			end++
+		}
+		end = start + len(trimURL(content[start:end]))
+
+		if parsed, e := url.Parse(content[start:end]); e == nil && parsed.Host != "" {
+			spans = append(spans, span{start: offset + start, end: offset + end, url: content[start:end]})
+		}
+
+		i = end
+	}
+
+	return spans
+}
+
+// URLs of Markdown: inline links, autolinks, reference definitions and bare URLs.
+// Fenced code blocks and code spans are not changed.
+func scanMarkdown(content string) []span {
+	var spans []span
+	fence := ""
+
+	for offset := 0; offset < len(content); {
+		end := strings.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += offset + 1
+		}
+		line := content[offset:end]
+
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			spans = scanMarkdownLine(line, offset, spans)
+		}
+
+		offset = end
+	}
+
+	return spans
+}
+
+// Text of line outside code spans
+func scanMarkdownLine(line string, offset int, spans []span) []span {
+	start := 0
+
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+
+		run := 0
+		for i+run < len(line) && line[i+run] == '`' {
+			run++
+		}
+
+		closing := indexRun(line[i+run:], run)
+		if closing < 0 {
+			i += run
+			continue
+		}
+
+		spans = scanText(line[start:i], offset+start, spans)
+		i += run + closing + run
+		start = i
+	}
+
+	return scanText(line[start:], offset+start, spans)
+}
+
+// Index of backtick run of exactly length n
+func indexRun(s string, n int) int {
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+
+		run := 0
+		for i+run < len(s) && s[i+run] == '`' {
+			run++
+		}
+		if run == n {
+			return i
+		}
+		i += run
+	}
+
+	return -1
+}
+
+// Href values of <a> and <area> tags. Comments, declarations and contents of
+// script, style and textarea elements are skipped.
+func scanHTML(content string) []span {
+	var spans []span
+
+	for i := 0; i < len(content); {
+		index := strings.IndexByte(content[i:], '<')
+		if index < 0 {
+			break
+		}
+		i += index
+
+		rest := content[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return spans
+			}
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?") || strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return spans
+			}
+			i += end + 1
+		case len(rest) > 1 && isLetter(rest[1]):
+			tag := parseTag(content, i)
+
+			if tag.name == "a" || tag.name == "area" {
+				for _, attribute := range tag.attributes {
+					if attribute.name != "href" {
+						continue
+					}
+
+					value := strings.TrimSpace(html.UnescapeString(content[attribute.start:attribute.end]))
+					if parsed, e := url.Parse(value); e == nil && parsed.Host != "" && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+						spans = append(spans, span{start: attribute.start, end: attribute.end, url: value, escape: true})
+					}
+					break
+				}
+			}
+
+			i = tag.end
+			if tag.name == "script" || tag.name == "style" || tag.name == "textarea" {
+				end := indexFold(content[i:], "</"+tag.name)
+				if end < 0 {
+					return spans
+				}
+				i += end
+			}
+		default:
+			i++
+		}
+	}
+
+	return spans
+}
+
+type tag struct {
+	name       string
+	attributes []attribute
+	// Index after closing >
+	end int
+}
+
+// Attribute with value at content[start:end]
+type attribute struct {
+	name  string
+	start int
+	end   int
+}
+
+// Start tag at content[start], attribute values may contain > when quoted
+func parseTag(content string, start int) tag {
+	i := start + 1
+	for i < len(content) && !isSpace(content[i]) && content[i] != '/' && content[i] != '>' {
+		i++
+	}
+
+	t := tag{name: strings.ToLower(content[start+1 : i])}
+
+	for i < len(content) {
+		for i < len(content) && (isSpace(content[i]) || content[i] == '/') {
+			i++
+		}
+		if i >= len(content) {
+			break
+		}
+		if content[i] == '>' {
+			t.end = i + 1
+			return t
+		}
+
+		nameStart := i
+		for i < len(content) && !isSpace(content[i]) && content[i] != '=' && content[i] != '>' && content[i] != '/' {
+			i++
+		}
+		a := attribute{name: strings.ToLower(content[nameStart:i]), start: i, end: i}
+
+		j := i
+		for j < len(content) && isSpace(content[j]) {
+			j++
+		}
+		if j < len(content) && content[j] == '=' {
+			j++
+			for j < len(content) && isSpace(content[j]) {
+				j++
+			}
+
+			if j < len(content) && (content[j] == '"' || content[j] == '\'') {
+				quote := content[j]
+				end := strings.IndexByte(content[j+1:], quote)
+				if end < 0 {
+					end = len(content) - j - 1
+				}
+				a.start, a.end = j+1, j+1+end
+				i = a.end + 1
+			} else {
+				a.start = j
+				for j < len(content) && !isSpace(content[j]) && content[j] != '>' {
+					j++
+				}
+				a.end = j
+				i = j
+			}
+		}
+
+		if a.name != "" {
+			t.attributes = append(t.attributes, a)
+		} else if i == nameStart {
+			i++
+		}
+	}
+
+	t.end = len(content)
+	return t
+}
+
+// Trailing punctuation of sentence and unbalanced closing parenthesis are not part of URL
+func trimURL(value string) string {
+	for len(value) > 0 {
+		last := value[len(value)-1]
+		switch {
+		case strings.IndexByte(".,;:!?*_~'\"", last) >= 0:
+			value = value[:len(value)-1]
+		case last == ')' && strings.Count(value, "(") < strings.Count(value, ")"):
+			value = value[:len(value)-1]
+		default:
+			return value
+		}
+	}
+
+	return value
+}
+
+func indexFold(s string, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func isTerminator(c byte) bool {
+	return c <= ' ' || c == '<' || c == '>' || c == '"' || c == '`' || c == '[' || c == ']' || c == 0x7f
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWord(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '_'
+}
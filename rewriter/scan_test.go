@@ -0,0 +1,70 @@
+package rewriter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func urls(content string, spans []span) []string {
+	result := []string{}
+	for _, s := range spans {
+		if content[s.start:s.end] != s.url && !s.escape {
+			return []string{"span " + content[s.start:s.end] + " != " + s.url}
+		}
+		result = append(result, s.url)
+	}
+	return result
+}
+
+func TestScanText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "test_sentence", content: "See https://test.com/a?b=1, or http://test.com/c.", want: []string{"https://test.com/a?b=1", "http://test.com/c"}},
+		{name: "test_parenthesis", content: "(docs at https://en.wikipedia.org/wiki/Go_(language))", want: []string{"https://en.wikipedia.org/wiki/Go_(language)"}},
+		{name: "test_quoted", content: `link "https://test.com/q" and <https://test.com/a>`, want: []string{"https://test.com/q", "https://test.com/a"}},
+		{name: "test_upper_case", content: "HTTPS://TEST.COM/A", want: []string{"HTTPS://TEST.COM/A"}},
+		{name: "test_word_boundary", content: "xhttps://test.com https:// http://", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := urls(tt.content, scanText(tt.content, 0, nil)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanMarkdown(t *testing.T) {
+	content := "# Title\n" +
+		"Read [the post](https://test.com/post \"Post\") and [https://test.com/a](https://test.com/b).\n" +
+		"Code `https://test.com/code` and ``https://test.com/`code` `` stays.\n" +
+		"```sh\ncurl https://test.com/fenced\n```\n" +
+		"[ref]: https://test.com/ref\n" +
+		"~~~\nhttps://test.com/tilde\n~~~\n" +
+		"Autolink <https://test.com/auto>"
+
+	want := []string{"https://test.com/post", "https://test.com/a", "https://test.com/b", "https://test.com/ref", "https://test.com/auto"}
+	if got := urls(content, scanMarkdown(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("scanMarkdown() = %v, want %v", got, want)
+	}
+}
+
+func TestScanHTML(t *testing.T) {
+	content := `<!DOCTYPE html><html><head><style>a[href="https://test.com/style"] {}</style></head><body>` +
+		`<!-- <a href="https://test.com/comment"> -->` +
+		`<p>Text https://test.com/text</p>` +
+		`<a class="button" title="a > b" HREF = "https://test.com/a?x=1&amp;y=2">A</a>` +
+		`<a href='https://test.com/single'>B</a><a href=https://test.com/unquoted>C</a>` +
+		`<a href="mailto:test@test.com">D</a><a href="/relative">E</a><area href="https://test.com/area">` +
+		`<img src="https://test.com/image.png"><script>document.write('<a href="https://test.com/script">')</script>` +
+		`<a data-href="https://test.com/data">F</a>`
+
+	want := []string{"https://test.com/a?x=1&y=2", "https://test.com/single", "https://test.com/unquoted", "https://test.com/area"}
+	if got := urls(content, scanHTML(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("scanHTML() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package tinysrc
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/password"
+)
+
+// Rotate password of protected link. Password can not be changed in place, so link is
+// recreated with the same destination and expiration and the old link is deactivated.
+// New password is generated with default password.Policy when newPassword is empty.
+// Returned link has new hash; it is returned even when deactivation of old link failed.
+func (client *Client) RotatePassword(hash string, newPassword models.Secret) (r *models.LinkResponse, errorResponse models.ErrorResponse) {
+	link, errorResponse := client.GetUrlByHash(hash)
+	if link == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return nil, errorResponse
+	}
+
+	if newPassword == "" {
+		generated, e := password.Generate(password.Policy{})
+		if e != nil {
+			errorResponse.Errors = append(errorResponse.Errors, e.Error())
+			return nil, errorResponse
+		}
+		newPassword = generated
+	}
+
+	r, errorResponse = client.CreateShortLink(models.LinkRequest{
+		Url:          link.Url,
+		AuthRequired: 1,
+		Password:     newPassword,
+		ExpiresAt:    link.ExpirationTime,
+	})
+	if r == nil {
+		if len(errorResponse.Errors) == 0 && len(errorResponse.Validations) == 0 {
+			errorResponse.Errors = append(errorResponse.Errors, "unexpected response of create request")
+		}
+		return nil, errorResponse
+	}
+
+	if r.Password == "" {
+		r.Password = newPassword
+	}
+
+	_, errorResponse = client.SetActive(hash, &models.LinkActivationRequest{Active: false})
+
+	return r, errorResponse
+}
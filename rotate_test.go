@@ -0,0 +1,116 @@
+package tinysrc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestClient_RotatePassword(t *testing.T) {
+	expiration := time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		password          models.Secret
+		deactivateStatus  int
+		wantHash          string
+		wantPassword      bool
+		wantErrors        bool
+		wantDeactivations int
+	}{
+		{
+			name:              "test_given_password",
+			password:          "new-secret",
+			deactivateStatus:  http.StatusOK,
+			wantHash:          "new",
+			wantDeactivations: 1,
+		},
+		{
+			name:              "test_generated_password",
+			deactivateStatus:  http.StatusOK,
+			wantHash:          "new",
+			wantPassword:      true,
+			wantDeactivations: 1,
+		},
+		{
+			name:              "test_deactivation_failed",
+			password:          "new-secret",
+			deactivateStatus:  http.StatusInternalServerError,
+			wantHash:          "new",
+			wantErrors:        true,
+			wantDeactivations: 1,
+		},
+		{
+			name:       "test_missing_link",
+			wantErrors: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created models.LinkRequest
+			deactivations := 0
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/client/url/old":
+					_ = json.NewEncoder(w).Encode(models.LinkUserResponse{Url: "https://test.com", Hash: "old", AuthRequired: 1, Password: "old-secret", Active: 1, ExpirationTime: &expiration})
+				case r.Method == http.MethodPost && r.URL.Path == "/create":
+					body, _ := io.ReadAll(r.Body)
+					_ = json.Unmarshal(body, &created)
+					_ = json.NewEncoder(w).Encode(models.LinkResponse{Url: ShortUrl("new"), AuthRequired: 1})
+				case r.Method == http.MethodPatch && r.URL.Path == "/client/old":
+					deactivations++
+					w.WriteHeader(tt.deactivateStatus)
+					_, _ = io.WriteString(w, `{"status": true}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = io.WriteString(w, `{"errors": ["not found"]}`)
+				}
+			}))
+			defer ts.Close()
+
+			testClient, _ := NewClient(context.Background(), "test", nil)
+			testClient.baseURL = &url.URL{Path: ts.URL}
+
+			hash := "old"
+			if tt.name == "test_missing_link" {
+				hash = "missing"
+			}
+
+			r, errorResponse := testClient.RotatePassword(hash, tt.password)
+			if (len(errorResponse.Errors) > 0) != tt.wantErrors {
+				t.Errorf("RotatePassword() errorResponse = %v, wantErrors %v", errorResponse, tt.wantErrors)
+			}
+			if deactivations != tt.wantDeactivations {
+				t.Errorf("RotatePassword() deactivations = %d, want %d", deactivations, tt.wantDeactivations)
+			}
+
+			if tt.wantHash == "" {
+				if r != nil {
+					t.Errorf("RotatePassword() r = %v, want nil", r)
+				}
+				return
+			}
+
+			if r == nil || HashFromUrl(r.Url) != tt.wantHash {
+				t.Fatalf("RotatePassword() r = %v, want hash %s", r, tt.wantHash)
+			}
+			if created.Url != "https://test.com" || created.AuthRequired != 1 || created.ExpirationTime != "2030-01-02 03:04" {
+				t.Errorf("RotatePassword() created = %+v", created)
+			}
+			if created.Password == "" || created.Password == "old-secret" || r.Password != created.Password {
+				t.Errorf("RotatePassword() password = %q, created %q", r.Password.Reveal(), created.Password.Reveal())
+			}
+			if !tt.wantPassword && created.Password != tt.password {
+				t.Errorf("RotatePassword() password = %q, want %q", created.Password.Reveal(), tt.password.Reveal())
+			}
+		})
+	}
+}
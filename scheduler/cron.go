@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parsed cron expression "minute hour day-of-month month day-of-week".
+// Fields support *, numbers, ranges (1-5), steps (*/15, 1-10/2) and lists (1,3,5).
+// Day of week is 0-6 starting on Sunday, 7 is Sunday too.
+type Cron struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	anyDom  bool
+	anyDow  bool
+	literal string
+}
+
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Parse cron expression
+func ParseCron(expression string) (*Cron, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("scheduler: cron %q: want 5 fields, got %d", expression, len(fields))
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, e := parseCronField(field, cronBounds[i][0], cronBounds[i][1])
+		if e != nil {
+			return nil, fmt.Errorf("scheduler: cron %q: %w", expression, e)
+		}
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &Cron{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		anyDom:  fields[2] == "*",
+		anyDow:  fields[4] == "*",
+		literal: expression,
+	}, nil
+}
+
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, e := strconv.Atoi(part[i+1:])
+			if e != nil || n <= 0 {
+				return 0, fmt.Errorf("wrong step %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		from, to := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, e1 := strconv.Atoi(bounds[0])
+			b, e2 := strconv.Atoi(bounds[1])
+			if e1 != nil || e2 != nil || a > b {
+				return 0, fmt.Errorf("wrong range %q", part)
+			}
+			from, to = a, b
+		default:
+			n, e := strconv.Atoi(part)
+			if e != nil {
+				return 0, fmt.Errorf("wrong value %q", part)
+			}
+			from, to = n, n
+			if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+
+		for n := from; n <= to; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+
+	return set, nil
+}
+
+// Check if cron fires at minute of t, t is used in its own location
+func (cron *Cron) Matches(t time.Time) bool {
+	if cron.minute&(1<<uint(t.Minute())) == 0 || cron.hour&(1<<uint(t.Hour())) == 0 || cron.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := cron.dom&(1<<uint(t.Day())) != 0
+	dowMatch := cron.dow&(1<<uint(t.Weekday())) != 0
+
+	// Classic cron: when both days are restricted, either of them matches
+	if !cron.anyDom && !cron.anyDow {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
+
+// Latest minute in (t-within, t] when cron fires, zero time if none
+func (cron *Cron) Prev(t time.Time, within time.Duration) time.Time {
+	current := t.Truncate(time.Minute)
+	limit := t.Add(-within)
+
+	for ; current.After(limit); current = current.Add(-time.Minute) {
+		if cron.Matches(current) {
+			return current
+		}
+	}
+
+	return time.Time{}
+}
+
+func (cron *Cron) String() string {
+	return cron.literal
+}
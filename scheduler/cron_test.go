@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{name: "test_every_minute", expression: "* * * * *"},
+		{name: "test_lists_ranges_steps", expression: "0,30 9-17/2 1-15 */3 1-5"},
+		{name: "test_sunday_seven", expression: "0 0 * * 7"},
+		{name: "test_fields_count", expression: "* * * *", wantErr: true},
+		{name: "test_out_of_range", expression: "60 * * * *", wantErr: true},
+		{name: "test_wrong_range", expression: "* 5-1 * * *", wantErr: true},
+		{name: "test_wrong_step", expression: "*/0 * * * *", wantErr: true},
+		{name: "test_wrong_value", expression: "a * * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expression); (err != nil) != tt.wantErr {
+				t.Errorf("ParseCron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCron_Matches(t *testing.T) {
+	// 2022-01-03 is Monday
+	monday := time.Date(2022, 1, 3, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		expression string
+		time       time.Time
+		want       bool
+	}{
+		{name: "test_exact", expression: "30 9 * * *", time: monday, want: true},
+		{name: "test_other_minute", expression: "31 9 * * *", time: monday, want: false},
+		{name: "test_weekdays", expression: "30 9 * * 1-5", time: monday, want: true},
+		{name: "test_weekend", expression: "30 9 * * 0,6", time: monday, want: false},
+		{name: "test_sunday_seven", expression: "30 9 * * 7", time: monday.AddDate(0, 0, -1), want: true},
+		{name: "test_step", expression: "*/15 */3 * * *", time: monday, want: true},
+		{name: "test_dom_or_dow", expression: "30 9 15 * 1", time: monday, want: true},
+		{name: "test_dom_and_month", expression: "30 9 3 2 *", time: monday, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, _ := ParseCron(tt.expression)
+			if got := cron.Matches(tt.time); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCron_Prev(t *testing.T) {
+	now := time.Date(2022, 1, 3, 9, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		expression string
+		within     time.Duration
+		want       time.Time
+	}{
+		{name: "test_current_minute", expression: "30 9 * * *", within: time.Minute, want: time.Date(2022, 1, 3, 9, 30, 0, 0, time.UTC)},
+		{name: "test_inside", expression: "0 9 * * *", within: time.Hour, want: time.Date(2022, 1, 3, 9, 0, 0, 0, time.UTC)},
+		{name: "test_outside", expression: "0 9 * * *", within: 30 * time.Minute},
+		{name: "test_previous_day", expression: "0 22 * * *", within: 12 * time.Hour, want: time.Date(2022, 1, 2, 22, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, _ := ParseCron(tt.expression)
+			if got := cron.Prev(now, tt.within); !got.Equal(tt.want) {
+				t.Errorf("Prev() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
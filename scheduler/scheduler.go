@@ -0,0 +1,293 @@
+// Package scheduler activates and deactivates TinySRC links according to
+// activation windows and recurring blackouts.
+//
+// Rules and applied states are persisted to a JSON file, so after restart
+// transitions which happened while scheduler was stopped are applied once
+// and already applied ones are not repeated.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DEFAULT_INTERVAL = time.Minute
+
+// Duration encoded as "1h30m" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if e := json.Unmarshal(data, &value); e != nil {
+		return e
+	}
+
+	parsed, e := time.ParseDuration(value)
+	if e != nil {
+		return e
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Recurring blackout, link is inactive for Duration every time Cron fires
+type Window struct {
+	Cron     string   `json:"cron"`
+	Duration Duration `json:"duration"`
+}
+
+// Activation rule of one link
+type Rule struct {
+	Hash string `json:"hash"`
+	// Link is inactive before Start, optional
+	Start *time.Time `json:"start,omitempty"`
+	// Link is inactive since End, optional
+	End *time.Time `json:"end,omitempty"`
+	// IANA time zone of blackout crons, UTC when empty
+	Location  string   `json:"location,omitempty"`
+	Blackouts []Window `json:"blackouts,omitempty"`
+}
+
+// State applied to a link by scheduler
+type Applied struct {
+	Active bool      `json:"active"`
+	At     time.Time `json:"at"`
+}
+
+// Transition applied by Tick
+type Transition struct {
+	Hash   string
+	Active bool
+	At     time.Time
+}
+
+type state struct {
+	Rules   map[string]*Rule    `json:"rules"`
+	Applied map[string]*Applied `json:"applied"`
+}
+
+type Scheduler struct {
+	Client *tinysrc.Client
+	// Tick interval of Run, DEFAULT_INTERVAL when zero
+	Interval time.Duration
+	// Called for every failed tick of Run, optional
+	OnError func(e error)
+
+	path  string
+	mu    sync.Mutex
+	state state
+}
+
+// Constructor of Scheduler, state is loaded from path if file exists
+func New(client *tinysrc.Client, path string) (*Scheduler, error) {
+	scheduler := &Scheduler{
+		Client:   client,
+		Interval: DEFAULT_INTERVAL,
+		path:     path,
+		state: state{
+			Rules:   make(map[string]*Rule),
+			Applied: make(map[string]*Applied),
+		},
+	}
+
+	data, e := os.ReadFile(path)
+	if errors.Is(e, os.ErrNotExist) {
+		return scheduler, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	if e = json.Unmarshal(data, &scheduler.state); e != nil {
+		return nil, fmt.Errorf("scheduler: %s: %w", path, e)
+	}
+
+	if scheduler.state.Rules == nil {
+		scheduler.state.Rules = make(map[string]*Rule)
+	}
+	if scheduler.state.Applied == nil {
+		scheduler.state.Applied = make(map[string]*Applied)
+	}
+
+	return scheduler, nil
+}
+
+// Add or replace rule of rule.Hash
+func (scheduler *Scheduler) SetRule(rule Rule) error {
+	if rule.Hash == "" {
+		return errors.New("scheduler: rule hash is empty")
+	}
+
+	if _, e := rule.Active(time.Now()); e != nil {
+		return e
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	scheduler.state.Rules[rule.Hash] = &rule
+	return scheduler.save()
+}
+
+// Remove rule, link keeps its current state
+func (scheduler *Scheduler) RemoveRule(hash string) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	delete(scheduler.state.Rules, hash)
+	delete(scheduler.state.Applied, hash)
+	return scheduler.save()
+}
+
+// Rules ordered by hash
+func (scheduler *Scheduler) Rules() []Rule {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	rules := make([]Rule, 0, len(scheduler.state.Rules))
+	for _, rule := range scheduler.state.Rules {
+		rules = append(rules, *rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Hash < rules[j].Hash })
+	return rules
+}
+
+// Apply states wanted at now which differ from already applied ones
+func (scheduler *Scheduler) Tick(now time.Time) ([]Transition, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	var transitions []Transition
+	var errs []error
+
+	hashes := make([]string, 0, len(scheduler.state.Rules))
+	for hash := range scheduler.state.Rules {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		active, e := scheduler.state.Rules[hash].Active(now)
+		if e != nil {
+			errs = append(errs, e)
+			continue
+		}
+
+		if applied, ok := scheduler.state.Applied[hash]; ok && applied.Active == active {
+			continue
+		}
+
+		_, errorResponse := scheduler.Client.SetActive(hash, &models.LinkActivationRequest{Active: active})
+		if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			errs = append(errs, fmt.Errorf("scheduler: %s: %v %v", hash, errorResponse.Errors, errorResponse.Validations))
+			continue
+		}
+
+		scheduler.state.Applied[hash] = &Applied{Active: active, At: now}
+		transitions = append(transitions, Transition{Hash: hash, Active: active, At: now})
+
+		// Saved after every transition, so crash does not repeat applied ones
+		if e = scheduler.save(); e != nil {
+			errs = append(errs, e)
+		}
+	}
+
+	return transitions, errors.Join(errs...)
+}
+
+// Tick every Interval until ctx is done
+func (scheduler *Scheduler) Run(ctx context.Context) error {
+	interval := scheduler.Interval
+	if interval <= 0 {
+		interval = DEFAULT_INTERVAL
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, e := scheduler.Tick(time.Now()); e != nil && scheduler.OnError != nil {
+			scheduler.OnError(e)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Write state atomically
+func (scheduler *Scheduler) save() error {
+	data, e := json.MarshalIndent(&scheduler.state, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	tmp, e := os.CreateTemp(filepath.Dir(scheduler.path), filepath.Base(scheduler.path)+".*")
+	if e != nil {
+		return e
+	}
+
+	if _, e = tmp.Write(data); e != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return e
+	}
+
+	if e = tmp.Close(); e != nil {
+		_ = os.Remove(tmp.Name())
+		return e
+	}
+
+	return os.Rename(tmp.Name(), scheduler.path)
+}
+
+// Check if link should be active at t
+func (rule *Rule) Active(t time.Time) (bool, error) {
+	loc := time.UTC
+	if rule.Location != "" {
+		var e error
+		if loc, e = time.LoadLocation(rule.Location); e != nil {
+			return false, fmt.Errorf("scheduler: %s: %w", rule.Hash, e)
+		}
+	}
+
+	if rule.Start != nil && t.Before(*rule.Start) {
+		return false, nil
+	}
+
+	if rule.End != nil && !t.Before(*rule.End) {
+		return false, nil
+	}
+
+	local := t.In(loc)
+	for _, window := range rule.Blackouts {
+		cron, e := ParseCron(window.Cron)
+		if e != nil {
+			return false, e
+		}
+
+		if !cron.Prev(local, time.Duration(window.Duration)).IsZero() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
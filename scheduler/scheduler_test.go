@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestRule_Active(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	rule := Rule{
+		Hash:     "test",
+		Start:    &start,
+		End:      &end,
+		Location: "America/New_York",
+		Blackouts: []Window{
+			// every night 22:00-06:00 New York time
+			{Cron: "0 22 * * *", Duration: Duration(8 * time.Hour)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		time    time.Time
+		want    bool
+		wantErr bool
+	}{
+		{name: "test_before_start", rule: rule, time: start.Add(-time.Minute), want: false},
+		{name: "test_after_end", rule: rule, time: end, want: false},
+		// 15:00 UTC is 10:00 in New York
+		{name: "test_active", rule: rule, time: time.Date(2022, 1, 10, 15, 0, 0, 0, time.UTC), want: true},
+		// 04:00 UTC is 23:00 in New York
+		{name: "test_blackout", rule: rule, time: time.Date(2022, 1, 10, 4, 0, 0, 0, time.UTC), want: false},
+		// 11:00 UTC is 06:00 in New York, blackout is over
+		{name: "test_blackout_over", rule: rule, time: time.Date(2022, 1, 10, 11, 0, 0, 0, time.UTC), want: true},
+		{name: "test_wrong_location", rule: Rule{Hash: "test", Location: "Nowhere/City"}, time: start, wantErr: true},
+		{name: "test_wrong_cron", rule: Rule{Hash: "test", Blackouts: []Window{{Cron: "bad"}}}, time: start, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Active(tt.time)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Active() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	var calls []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := models.LinkActivationRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		hash := strings.TrimPrefix(r.URL.Path, "/v1/client/")
+		if hash == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+			return
+		}
+
+		if request.Active {
+			calls = append(calls, "+"+hash)
+		} else {
+			calls = append(calls, "-"+hash)
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	path := filepath.Join(t.TempDir(), "scheduler.json")
+
+	scheduler, err := New(client, path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	start := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	_ = scheduler.SetRule(Rule{Hash: "campaign", Start: &start, End: &end})
+	_ = scheduler.SetRule(Rule{Hash: "lunch", Blackouts: []Window{{Cron: "0 13 * * *", Duration: Duration(time.Hour)}}})
+
+	if err = scheduler.SetRule(Rule{Hash: "bad", Blackouts: []Window{{Cron: "* *"}}}); err == nil {
+		t.Errorf("SetRule() error = nil, want error")
+	}
+
+	tests := []struct {
+		name      string
+		restart   bool
+		time      time.Time
+		wantCalls []string
+	}{
+		{name: "test_initial", time: start.Add(-time.Hour), wantCalls: []string{"-campaign", "+lunch"}},
+		{name: "test_no_changes", time: start.Add(-time.Minute), wantCalls: nil},
+		{name: "test_start", time: start, wantCalls: []string{"+campaign"}},
+		{name: "test_blackout", time: start.Add(time.Hour), wantCalls: []string{"-lunch"}},
+		{name: "test_restart_no_duplicates", restart: true, time: start.Add(time.Hour + time.Minute), wantCalls: nil},
+		{name: "test_restart_missed", restart: true, time: start.Add(3 * time.Hour), wantCalls: []string{"-campaign", "+lunch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+
+			if tt.restart {
+				if scheduler, err = New(client, path); err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+			}
+
+			transitions, err := scheduler.Tick(tt.time)
+			if err != nil {
+				t.Fatalf("Tick() error = %v", err)
+			}
+			if len(transitions) != len(tt.wantCalls) {
+				t.Errorf("Tick() transitions = %v, want %v", transitions, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("Tick() calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	_ = scheduler.SetRule(Rule{Hash: "missing"})
+	if _, err = scheduler.Tick(start); err == nil {
+		t.Errorf("Tick() error = nil, want error")
+	}
+
+	_ = scheduler.RemoveRule("missing")
+	if got := len(scheduler.Rules()); got != 2 {
+		t.Errorf("Rules() = %v, want 2 rules", got)
+	}
+}
@@ -2,21 +2,16 @@ package tinysrc
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/dmitrypro77/tinysrc-api-sdk/models"
 	"net/http"
 	"net/url"
+	"strconv"
 )
 
 // Get List My Urls
 func (client *Client) GetStatByHash(hash string, params models.StatRequest) (r *models.StatPaginatedResponse, errorResponse models.ErrorResponse) {
-	values := url.Values{}
-
-	values.Add("limit", string(rune(params.Limit)))
-	values.Add("page", string(rune(params.Page)))
-	values.Add("date-start", params.DateStart.Format(DATE_FORMAT))
-	values.Add("date-end", params.DateEnd.Format(DATE_FORMAT))
-
-	resp, e := client.sendRequest(http.MethodGet, "/client/stat/"+hash+"?"+values.Encode(), nil)
+	resp, e := client.sendRequest(http.MethodGet, "/client/stat/"+hash+"?"+client.statValues(params).Encode(), nil)
 	if e != nil {
 		errorResponse.Errors = append(errorResponse.Errors, e.Error())
 		return nil, errorResponse
@@ -37,5 +32,133 @@ func (client *Client) GetStatByHash(hash string, params models.StatRequest) (r *
 		return nil, errorResponse
 	}
 
+	for _, s := range stat.Data {
+		client.localizeStat(s)
+	}
+
 	return &stat, errorResponse
 }
+
+// Get Statistic By Hash without buffering the whole page.
+// onTotal is called once total is decoded, onStat is called for every record,
+// returning an error from onStat stops decoding. Both callbacks are optional.
+func (client *Client) GetStatByHashStream(hash string, params models.StatRequest, onTotal func(total int64), onStat func(stat *models.StatResponse) error) (errorResponse models.ErrorResponse) {
+	resp, e := client.sendRequest(http.MethodGet, "/client/stat/"+hash+"?"+client.statValues(params).Encode(), nil)
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+		return errorResponse
+	}
+
+	defer resp.Body.Close()
+
+	if !client.isSuccess(resp.StatusCode) {
+		apiErrors := client.parseErrorResponse(resp)
+
+		return *apiErrors
+	}
+
+	e = decodeStatStream(json.NewDecoder(resp.Body), onTotal, func(stat *models.StatResponse) error {
+		client.localizeStat(stat)
+		if onStat != nil {
+			return onStat(stat)
+		}
+		return nil
+	})
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+		return errorResponse
+	}
+
+	return errorResponse
+}
+
+// Query parameters of statistic request
+func (client *Client) statValues(params models.StatRequest) url.Values {
+	values := url.Values{}
+
+	values.Add("limit", strconv.FormatInt(params.Limit, 10))
+	values.Add("page", strconv.FormatInt(params.Page, 10))
+	values.Add("date-start", client.FormatTime(params.DateStart))
+	values.Add("date-end", client.FormatTime(params.DateEnd))
+
+	return values
+}
+
+// Walk through StatPaginatedResponse object token by token
+func decodeStatStream(decoder *json.Decoder, onTotal func(total int64), onStat func(stat *models.StatResponse) error) error {
+	if e := expectDelim(decoder, '{'); e != nil {
+		return e
+	}
+
+	for decoder.More() {
+		token, e := decoder.Token()
+		if e != nil {
+			return e
+		}
+
+		switch token {
+		case "total":
+			var total int64
+			if e = decoder.Decode(&total); e != nil {
+				return e
+			}
+			if onTotal != nil {
+				onTotal(total)
+			}
+		case "data":
+			if e = decodeStatArray(decoder, onStat); e != nil {
+				return e
+			}
+		default:
+			var skip json.RawMessage
+			if e = decoder.Decode(&skip); e != nil {
+				return e
+			}
+		}
+	}
+
+	return expectDelim(decoder, '}')
+}
+
+func decodeStatArray(decoder *json.Decoder, onStat func(stat *models.StatResponse) error) error {
+	token, e := decoder.Token()
+	if e != nil {
+		return e
+	}
+
+	if token == nil {
+		return nil
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("tinysrc: unexpected token %v, want [", token)
+	}
+
+	for decoder.More() {
+		stat := &models.StatResponse{}
+		if e = decoder.Decode(stat); e != nil {
+			return e
+		}
+
+		if onStat != nil {
+			if e = onStat(stat); e != nil {
+				return e
+			}
+		}
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, want json.Delim) error {
+	token, e := decoder.Token()
+	if e != nil {
+		return e
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("tinysrc: unexpected token %v, want %v", token, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package tinysrc
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Options of chunked statistic request
+type StatChunkOptions struct {
+	// Length of every window, STAT_WINDOW_DAY when zero
+	Window time.Duration
+	// Max windows requested at the same time, STAT_CHUNK_CONCURRENCY when zero
+	Concurrency int
+	// Page size used inside every window, STAT_CHUNK_LIMIT when zero
+	Limit int64
+}
+
+type statChunk struct {
+	start time.Time
+	end   time.Time
+	stats []*models.StatResponse
+	err   *models.ErrorResponse
+}
+
+// Get Statistic By Hash splitting [DateStart, DateEnd] into windows.
+// Windows are requested concurrently, every window is read page by page.
+// Rows are ordered by Created. Windows are half-open [start, end) on minute
+// boundaries, rows of the boundary minute returned by both neighbour windows
+// (DATE_FORMAT has minute precision) are kept by the later one only.
+func (client *Client) GetStatByHashChunked(hash string, params models.StatRequest, options StatChunkOptions) (r *models.StatPaginatedResponse, errorResponse models.ErrorResponse) {
+	if options.Window <= 0 {
+		options.Window = STAT_WINDOW_DAY
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = STAT_CHUNK_CONCURRENCY
+	}
+	if options.Limit <= 0 {
+		options.Limit = STAT_CHUNK_LIMIT
+	}
+
+	if params.DateEnd.Before(params.DateStart) {
+		errorResponse.Errors = append(errorResponse.Errors, "DateEnd is before DateStart")
+		return nil, errorResponse
+	}
+
+	chunks := splitStatRange(params.DateStart, params.DateEnd, options.Window)
+
+	var wg sync.WaitGroup
+	pool := make(chan struct{}, options.Concurrency)
+
+	for _, chunk := range chunks {
+		wg.Add(1)
+		pool <- struct{}{}
+
+		go func(chunk *statChunk) {
+			defer wg.Done()
+			defer func() { <-pool }()
+
+			chunk.stats, chunk.err = client.getStatWindow(hash, chunk.start, chunk.end, options.Limit)
+		}(chunk)
+	}
+
+	wg.Wait()
+
+	var stats []*models.StatResponse
+	for i, chunk := range chunks {
+		if chunk.err != nil {
+			return nil, *chunk.err
+		}
+
+		for _, stat := range chunk.stats {
+			// The first window keeps rows before DateStart of the same minute, the last one rows up to DateEnd
+			if (i > 0 && stat.Created.Before(chunk.start)) || (i < len(chunks)-1 && !stat.Created.Before(chunk.end)) {
+				continue
+			}
+			stats = append(stats, stat)
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Created.Before(stats[j].Created)
+	})
+
+	return &models.StatPaginatedResponse{Data: stats, Total: int64(len(stats))}, errorResponse
+}
+
+// Read all pages of one window
+func (client *Client) getStatWindow(hash string, start time.Time, end time.Time, limit int64) ([]*models.StatResponse, *models.ErrorResponse) {
+	var stats []*models.StatResponse
+
+	for page := int64(1); ; page++ {
+		stat, errorResponse := client.GetStatByHash(hash, models.StatRequest{
+			Limit:     limit,
+			Page:      page,
+			DateStart: start,
+			DateEnd:   end,
+		})
+
+		if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			return nil, &errorResponse
+		}
+
+		stats = append(stats, stat.Data...)
+
+		if len(stat.Data) == 0 || int64(len(stats)) >= stat.Total {
+			return stats, nil
+		}
+	}
+}
+
+// Split range into windows, boundaries between windows are truncated to minute.
+// Last window is shorter if range is not divisible by window.
+func splitStatRange(start time.Time, end time.Time, window time.Duration) []*statChunk {
+	if window < time.Minute {
+		window = time.Minute
+	}
+
+	var chunks []*statChunk
+
+	for from := start; ; {
+		to := from.Add(window).Truncate(time.Minute)
+		if !to.Before(end) {
+			return append(chunks, &statChunk{start: from, end: end})
+		}
+
+		chunks = append(chunks, &statChunk{start: from, end: to})
+		from = to
+	}
+}
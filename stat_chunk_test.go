@@ -0,0 +1,235 @@
+package tinysrc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_GetStatByHashChunked(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// one row per hour, rows on window boundaries are returned by both windows
+	var rows []*models.StatResponse
+	for i := 0; i <= 48; i++ {
+		rows = append(rows, &models.StatResponse{Ip: strconv.Itoa(i), Created: start.Add(time.Duration(i) * time.Hour)})
+	}
+
+	var mu sync.Mutex
+	requests := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		query := r.URL.Query()
+		from, _ := time.Parse(DATE_FORMAT, query.Get("date-start"))
+		to, _ := time.Parse(DATE_FORMAT, query.Get("date-end"))
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		page, _ := strconv.Atoi(query.Get("page"))
+
+		var window []*models.StatResponse
+		for i := len(rows) - 1; i >= 0; i-- {
+			if !rows[i].Created.Before(from) && !rows[i].Created.After(to) {
+				window = append(window, rows[i])
+			}
+		}
+
+		resp := models.StatPaginatedResponse{Total: int64(len(window))}
+		if offset := (page - 1) * limit; offset < len(window) {
+			end := offset + limit
+			if end > len(window) {
+				end = len(window)
+			}
+			resp.Data = window[offset:end]
+		}
+
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+
+	defer ts.Close()
+
+	testClient, _ := NewClient(context.Background(), "test", nil)
+	testClient.baseURL = &url.URL{
+		Path: ts.URL,
+	}
+
+	tests := []struct {
+		name         string
+		params       models.StatRequest
+		options      StatChunkOptions
+		wantTotal    int64
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "test_day_windows",
+			params:       models.StatRequest{DateStart: start, DateEnd: start.Add(48 * time.Hour)},
+			options:      StatChunkOptions{Window: STAT_WINDOW_DAY, Concurrency: 2, Limit: 10},
+			wantTotal:    49,
+			wantRequests: 6,
+		},
+		{
+			name:         "test_hour_windows",
+			params:       models.StatRequest{DateStart: start, DateEnd: start.Add(6 * time.Hour)},
+			options:      StatChunkOptions{Window: STAT_WINDOW_HOUR},
+			wantTotal:    7,
+			wantRequests: 6,
+		},
+		{
+			name:    "test_wrong_range",
+			params:  models.StatRequest{DateStart: start.Add(time.Hour), DateEnd: start},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+
+			gotR, gotErrorResponse := testClient.GetStatByHashChunked("test", tt.params, tt.options)
+			if (len(gotErrorResponse.Errors) > 0) != tt.wantErr {
+				t.Fatalf("GetStatByHashChunked() gotErrorResponse = %v, wantErr %v", gotErrorResponse, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if gotR.Total != tt.wantTotal || int64(len(gotR.Data)) != tt.wantTotal {
+				t.Errorf("GetStatByHashChunked() total = %v, rows = %v, want %v", gotR.Total, len(gotR.Data), tt.wantTotal)
+			}
+			if !reflect.DeepEqual(gotR.Data, rows[:tt.wantTotal]) {
+				t.Errorf("GetStatByHashChunked() rows are not ordered by Created")
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("GetStatByHashChunked() requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func Test_splitStatRange(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		start  time.Time
+		end    time.Time
+		window time.Duration
+		want   [][2]time.Time
+	}{
+		{
+			name:   "test_exact",
+			end:    start.Add(2 * STAT_WINDOW_WEEK),
+			window: STAT_WINDOW_WEEK,
+			want: [][2]time.Time{
+				{start, start.Add(STAT_WINDOW_WEEK)},
+				{start.Add(STAT_WINDOW_WEEK), start.Add(2 * STAT_WINDOW_WEEK)},
+			},
+		},
+		{
+			name:   "test_remainder",
+			end:    start.Add(36 * time.Hour),
+			window: STAT_WINDOW_DAY,
+			want: [][2]time.Time{
+				{start, start.Add(STAT_WINDOW_DAY)},
+				{start.Add(STAT_WINDOW_DAY), start.Add(36 * time.Hour)},
+			},
+		},
+		{
+			name:   "test_unaligned",
+			start:  start.Add(30 * time.Second),
+			end:    start.Add(36 * time.Hour),
+			window: STAT_WINDOW_DAY,
+			want: [][2]time.Time{
+				{start.Add(30 * time.Second), start.Add(STAT_WINDOW_DAY)},
+				{start.Add(STAT_WINDOW_DAY), start.Add(36 * time.Hour)},
+			},
+		},
+		{
+			name:   "test_empty_range",
+			end:    start,
+			window: STAT_WINDOW_DAY,
+			want:   [][2]time.Time{{start, start}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := tt.start
+			if from.IsZero() {
+				from = start
+			}
+
+			var got [][2]time.Time
+			for _, chunk := range splitStatRange(from, tt.end, tt.window) {
+				got = append(got, [2]time.Time{chunk.start, chunk.end})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetStatByHashChunkedDuplicates(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 30, 0, time.UTC)
+	boundary := time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC)
+
+	// Two real clicks of the same visitor in the same second and clicks of the boundary minute
+	click := models.StatResponse{Ip: "1.1.1.1", Browser: "Chrome", Created: start.Add(20 * time.Minute)}
+	first, second := click, click
+	rows := []*models.StatResponse{
+		{Ip: "0", Created: start.Add(-10 * time.Second)},
+		&first,
+		&second,
+		{Ip: "1", Created: boundary.Add(-time.Second)},
+		{Ip: "2", Created: boundary},
+		{Ip: "3", Created: boundary.Add(30 * time.Second)},
+		{Ip: "4", Created: boundary.Add(90 * time.Minute)},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		from, _ := time.Parse(DATE_FORMAT, query.Get("date-start"))
+		to, _ := time.Parse(DATE_FORMAT, query.Get("date-end"))
+
+		// date-end includes the whole minute
+		resp := models.StatPaginatedResponse{}
+		for _, row := range rows {
+			if !row.Created.Before(from) && row.Created.Before(to.Add(time.Minute)) {
+				resp.Data = append(resp.Data, row)
+			}
+		}
+		resp.Total = int64(len(resp.Data))
+
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+
+	defer ts.Close()
+
+	testClient, _ := NewClient(context.Background(), "test", nil)
+	testClient.baseURL = &url.URL{
+		Path: ts.URL,
+	}
+
+	params := models.StatRequest{DateStart: start, DateEnd: boundary.Add(2 * time.Hour)}
+	gotR, gotErrorResponse := testClient.GetStatByHashChunked("test", params, StatChunkOptions{Window: STAT_WINDOW_HOUR})
+	if len(gotErrorResponse.Errors) > 0 {
+		t.Fatalf("GetStatByHashChunked() gotErrorResponse = %v", gotErrorResponse)
+	}
+
+	if !reflect.DeepEqual(gotR.Data, rows) {
+		var got []string
+		for _, stat := range gotR.Data {
+			got = append(got, stat.Ip+" "+stat.Created.Format(time.RFC3339))
+		}
+		t.Errorf("GetStatByHashChunked() rows = %v", got)
+	}
+}
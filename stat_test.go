@@ -82,3 +82,123 @@ func TestClient_GetStatByHash(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetStatByHashStream(t *testing.T) {
+	type args struct {
+		body   string
+		status int
+		stop   bool
+	}
+
+	created := time.Date(2022, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		args      args
+		wantTotal int64
+		wantStats []*models.StatResponse
+		wantErr   bool
+	}{
+		{
+			name: "test_success",
+			args: args{
+				body:   `{"data":[{"ip":"8.8.8.8","created":"2022-01-02T03:04:00Z"},{"ip":"1.1.1.1","bot":true}],"extra":{"a":[1]},"total":2}`,
+				status: http.StatusOK,
+			},
+			wantTotal: 2,
+			wantStats: []*models.StatResponse{
+				{Ip: "8.8.8.8", Created: created},
+				{Ip: "1.1.1.1", Bot: true},
+			},
+		},
+		{
+			name: "test_null_data",
+			args: args{
+				body:   `{"total":0,"data":null}`,
+				status: http.StatusOK,
+			},
+			wantTotal: 0,
+		},
+		{
+			name: "test_stop",
+			args: args{
+				body:   `{"total":2,"data":[{"ip":"8.8.8.8"},{"ip":"1.1.1.1"}]}`,
+				status: http.StatusOK,
+				stop:   true,
+			},
+			wantTotal: 2,
+			wantStats: []*models.StatResponse{{Ip: "8.8.8.8"}},
+			wantErr:   true,
+		},
+		{
+			name: "test_broken_json",
+			args: args{
+				body:   `{"total":2,"data":[{"ip":`,
+				status: http.StatusOK,
+			},
+			wantTotal: 2,
+			wantErr:   true,
+		},
+		{
+			name: "test_api_error",
+			args: args{
+				body:   `{"errors":["Not Found"]}`,
+				status: http.StatusNotFound,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.args.status)
+				_, _ = io.WriteString(w, tt.args.body)
+			}))
+			defer ts.Close()
+
+			testClient, _ := NewClient(context.Background(), "test", nil)
+			testClient.baseURL = &url.URL{
+				Path: ts.URL,
+			}
+
+			var gotTotal int64
+			var gotStats []*models.StatResponse
+
+			gotErrorResponse := testClient.GetStatByHashStream("test", models.StatRequest{Limit: 10, Page: 1}, func(total int64) {
+				gotTotal = total
+			}, func(stat *models.StatResponse) error {
+				gotStats = append(gotStats, stat)
+				if tt.args.stop {
+					return io.EOF
+				}
+				return nil
+			})
+
+			if (len(gotErrorResponse.Errors) > 0) != tt.wantErr {
+				t.Errorf("GetStatByHashStream() gotErrorResponse = %v, wantErr %v", gotErrorResponse, tt.wantErr)
+			}
+			if gotTotal != tt.wantTotal {
+				t.Errorf("GetStatByHashStream() gotTotal = %v, want %v", gotTotal, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(gotStats, tt.wantStats) {
+				t.Errorf("GetStatByHashStream() gotStats = %v, want %v", gotStats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestClient_statValues(t *testing.T) {
+	testClient, _ := NewClient(context.Background(), "test", nil)
+
+	got := testClient.statValues(models.StatRequest{
+		Limit:     100,
+		Page:      2,
+		DateStart: time.Date(2022, 1, 1, 10, 30, 0, 0, time.UTC),
+		DateEnd:   time.Date(2022, 1, 2, 10, 30, 0, 0, time.UTC),
+	}).Encode()
+
+	want := "date-end=2022-01-02+10%3A30&date-start=2022-01-01+10%3A30&limit=100&page=2"
+	if got != want {
+		t.Errorf("statValues() = %v, want %v", got, want)
+	}
+}
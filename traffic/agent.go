@@ -0,0 +1,213 @@
+package traffic
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"strconv"
+	"strings"
+)
+
+type DeviceType string
+
+const (
+	DeviceDesktop DeviceType = "desktop"
+	DeviceMobile  DeviceType = "mobile"
+	DeviceTablet  DeviceType = "tablet"
+	DeviceBot     DeviceType = "bot"
+	DeviceUnknown DeviceType = "unknown"
+)
+
+const UNKNOWN = "Unknown"
+
+// Normalized browser and device of click
+type Agent struct {
+	Browser string `json:"browser"`
+	// Major browser version, 0 when unknown
+	Version int        `json:"version,omitempty"`
+	Os      string     `json:"os"`
+	Device  DeviceType `json:"device"`
+}
+
+// Browser families by lower-cased names and aliases reported for them
+var browserFamilies = map[string]string{
+	"chrome":            "Chrome",
+	"google chrome":     "Chrome",
+	"chrome mobile":     "Chrome",
+	"chrome mobile ios": "Chrome",
+	"crios":             "Chrome",
+	"chromium":          "Chromium",
+	"headlesschrome":    "Chrome Headless",
+	"headless chrome":   "Chrome Headless",
+	"firefox":           "Firefox",
+	"mozilla firefox":   "Firefox",
+	"firefox mobile":    "Firefox",
+	"firefox ios":       "Firefox",
+	"fxios":             "Firefox",
+	"safari":            "Safari",
+	"mobile safari":     "Safari",
+	"webkit":            "Safari",
+	"edge":              "Edge",
+	"edg":               "Edge",
+	"edga":              "Edge",
+	"edgios":            "Edge",
+	"microsoft edge":    "Edge",
+	"ie":                "Internet Explorer",
+	"msie":              "Internet Explorer",
+	"internet explorer": "Internet Explorer",
+	"opera":             "Opera",
+	"opr":               "Opera",
+	"opera mini":        "Opera",
+	"opera mobile":      "Opera",
+	"samsung internet":  "Samsung Internet",
+	"samsungbrowser":    "Samsung Internet",
+	"yandex":            "Yandex Browser",
+	"yandex browser":    "Yandex Browser",
+	"yabrowser":         "Yandex Browser",
+	"uc browser":        "UC Browser",
+	"ucbrowser":         "UC Browser",
+	"brave":             "Brave",
+	"vivaldi":           "Vivaldi",
+	"duckduckgo":        "DuckDuckGo",
+	"facebook":          "Facebook In-App",
+	"fban":              "Facebook In-App",
+	"instagram":         "Instagram In-App",
+	"android webview":   "Android WebView",
+	"chrome webview":    "Android WebView",
+	"miui browser":      "MIUI Browser",
+	"huawei browser":    "Huawei Browser",
+	"silk":              "Silk",
+	"amazon silk":       "Silk",
+}
+
+// Operating systems by lower-cased name prefixes, longest prefix wins
+var osFamilies = map[string]string{
+	"windows":       "Windows",
+	"win":           "Windows",
+	"mac os":        "macOS",
+	"macos":         "macOS",
+	"os x":          "macOS",
+	"macintosh":     "macOS",
+	"ios":           "iOS",
+	"iphone os":     "iOS",
+	"ipados":        "iOS",
+	"android":       "Android",
+	"linux":         "Linux",
+	"ubuntu":        "Linux",
+	"debian":        "Linux",
+	"fedora":        "Linux",
+	"chrome os":     "ChromeOS",
+	"chromeos":      "ChromeOS",
+	"cros":          "ChromeOS",
+	"freebsd":       "FreeBSD",
+	"harmonyos":     "HarmonyOS",
+	"kaios":         "KaiOS",
+	"tizen":         "Tizen",
+	"windows phone": "Windows Phone",
+}
+
+var tabletPlatforms = []string{"ipad", "tablet", "kindle", "playbook", "nexus 7", "nexus 9", "nexus 10", "sm-t"}
+var mobilePlatforms = []string{"iphone", "ipod", "android", "mobile", "phone", "blackberry", "opera mini"}
+var desktopOs = map[string]bool{"Windows": true, "macOS": true, "Linux": true, "ChromeOS": true, "FreeBSD": true}
+
+// Normalize browser family, major version, operating system and device type of stat row
+func ParseAgent(stat *models.StatResponse) Agent {
+	agent := Agent{
+		Browser: BrowserFamily(stat.Browser),
+		Version: MajorVersion(stat.BrowserVersion),
+		Os:      OsFamily(stat.Os),
+	}
+
+	if agent.Os == UNKNOWN {
+		agent.Os = OsFamily(stat.Platform)
+	}
+
+	agent.Device = deviceType(stat, agent.Os)
+	return agent
+}
+
+// Canonical browser family, unknown names are trimmed and kept as is
+func BrowserFamily(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || strings.EqualFold(name, "unknown") {
+		return UNKNOWN
+	}
+
+	lower := strings.ToLower(name)
+	if family, ok := browserFamilies[lower]; ok {
+		return family
+	}
+
+	// "Chrome 99", "Firefox/98.0"
+	if i := strings.IndexAny(lower, " /"); i > 0 {
+		if family, ok := browserFamilies[lower[:i]]; ok && MajorVersion(lower[i+1:]) > 0 {
+			return family
+		}
+	}
+
+	return name
+}
+
+// Major number of version like "99.0.4844.51", 0 when version does not start with number
+func MajorVersion(version string) int {
+	version = strings.TrimSpace(version)
+
+	end := 0
+	for end < len(version) && version[end] >= '0' && version[end] <= '9' {
+		end++
+	}
+
+	major, _ := strconv.Atoi(version[:end])
+	return major
+}
+
+// Canonical operating system family, unknown names are kept as is
+func OsFamily(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || strings.EqualFold(name, "unknown") {
+		return UNKNOWN
+	}
+
+	lower := strings.ToLower(name)
+	best, family := 0, ""
+	for prefix, value := range osFamilies {
+		if len(prefix) > best && strings.HasPrefix(lower, prefix) {
+			best, family = len(prefix), value
+		}
+	}
+
+	if family != "" {
+		return family
+	}
+
+	return name
+}
+
+func deviceType(stat *models.StatResponse, os string) DeviceType {
+	if stat.Bot {
+		return DeviceBot
+	}
+
+	platform := strings.ToLower(stat.Platform)
+	for _, marker := range tabletPlatforms {
+		if strings.Contains(platform, marker) {
+			return DeviceTablet
+		}
+	}
+
+	if stat.Mobile {
+		return DeviceMobile
+	}
+	for _, marker := range mobilePlatforms {
+		if strings.Contains(platform, marker) {
+			return DeviceMobile
+		}
+	}
+
+	switch {
+	case os == "iOS" || os == "Android" || os == "Windows Phone" || os == "KaiOS":
+		return DeviceMobile
+	case desktopOs[os]:
+		return DeviceDesktop
+	}
+
+	return DeviceUnknown
+}
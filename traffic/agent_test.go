@@ -0,0 +1,63 @@
+package traffic
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"testing"
+)
+
+func TestParseAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		stat models.StatResponse
+		want Agent
+	}{
+		{
+			name: "test_desktop_chrome",
+			stat: models.StatResponse{Browser: "Chrome", BrowserVersion: "99.0.4844.51", Os: "Windows 10", Platform: "Windows"},
+			want: Agent{Browser: "Chrome", Version: 99, Os: "Windows", Device: DeviceDesktop},
+		},
+		{
+			name: "test_mobile_safari",
+			stat: models.StatResponse{Browser: "Mobile Safari", BrowserVersion: "15.4", Os: "iOS", Platform: "iPhone", Mobile: true},
+			want: Agent{Browser: "Safari", Version: 15, Os: "iOS", Device: DeviceMobile},
+		},
+		{
+			name: "test_tablet",
+			stat: models.StatResponse{Browser: "safari", BrowserVersion: "16", Os: "iPadOS", Platform: "iPad", Mobile: true},
+			want: Agent{Browser: "Safari", Version: 16, Os: "iOS", Device: DeviceTablet},
+		},
+		{
+			name: "test_android_without_mobile_flag",
+			stat: models.StatResponse{Browser: "SamsungBrowser", BrowserVersion: "17.0", Os: "Android 12"},
+			want: Agent{Browser: "Samsung Internet", Version: 17, Os: "Android", Device: DeviceMobile},
+		},
+		{
+			name: "test_os_from_platform",
+			stat: models.StatResponse{Browser: "Firefox/98.0", Platform: "Macintosh"},
+			want: Agent{Browser: "Firefox", Os: "macOS", Device: DeviceDesktop},
+		},
+		{
+			name: "test_bot",
+			stat: models.StatResponse{Browser: "HeadlessChrome", BrowserVersion: "100", Os: "Linux", Bot: true},
+			want: Agent{Browser: "Chrome Headless", Version: 100, Os: "Linux", Device: DeviceBot},
+		},
+		{
+			name: "test_unknown",
+			stat: models.StatResponse{Browser: "  Links  Browser ", BrowserVersion: "v2", Os: "unknown"},
+			want: Agent{Browser: "Links Browser", Os: UNKNOWN, Device: DeviceUnknown},
+		},
+		{
+			name: "test_windows_phone",
+			stat: models.StatResponse{Browser: "IE", BrowserVersion: "11.0", Os: "Windows Phone 8.1"},
+			want: Agent{Browser: "Internet Explorer", Version: 11, Os: "Windows Phone", Device: DeviceMobile},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAgent(&tt.stat); got != tt.want {
+				t.Errorf("ParseAgent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
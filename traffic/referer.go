@@ -0,0 +1,240 @@
+package traffic
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+type SourceKind string
+
+const (
+	// No referer
+	SourceDirect SourceKind = "direct"
+	SourceSearch SourceKind = "search"
+	SourceSocial SourceKind = "social"
+	// Any other site
+	SourceReferral SourceKind = "referral"
+	// Mobile application, e.g. android-app://org.telegram.messenger
+	SourceApp SourceKind = "app"
+	// Referer which is not an absolute url
+	SourceUnknown SourceKind = "unknown"
+)
+
+// Traffic source of click parsed from referer
+type Source struct {
+	Kind SourceKind `json:"kind"`
+	Host string     `json:"host,omitempty"`
+	// Registrable domain of host ("news.bbc.co.uk" is "bbc.co.uk"), package name for apps
+	Domain string `json:"domain,omitempty"`
+	// Search engine or social network name
+	Name string `json:"name,omitempty"`
+	// Search terms, most engines do not pass them anymore
+	Terms string `json:"terms,omitempty"`
+}
+
+// Public suffixes with more than one label, single label suffixes (com, de, ...) are implied
+var publicSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true, "me.uk": true, "ltd.uk": true, "plc.uk": true,
+	"com.au": true, "net.au": true, "org.au": true, "edu.au": true, "gov.au": true,
+	"co.nz": true, "org.nz": true, "co.jp": true, "ne.jp": true, "or.jp": true, "ac.jp": true,
+	"co.kr": true, "or.kr": true, "co.in": true, "net.in": true, "org.in": true, "co.za": true, "org.za": true,
+	"com.br": true, "net.br": true, "org.br": true, "com.mx": true, "com.ar": true, "com.co": true,
+	"com.tr": true, "com.cn": true, "net.cn": true, "org.cn": true, "com.hk": true, "com.tw": true, "com.sg": true,
+	"com.ua": true, "org.ua": true, "com.pl": true, "co.il": true, "co.id": true, "com.my": true, "com.ph": true,
+	"com.vn": true, "com.eg": true, "com.sa": true, "com.pk": true, "co.th": true, "msk.ru": true, "spb.ru": true,
+	"github.io": true, "gitlab.io": true, "blogspot.com": true, "herokuapp.com": true, "pages.dev": true,
+	"vercel.app": true, "netlify.app": true, "appspot.com": true, "cloudfront.net": true,
+}
+
+type searchEngine struct {
+	name   string
+	params []string
+}
+
+// Search engines by registrable domain without public suffix ("google" of google.co.uk) or by host
+var searchEngines = map[string]searchEngine{
+	"google":           {name: "Google", params: []string{"q"}},
+	"bing":             {name: "Bing", params: []string{"q"}},
+	"yahoo":            {name: "Yahoo", params: []string{"p", "q"}},
+	"duckduckgo":       {name: "DuckDuckGo", params: []string{"q"}},
+	"yandex":           {name: "Yandex", params: []string{"text"}},
+	"ya":               {name: "Yandex", params: []string{"text"}},
+	"baidu":            {name: "Baidu", params: []string{"wd", "word"}},
+	"ecosia":           {name: "Ecosia", params: []string{"q"}},
+	"ask":              {name: "Ask", params: []string{"q"}},
+	"naver":            {name: "Naver", params: []string{"query"}},
+	"seznam":           {name: "Seznam", params: []string{"q"}},
+	"startpage":        {name: "Startpage", params: []string{"query", "q"}},
+	"qwant":            {name: "Qwant", params: []string{"q"}},
+	"search.brave.com": {name: "Brave Search", params: []string{"q"}},
+	"sogou":            {name: "Sogou", params: []string{"query"}},
+	"so":               {name: "360 Search", params: []string{"q"}},
+	"aol":              {name: "AOL", params: []string{"q", "query"}},
+	"search.aol.com":   {name: "AOL", params: []string{"q", "query"}},
+	"go.mail.ru":       {name: "Mail.ru", params: []string{"q"}},
+}
+
+// Social networks by registrable domain or host
+var socialNetworks = map[string]string{
+	"facebook.com":           "Facebook",
+	"fb.com":                 "Facebook",
+	"fb.me":                  "Facebook",
+	"messenger.com":          "Facebook",
+	"instagram.com":          "Instagram",
+	"twitter.com":            "Twitter",
+	"x.com":                  "Twitter",
+	"t.co":                   "Twitter",
+	"linkedin.com":           "LinkedIn",
+	"lnkd.in":                "LinkedIn",
+	"reddit.com":             "Reddit",
+	"redd.it":                "Reddit",
+	"pinterest.com":          "Pinterest",
+	"pin.it":                 "Pinterest",
+	"youtube.com":            "YouTube",
+	"youtu.be":               "YouTube",
+	"tiktok.com":             "TikTok",
+	"vk.com":                 "VK",
+	"ok.ru":                  "Odnoklassniki",
+	"t.me":                   "Telegram",
+	"telegram.org":           "Telegram",
+	"whatsapp.com":           "WhatsApp",
+	"wa.me":                  "WhatsApp",
+	"threads.net":            "Threads",
+	"tumblr.com":             "Tumblr",
+	"quora.com":              "Quora",
+	"news.ycombinator.com":   "Hacker News",
+	"discord.com":            "Discord",
+	"snapchat.com":           "Snapchat",
+	"weibo.com":              "Weibo",
+	"org.telegram.messenger": "Telegram",
+	"com.facebook.katana":    "Facebook",
+	"com.instagram.android":  "Instagram",
+	"com.twitter.android":    "Twitter",
+	"com.linkedin.android":   "LinkedIn",
+	"com.reddit.frontpage":   "Reddit",
+	"com.whatsapp":           "WhatsApp",
+	"com.vkontakte.android":  "VK",
+}
+
+// Parse referer into traffic source
+func ParseReferer(referer string) Source {
+	referer = strings.TrimSpace(referer)
+	if referer == "" || referer == "-" || strings.EqualFold(referer, "null") {
+		return Source{Kind: SourceDirect}
+	}
+
+	parsed, e := url.Parse(referer)
+	if e != nil || parsed.Scheme == "" {
+		// Referer without scheme, e.g. "facebook.com/page"
+		parsed, e = url.Parse("http://" + referer)
+		if e != nil || !strings.Contains(parsed.Hostname(), ".") {
+			return Source{Kind: SourceUnknown}
+		}
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme == "android-app" || scheme == "ios-app" {
+		return appSource(parsed)
+	}
+	if scheme != "http" && scheme != "https" {
+		return Source{Kind: SourceUnknown}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "" {
+		return Source{Kind: SourceUnknown}
+	}
+
+	source := Source{Kind: SourceReferral, Host: host, Domain: RegistrableDomain(host)}
+
+	if name, ok := socialNetworks[host]; ok {
+		source.Kind, source.Name = SourceSocial, name
+		return source
+	}
+	if name, ok := socialNetworks[source.Domain]; ok {
+		source.Kind, source.Name = SourceSocial, name
+		return source
+	}
+
+	engine, ok := searchEngines[host]
+	if !ok && !isServiceHost(host) {
+		engine, ok = searchEngines[strings.SplitN(source.Domain, ".", 2)[0]]
+	}
+	if ok {
+		source.Kind, source.Name = SourceSearch, engine.name
+		source.Terms = searchTerms(parsed, engine.params)
+	}
+
+	return source
+}
+
+// Mail, maps and other services of search engines are not search traffic
+func isServiceHost(host string) bool {
+	for _, service := range []string{"mail.", "maps.", "drive.", "docs.", "translate.", "news.", "disk.", "music.", "market."} {
+		if strings.HasPrefix(host, service) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func searchTerms(parsed *url.URL, params []string) string {
+	query := parsed.Query()
+
+	// Some engines keep query in fragment, e.g. "#q=test"
+	if fragment, e := url.ParseQuery(parsed.Fragment); e == nil {
+		for key, values := range fragment {
+			if _, ok := query[key]; !ok {
+				query[key] = values
+			}
+		}
+	}
+
+	for _, param := range params {
+		if terms := strings.Join(strings.Fields(query.Get(param)), " "); terms != "" {
+			return terms
+		}
+	}
+
+	return ""
+}
+
+func appSource(parsed *url.URL) Source {
+	app := strings.ToLower(parsed.Host)
+	source := Source{Kind: SourceApp, Domain: app}
+
+	if name, ok := socialNetworks[app]; ok {
+		source.Kind, source.Name = SourceSocial, name
+	}
+	if app == "com.google.android.googlequicksearchbox" {
+		source.Kind, source.Name = SourceSearch, "Google"
+	}
+
+	return source
+}
+
+// Registrable domain (public suffix plus one label) of host, IP addresses are returned as is
+func RegistrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return host
+	}
+
+	suffix := 1
+	if len(labels) >= 3 && publicSuffixes[strings.Join(labels[len(labels)-2:], ".")] {
+		suffix = 2
+	}
+
+	if len(labels) <= suffix {
+		return host
+	}
+
+	return strings.Join(labels[len(labels)-suffix-1:], ".")
+}
@@ -0,0 +1,122 @@
+package traffic
+
+import "testing"
+
+func TestParseReferer(t *testing.T) {
+	tests := []struct {
+		name    string
+		referer string
+		want    Source
+	}{
+		{
+			name: "test_direct",
+			want: Source{Kind: SourceDirect},
+		},
+		{
+			name:    "test_google_without_terms",
+			referer: "https://www.google.co.uk/",
+			want:    Source{Kind: SourceSearch, Host: "www.google.co.uk", Domain: "google.co.uk", Name: "Google"},
+		},
+		{
+			name:    "test_bing_terms",
+			referer: "https://www.bing.com/search?q=tiny+url++shortener&form=QBLH",
+			want:    Source{Kind: SourceSearch, Host: "www.bing.com", Domain: "bing.com", Name: "Bing", Terms: "tiny url shortener"},
+		},
+		{
+			name:    "test_yandex_terms",
+			referer: "https://yandex.ru/search/?text=%D1%81%D1%81%D1%8B%D0%BB%D0%BA%D0%B0&lr=213",
+			want:    Source{Kind: SourceSearch, Host: "yandex.ru", Domain: "yandex.ru", Name: "Yandex", Terms: "ссылка"},
+		},
+		{
+			name:    "test_terms_in_fragment",
+			referer: "https://duckduckgo.com/#q=go+sdk",
+			want:    Source{Kind: SourceSearch, Host: "duckduckgo.com", Domain: "duckduckgo.com", Name: "DuckDuckGo", Terms: "go sdk"},
+		},
+		{
+			name:    "test_search_engine_service",
+			referer: "https://mail.google.com/mail/u/0/",
+			want:    Source{Kind: SourceReferral, Host: "mail.google.com", Domain: "google.com"},
+		},
+		{
+			name:    "test_search_by_host",
+			referer: "https://search.brave.com/search?q=test",
+			want:    Source{Kind: SourceSearch, Host: "search.brave.com", Domain: "brave.com", Name: "Brave Search", Terms: "test"},
+		},
+		{
+			name:    "test_social_short_domain",
+			referer: "https://t.co/abc",
+			want:    Source{Kind: SourceSocial, Host: "t.co", Domain: "t.co", Name: "Twitter"},
+		},
+		{
+			name:    "test_social_subdomain",
+			referer: "https://l.facebook.com/l.php?u=https%3A%2F%2Ftinysrc.me",
+			want:    Source{Kind: SourceSocial, Host: "l.facebook.com", Domain: "facebook.com", Name: "Facebook"},
+		},
+		{
+			name:    "test_social_by_host",
+			referer: "https://news.ycombinator.com/item?id=1",
+			want:    Source{Kind: SourceSocial, Host: "news.ycombinator.com", Domain: "ycombinator.com", Name: "Hacker News"},
+		},
+		{
+			name:    "test_referral_public_suffix",
+			referer: "HTTPS://News.BBC.co.uk./article",
+			want:    Source{Kind: SourceReferral, Host: "news.bbc.co.uk", Domain: "bbc.co.uk"},
+		},
+		{
+			name:    "test_referral_without_scheme",
+			referer: "blog.test.com/post",
+			want:    Source{Kind: SourceReferral, Host: "blog.test.com", Domain: "test.com"},
+		},
+		{
+			name:    "test_referral_ip",
+			referer: "http://192.168.1.1:8080/",
+			want:    Source{Kind: SourceReferral, Host: "192.168.1.1", Domain: "192.168.1.1"},
+		},
+		{
+			name:    "test_app",
+			referer: "android-app://com.slack",
+			want:    Source{Kind: SourceApp, Domain: "com.slack"},
+		},
+		{
+			name:    "test_social_app",
+			referer: "android-app://org.telegram.messenger/",
+			want:    Source{Kind: SourceSocial, Domain: "org.telegram.messenger", Name: "Telegram"},
+		},
+		{
+			name:    "test_search_app",
+			referer: "android-app://com.google.android.googlequicksearchbox/",
+			want:    Source{Kind: SourceSearch, Domain: "com.google.android.googlequicksearchbox", Name: "Google"},
+		},
+		{
+			name:    "test_unknown",
+			referer: "about:blank",
+			want:    Source{Kind: SourceUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseReferer(tt.referer); got != tt.want {
+				t.Errorf("ParseReferer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"www.test.com":       "test.com",
+		"a.b.test.com.au":    "test.com.au",
+		"co.uk":              "co.uk",
+		"user.github.io":     "user.github.io",
+		"localhost":          "localhost",
+		"2001:db8::1":        "2001:db8::1",
+		"WWW.Example.Co.JP.": "example.co.jp",
+	}
+
+	for host, want := range tests {
+		if got := RegistrableDomain(host); got != want {
+			t.Errorf("RegistrableDomain(%s) = %s, want %s", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package traffic normalizes browsers and devices of clicks and parses
+// referers into traffic sources: direct, search engines with search terms,
+// social networks and referring domains.
+//
+//	stats := traffic.Enrich(statistic.Data)
+//	report := traffic.Analyze(stats, 10)
+package traffic
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"sort"
+	"strconv"
+)
+
+// Stat row with normalized agent and traffic source
+type Stat struct {
+	*models.StatResponse
+	Agent  Agent  `json:"agent"`
+	Source Source `json:"source"`
+}
+
+type Count struct {
+	Name   string `json:"name"`
+	Clicks int64  `json:"clicks"`
+	// Share of all clicks, 0..1
+	Share float64 `json:"share"`
+}
+
+// Traffic sources and audience of clicks, every list is sorted by clicks desc
+type Report struct {
+	Clicks int64 `json:"clicks"`
+	// Clicks by SourceKind
+	Kinds []*Count `json:"kinds"`
+	// Referring registrable domains, direct traffic is not included
+	Domains  []*Count `json:"domains"`
+	Engines  []*Count `json:"engines"`
+	Terms    []*Count `json:"terms"`
+	Networks []*Count `json:"networks"`
+	Browsers []*Count `json:"browsers"`
+	// Browser with major version, e.g. "Chrome 99"
+	Versions []*Count `json:"versions"`
+	Os       []*Count `json:"os"`
+	Devices  []*Count `json:"devices"`
+}
+
+// Parse agent and referer of every row
+func Enrich(stats []*models.StatResponse) []*Stat {
+	result := make([]*Stat, len(stats))
+
+	for i, stat := range stats {
+		result[i] = &Stat{StatResponse: stat, Agent: ParseAgent(stat), Source: ParseReferer(stat.Referer)}
+	}
+
+	return result
+}
+
+// Build report of enriched rows, lists are limited to top entries, top <= 0 keeps all entries
+func Analyze(stats []*Stat, top int) *Report {
+	counters := make(map[string]map[string]int64)
+	add := func(list string, name string) {
+		if name == "" {
+			return
+		}
+		if counters[list] == nil {
+			counters[list] = make(map[string]int64)
+		}
+		counters[list][name]++
+	}
+
+	for _, stat := range stats {
+		add("kinds", string(stat.Source.Kind))
+		add("domains", stat.Source.Domain)
+		add("terms", stat.Source.Terms)
+		add("browsers", stat.Agent.Browser)
+		add("os", stat.Agent.Os)
+		add("devices", string(stat.Agent.Device))
+
+		switch stat.Source.Kind {
+		case SourceSearch:
+			add("engines", stat.Source.Name)
+		case SourceSocial:
+			add("networks", stat.Source.Name)
+		}
+
+		if stat.Agent.Version > 0 {
+			add("versions", stat.Agent.Browser+" "+strconv.Itoa(stat.Agent.Version))
+		}
+	}
+
+	total := int64(len(stats))
+	counts := func(list string) []*Count {
+		result := make([]*Count, 0, len(counters[list]))
+		for name, clicks := range counters[list] {
+			result = append(result, &Count{Name: name, Clicks: clicks, Share: float64(clicks) / float64(total)})
+		}
+
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Clicks != result[j].Clicks {
+				return result[i].Clicks > result[j].Clicks
+			}
+			return result[i].Name < result[j].Name
+		})
+
+		if top > 0 && len(result) > top {
+			result = result[:top]
+		}
+		return result
+	}
+
+	return &Report{
+		Clicks:   total,
+		Kinds:    counts("kinds"),
+		Domains:  counts("domains"),
+		Engines:  counts("engines"),
+		Terms:    counts("terms"),
+		Networks: counts("networks"),
+		Browsers: counts("browsers"),
+		Versions: counts("versions"),
+		Os:       counts("os"),
+		Devices:  counts("devices"),
+	}
+}
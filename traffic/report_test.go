@@ -0,0 +1,80 @@
+package traffic
+
+import (
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	stats := Enrich([]*models.StatResponse{
+		{Browser: "Chrome", BrowserVersion: "99.0", Os: "Windows", Referer: "https://www.google.com/search?q=tinysrc"},
+		{Browser: "Chrome", BrowserVersion: "100.0", Os: "Android", Mobile: true, Referer: "https://www.google.de/"},
+		{Browser: "Safari", BrowserVersion: "15", Os: "iOS", Mobile: true, Referer: "https://t.co/x"},
+		{Browser: "Firefox", BrowserVersion: "98", Os: "Linux", Referer: ""},
+		{Browser: "Firefox", Os: "Linux", Referer: "https://blog.test.com/post"},
+	})
+
+	if stats[0].Source.Terms != "tinysrc" || stats[2].Agent.Device != DeviceMobile {
+		t.Fatalf("Enrich() = %+v %+v", stats[0], stats[2])
+	}
+
+	report := Analyze(stats, 2)
+
+	tests := []struct {
+		name string
+		got  []*Count
+		want []*Count
+	}{
+		{
+			name: "test_kinds",
+			got:  report.Kinds,
+			want: []*Count{{Name: "search", Clicks: 2, Share: 0.4}, {Name: "direct", Clicks: 1, Share: 0.2}},
+		},
+		{
+			name: "test_domains",
+			got:  report.Domains,
+			want: []*Count{{Name: "google.com", Clicks: 1, Share: 0.2}, {Name: "google.de", Clicks: 1, Share: 0.2}},
+		},
+		{
+			name: "test_engines",
+			got:  report.Engines,
+			want: []*Count{{Name: "Google", Clicks: 2, Share: 0.4}},
+		},
+		{
+			name: "test_terms",
+			got:  report.Terms,
+			want: []*Count{{Name: "tinysrc", Clicks: 1, Share: 0.2}},
+		},
+		{
+			name: "test_networks",
+			got:  report.Networks,
+			want: []*Count{{Name: "Twitter", Clicks: 1, Share: 0.2}},
+		},
+		{
+			name: "test_versions",
+			got:  report.Versions,
+			want: []*Count{{Name: "Chrome 100", Clicks: 1, Share: 0.2}, {Name: "Chrome 99", Clicks: 1, Share: 0.2}},
+		},
+		{
+			name: "test_devices",
+			got:  report.Devices,
+			want: []*Count{{Name: "desktop", Clicks: 3, Share: 0.6}, {Name: "mobile", Clicks: 2, Share: 0.4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				for _, count := range tt.got {
+					t.Logf("%+v", count)
+				}
+				t.Errorf("Analyze() %s mismatch", tt.name)
+			}
+		})
+	}
+
+	if report.Clicks != 5 || len(Analyze(stats, 0).Kinds) != 4 {
+		t.Errorf("Analyze() clicks = %d", report.Clicks)
+	}
+}
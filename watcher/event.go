@@ -0,0 +1,46 @@
+package watcher
+
+import (
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"time"
+)
+
+type Type string
+
+const (
+	LinkCreated       Type = "link.created"
+	LinkActivated     Type = "link.activated"
+	LinkDeactivated   Type = "link.deactivated"
+	LinkExpired       Type = "link.expired"
+	ClickRecorded     Type = "click.recorded"
+	ClickCountChanged Type = "click.count_changed"
+)
+
+// Change of a link found by a poll
+type Event struct {
+	Type Type   `json:"type"`
+	Hash string `json:"hash"`
+	// Time of poll which found the change
+	Time time.Time                `json:"time"`
+	Link *models.LinkUserResponse `json:"link,omitempty"`
+	// Click of ClickRecorded
+	Click *models.StatResponse `json:"click,omitempty"`
+	// Counters of ClickCountChanged
+	Clicks         int64 `json:"clicks,omitempty"`
+	PreviousClicks int64 `json:"previous_clicks,omitempty"`
+}
+
+func (event *Event) String() string {
+	switch event.Type {
+	case ClickRecorded:
+		return fmt.Sprintf("%s %s from %s at %s", event.Type, event.Hash, event.Click.Ip, event.Click.Created.Format(time.RFC3339))
+	case ClickCountChanged:
+		return fmt.Sprintf("%s %s %d -> %d", event.Type, event.Hash, event.PreviousClicks, event.Clicks)
+	default:
+		return fmt.Sprintf("%s %s", event.Type, event.Hash)
+	}
+}
+
+// Callback of events, called in order of events
+type Handler func(event *Event)
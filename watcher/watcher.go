@@ -0,0 +1,416 @@
+// Package watcher polls links and their statistic and emits events for
+// changes: new links, activation, expiration, new clicks and click counters.
+// TinySRC has no webhooks, so the watcher diffs every poll against the
+// previous snapshot, which is persisted between runs.
+//
+//	w, e := watcher.New(client, "watcher.json")
+//	w.Handle(func(event *watcher.Event) { fmt.Println(event) })
+//	events := w.Subscribe(100)
+//	e = w.Run(ctx)
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_MIN_INTERVAL = 15 * time.Second
+const DEFAULT_MAX_INTERVAL = 5 * time.Minute
+
+// Snapshot of one link
+type State struct {
+	Link    *models.LinkUserResponse `json:"link"`
+	Expired bool                     `json:"expired"`
+	// Created of the newest emitted click
+	LastClick time.Time `json:"last_click,omitempty"`
+	// Clicks emitted at LastClick, once per row, rows of the same minute are returned again by the next poll
+	LastClicks []string `json:"last_clicks,omitempty"`
+}
+
+type Snapshot struct {
+	Updated time.Time         `json:"updated"`
+	Links   map[string]*State `json:"links"`
+}
+
+type Watcher struct {
+	Client *tinysrc.Client
+	// Watched hashes loaded with GetUrlByHash, all links of GetListUrls when empty
+	Hashes []string
+	// Search query of GetListUrls
+	Query string
+	// Load statistic of links with changed click counter and emit ClickRecorded
+	Clicks bool
+	// Polls with events are followed by MinInterval, the interval doubles after
+	// every poll without events up to MaxInterval
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// Emit LinkCreated for links found by the first poll, otherwise first poll only takes snapshot
+	EmitInitial bool
+
+	path     string
+	mutex    sync.Mutex
+	snapshot *Snapshot
+	interval time.Duration
+	handlers []Handler
+	channels []chan *Event
+}
+
+// Constructor of Watcher, snapshot is loaded from path if file exists.
+// Empty path keeps snapshot in memory only.
+func New(client *tinysrc.Client, path string) (*Watcher, error) {
+	watcher := &Watcher{
+		Client:      client,
+		Clicks:      true,
+		MinInterval: DEFAULT_MIN_INTERVAL,
+		MaxInterval: DEFAULT_MAX_INTERVAL,
+		path:        path,
+	}
+
+	if path == "" {
+		return watcher, nil
+	}
+
+	data, e := os.ReadFile(path)
+	if errors.Is(e, os.ErrNotExist) {
+		return watcher, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	snapshot := &Snapshot{}
+	if e = json.Unmarshal(data, snapshot); e != nil {
+		return nil, fmt.Errorf("watcher: %s: %w", path, e)
+	}
+
+	if snapshot.Links == nil {
+		snapshot.Links = make(map[string]*State)
+	}
+	watcher.snapshot = snapshot
+
+	return watcher, nil
+}
+
+// Register callback, handlers are called synchronously by Poll
+func (watcher *Watcher) Handle(handler Handler) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	watcher.handlers = append(watcher.handlers, handler)
+}
+
+// Channel of events with buffer of size, Poll blocks while the channel is full.
+// Channels are closed when Run returns.
+func (watcher *Watcher) Subscribe(size int) <-chan *Event {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	channel := make(chan *Event, size)
+	watcher.channels = append(watcher.channels, channel)
+	return channel
+}
+
+// Copy of snapshot, nil before the first poll
+func (watcher *Watcher) Snapshot() *Snapshot {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if watcher.snapshot == nil {
+		return nil
+	}
+
+	snapshot := &Snapshot{Updated: watcher.snapshot.Updated, Links: make(map[string]*State, len(watcher.snapshot.Links))}
+	for hash, state := range watcher.snapshot.Links {
+		copied := *state
+		snapshot.Links[hash] = &copied
+	}
+
+	return snapshot
+}
+
+// Interval before the next poll
+func (watcher *Watcher) Interval() time.Duration {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if watcher.interval <= 0 {
+		return watcher.minInterval()
+	}
+
+	return watcher.interval
+}
+
+func (watcher *Watcher) minInterval() time.Duration {
+	if watcher.MinInterval <= 0 {
+		return DEFAULT_MIN_INTERVAL
+	}
+
+	return watcher.MinInterval
+}
+
+func (watcher *Watcher) maxInterval() time.Duration {
+	if watcher.MaxInterval < watcher.minInterval() {
+		return watcher.minInterval()
+	}
+
+	return watcher.MaxInterval
+}
+
+// Poll until ctx is done, failed polls are retried after MaxInterval
+func (watcher *Watcher) Run(ctx context.Context) error {
+	defer watcher.close()
+
+	for {
+		_, errorResponse := watcher.Poll(time.Now())
+		interval := watcher.Interval()
+		if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			interval = watcher.maxInterval()
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (watcher *Watcher) close() {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	for _, channel := range watcher.channels {
+		close(channel)
+	}
+	watcher.channels = nil
+}
+
+// Load links, diff them with snapshot, emit events and persist snapshot
+func (watcher *Watcher) Poll(now time.Time) (events []*Event, errorResponse models.ErrorResponse) {
+	links, failed, errorResponse := watcher.links()
+	if links == nil && len(failed) == 0 {
+		return nil, errorResponse
+	}
+
+	watcher.mutex.Lock()
+	initial := watcher.snapshot == nil
+	previous := watcher.snapshot
+	watcher.mutex.Unlock()
+
+	if initial {
+		previous = &Snapshot{Links: make(map[string]*State)}
+	}
+
+	snapshot := &Snapshot{Updated: now, Links: make(map[string]*State, len(links))}
+
+	for _, link := range links {
+		state := &State{Link: link, Expired: link.ExpirationTime != nil && !link.ExpirationTime.After(now)}
+		snapshot.Links[link.Hash] = state
+
+		old, ok := previous.Links[link.Hash]
+		if !ok {
+			if !initial || watcher.EmitInitial {
+				events = append(events, &Event{Type: LinkCreated, Hash: link.Hash, Time: now, Link: link})
+			}
+			state.LastClick = now
+			continue
+		}
+
+		state.LastClick, state.LastClicks = old.LastClick, old.LastClicks
+
+		if old.Link.Active == 0 && link.Active != 0 {
+			events = append(events, &Event{Type: LinkActivated, Hash: link.Hash, Time: now, Link: link})
+		}
+		if old.Link.Active != 0 && link.Active == 0 {
+			events = append(events, &Event{Type: LinkDeactivated, Hash: link.Hash, Time: now, Link: link})
+		}
+		if state.Expired && !old.Expired {
+			events = append(events, &Event{Type: LinkExpired, Hash: link.Hash, Time: now, Link: link})
+		}
+
+		if link.Clicks == old.Link.Clicks {
+			continue
+		}
+
+		if watcher.Clicks && link.Clicks > old.Link.Clicks {
+			clicks, failure := watcher.clicks(state, now)
+			if len(failure.Errors) > 0 || len(failure.Validations) > 0 {
+				// Previous counter is kept, so clicks are loaded again by the next poll
+				kept := *link
+				kept.Clicks = old.Link.Clicks
+				state.Link = &kept
+				errorResponse.Errors = append(errorResponse.Errors, failure.Errors...)
+				continue
+			}
+			events = append(events, clicks...)
+		}
+
+		events = append(events, &Event{Type: ClickCountChanged, Hash: link.Hash, Time: now, Link: link, Clicks: link.Clicks, PreviousClicks: old.Link.Clicks})
+	}
+
+	// Links which failed to load are kept as they were
+	for hash := range failed {
+		if state, ok := previous.Links[hash]; ok {
+			snapshot.Links[hash] = state
+		}
+	}
+
+	watcher.mutex.Lock()
+	watcher.snapshot = snapshot
+	if len(events) > 0 {
+		watcher.interval = watcher.minInterval()
+	} else {
+		if watcher.interval <= 0 {
+			watcher.interval = watcher.minInterval()
+		}
+		watcher.interval *= 2
+		if watcher.interval > watcher.maxInterval() {
+			watcher.interval = watcher.maxInterval()
+		}
+	}
+	e := watcher.save()
+	handlers := append([]Handler(nil), watcher.handlers...)
+	channels := append([]chan *Event(nil), watcher.channels...)
+	watcher.mutex.Unlock()
+
+	if e != nil {
+		errorResponse.Errors = append(errorResponse.Errors, e.Error())
+	}
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+		for _, channel := range channels {
+			channel <- event
+		}
+	}
+
+	return events, errorResponse
+}
+
+// Watched links, failed contains hashes which could not be loaded
+func (watcher *Watcher) links() (links []*models.LinkUserResponse, failed map[string]bool, errorResponse models.ErrorResponse) {
+	if len(watcher.Hashes) == 0 {
+		links, errorResponse = watcher.Client.GetAllUrls(watcher.Query, 0)
+		if len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+			return nil, nil, errorResponse
+		}
+		if links == nil {
+			links = []*models.LinkUserResponse{}
+		}
+		return links, nil, errorResponse
+	}
+
+	failed = make(map[string]bool)
+	links = []*models.LinkUserResponse{}
+
+	for _, hash := range watcher.Hashes {
+		link, failure := watcher.Client.GetUrlByHash(hash)
+		if link == nil || len(failure.Errors) > 0 || len(failure.Validations) > 0 {
+			failed[hash] = true
+			for _, e := range failure.Errors {
+				errorResponse.Errors = append(errorResponse.Errors, hash+": "+e)
+			}
+			continue
+		}
+
+		if link.Hash == "" {
+			link.Hash = hash
+		}
+		links = append(links, link)
+	}
+
+	return links, failed, errorResponse
+}
+
+// ClickRecorded events of rows created since LastClick, LastClick of state is moved forward
+func (watcher *Watcher) clicks(state *State, now time.Time) (events []*Event, errorResponse models.ErrorResponse) {
+	start := state.LastClick
+	if start.IsZero() || start.After(now) {
+		start = now.Add(-watcher.maxInterval())
+	}
+
+	r, errorResponse := watcher.Client.GetStatByHashChunked(state.Link.Hash, models.StatRequest{DateStart: start, DateEnd: now}, tinysrc.StatChunkOptions{})
+	if r == nil || len(errorResponse.Errors) > 0 || len(errorResponse.Validations) > 0 {
+		return nil, errorResponse
+	}
+
+	// Identical clicks are real clicks, only as many rows as were emitted by previous polls are skipped
+	emitted := make(map[string]int, len(state.LastClicks))
+	for _, key := range state.LastClicks {
+		emitted[key]++
+	}
+
+	rows := append([]*models.StatResponse(nil), r.Data...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Created.Before(rows[j].Created) })
+
+	for _, row := range rows {
+		if row.Created.Before(state.LastClick) {
+			continue
+		}
+
+		key := clickKey(row)
+		if row.Created.Equal(state.LastClick) && emitted[key] > 0 {
+			emitted[key]--
+			continue
+		}
+
+		if row.Created.After(state.LastClick) {
+			state.LastClick = row.Created
+			state.LastClicks = nil
+		}
+		state.LastClicks = append(state.LastClicks, key)
+
+		events = append(events, &Event{Type: ClickRecorded, Hash: state.Link.Hash, Time: now, Link: state.Link, Click: row})
+	}
+
+	return events, errorResponse
+}
+
+// Rows have no id, clicks of the same time are told apart by their fields, identical clicks are counted
+func clickKey(stat *models.StatResponse) string {
+	return strings.Join([]string{stat.Created.Format(time.RFC3339Nano), stat.Ip, stat.Browser, stat.BrowserVersion, stat.Os, stat.Platform, stat.Referer}, "|")
+}
+
+// Write snapshot atomically, caller holds mutex
+func (watcher *Watcher) save() error {
+	if watcher.path == "" {
+		return nil
+	}
+
+	data, e := json.MarshalIndent(watcher.snapshot, "", "  ")
+	if e != nil {
+		return e
+	}
+
+	tmp, e := os.CreateTemp(filepath.Dir(watcher.path), filepath.Base(watcher.path)+".*")
+	if e != nil {
+		return e
+	}
+
+	if _, e = tmp.Write(data); e != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return e
+	}
+
+	if e = tmp.Close(); e != nil {
+		_ = os.Remove(tmp.Name())
+		return e
+	}
+
+	return os.Rename(tmp.Name(), watcher.path)
+}
@@ -0,0 +1,292 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dmitrypro77/tinysrc-api-sdk"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	mu          sync.Mutex
+	links       []*models.LinkUserResponse
+	stats       map[string][]*models.StatResponse
+	failedStats bool
+}
+
+func (server *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/v1/client/url":
+		var data []*models.LinkUserResponse
+		if r.URL.Query().Get("page") == "1" {
+			for _, link := range server.links {
+				copied := *link
+				data = append(data, &copied)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(&models.PaginatedLinkUserResponse{Data: data, Total: int64(len(server.links))})
+	case strings.HasPrefix(r.URL.Path, "/v1/client/url/"):
+		hash := strings.TrimPrefix(r.URL.Path, "/v1/client/url/")
+		for _, link := range server.links {
+			if link.Hash == hash {
+				_ = json.NewEncoder(w).Encode(link)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":["Not Found"]}`))
+	case strings.HasPrefix(r.URL.Path, "/v1/client/stat/") && !server.failedStats:
+		hash := strings.TrimPrefix(r.URL.Path, "/v1/client/stat/")
+		resp := models.StatPaginatedResponse{}
+		if r.URL.Query().Get("page") == "1" {
+			resp.Data = server.stats[hash]
+		}
+		resp.Total = int64(len(resp.Data))
+		_ = json.NewEncoder(w).Encode(&resp)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"errors":["Internal Server Error"]}`))
+	}
+}
+
+func (server *testServer) update(fn func()) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	fn()
+}
+
+func types(events []*Event) []string {
+	var result []string
+	for _, event := range events {
+		result = append(result, string(event.Type)+" "+event.Hash)
+	}
+	return result
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	start := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	expiration := start.Add(10 * time.Minute)
+
+	server := &testServer{
+		links: []*models.LinkUserResponse{{Hash: "a", Active: 1}},
+		stats: map[string][]*models.StatResponse{
+			"a": {{Ip: "1.1.1.0", Created: start.Add(-time.Minute)}},
+		},
+	}
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	path := filepath.Join(t.TempDir(), "watcher.json")
+	watcher, e := New(client, path)
+	if e != nil {
+		t.Fatalf("New() error = %v", e)
+	}
+	watcher.MinInterval = time.Second
+	watcher.MaxInterval = 3 * time.Second
+
+	var handled []*Event
+	watcher.Handle(func(event *Event) { handled = append(handled, event) })
+	channel := watcher.Subscribe(10)
+
+	// First poll takes snapshot only
+	if events, errorResponse := watcher.Poll(start); len(events) != 0 || len(errorResponse.Errors) > 0 {
+		t.Fatalf("Poll() = %v, %v", types(events), errorResponse.Errors)
+	}
+	if watcher.Interval() != 2*time.Second {
+		t.Errorf("Interval() = %v", watcher.Interval())
+	}
+
+	server.update(func() {
+		server.links[0].Active = 0
+		server.links[0].Clicks = 2
+		server.links = append(server.links, &models.LinkUserResponse{Hash: "b", Active: 1, ExpirationTime: &expiration})
+		server.stats["a"] = append(server.stats["a"],
+			&models.StatResponse{Ip: "1.1.1.1", Created: start.Add(time.Minute)},
+			&models.StatResponse{Ip: "1.1.1.2", Created: start.Add(2 * time.Minute)},
+		)
+	})
+
+	events, errorResponse := watcher.Poll(start.Add(5 * time.Minute))
+	if len(errorResponse.Errors) > 0 {
+		t.Fatalf("Poll() errors = %v", errorResponse.Errors)
+	}
+
+	want := []string{"link.deactivated a", "click.recorded a", "click.recorded a", "click.count_changed a", "link.created b"}
+	if !reflect.DeepEqual(types(events), want) {
+		t.Fatalf("Poll() = %v, want %v", types(events), want)
+	}
+	if events[1].Click.Ip != "1.1.1.1" || events[3].PreviousClicks != 0 || events[3].Clicks != 2 {
+		t.Errorf("Poll() = %v", events)
+	}
+	if !reflect.DeepEqual(handled, events) || len(channel) != len(events) || watcher.Interval() != time.Second {
+		t.Errorf("Poll() handled %d, sent %d, interval %v", len(handled), len(channel), watcher.Interval())
+	}
+
+	// Snapshot is reloaded, click of the same minute as the last one is new
+	reloaded, e := New(client, path)
+	if e != nil {
+		t.Fatalf("New() error = %v", e)
+	}
+
+	server.update(func() {
+		server.links[0].Clicks = 3
+		server.stats["a"] = append(server.stats["a"], &models.StatResponse{Ip: "1.1.1.3", Created: start.Add(2 * time.Minute)})
+		server.failedStats = true
+	})
+
+	events, errorResponse = reloaded.Poll(start.Add(15 * time.Minute))
+	if want := []string{"link.expired b"}; len(errorResponse.Errors) == 0 || !reflect.DeepEqual(types(events), want) {
+		t.Fatalf("Poll() = %v, %v, want %v with errors", types(events), errorResponse.Errors, want)
+	}
+
+	server.update(func() { server.failedStats = false })
+
+	events, errorResponse = reloaded.Poll(start.Add(16 * time.Minute))
+	if want := []string{"click.recorded a", "click.count_changed a"}; len(errorResponse.Errors) > 0 || !reflect.DeepEqual(types(events), want) {
+		t.Fatalf("Poll() = %v, %v, want %v", types(events), errorResponse.Errors, want)
+	}
+	if events[0].Click.Ip != "1.1.1.3" || events[1].PreviousClicks != 2 {
+		t.Errorf("Poll() = %v", events)
+	}
+
+	if events, _ = reloaded.Poll(start.Add(17 * time.Minute)); len(events) != 0 {
+		t.Errorf("Poll() = %v", types(events))
+	}
+}
+
+func TestWatcher_IdenticalClicks(t *testing.T) {
+	start := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	click := models.StatResponse{Ip: "1.1.1.1", Browser: "Chrome", Created: start.Add(time.Minute)}
+
+	server := &testServer{
+		links: []*models.LinkUserResponse{{Hash: "a", Active: 1}},
+		stats: map[string][]*models.StatResponse{},
+	}
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	watcher, _ := New(client, "")
+	_, _ = watcher.Poll(start)
+
+	tests := []struct {
+		name       string
+		added      int
+		wantClicks int
+	}{
+		{name: "test_two_in_one_poll", added: 2, wantClicks: 2},
+		{name: "test_third_in_next_poll", added: 1, wantClicks: 1},
+		{name: "test_no_new_rows", added: 0, wantClicks: 0},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.update(func() {
+				for j := 0; j < tt.added; j++ {
+					copied := click
+					server.stats["a"] = append(server.stats["a"], &copied)
+				}
+				server.links[0].Clicks += int64(tt.added)
+			})
+
+			events, _ := watcher.Poll(start.Add(time.Duration(i+2) * time.Minute))
+			clicks := 0
+			for _, event := range events {
+				if event.Type == ClickRecorded {
+					clicks++
+				}
+			}
+			if clicks != tt.wantClicks {
+				t.Errorf("Poll() = %v, want %d clicks", types(events), tt.wantClicks)
+			}
+		})
+	}
+}
+
+func TestWatcher_Hashes(t *testing.T) {
+	server := &testServer{links: []*models.LinkUserResponse{{Hash: "a", Active: 1}}}
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	watcher, _ := New(client, "")
+	watcher.Hashes = []string{"a", "b"}
+	watcher.EmitInitial = true
+
+	now := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	events, errorResponse := watcher.Poll(now)
+	if !reflect.DeepEqual(types(events), []string{"link.created a"}) || len(errorResponse.Errors) != 1 || !strings.HasPrefix(errorResponse.Errors[0], "b: ") {
+		t.Fatalf("Poll() = %v, %v", types(events), errorResponse.Errors)
+	}
+
+	// a fails to load, state of a is kept
+	server.update(func() { server.links = []*models.LinkUserResponse{{Hash: "b"}} })
+
+	events, errorResponse = watcher.Poll(now.Add(time.Minute))
+	if !reflect.DeepEqual(types(events), []string{"link.created b"}) || len(errorResponse.Errors) != 1 {
+		t.Fatalf("Poll() = %v, %v", types(events), errorResponse.Errors)
+	}
+
+	if snapshot := watcher.Snapshot(); len(snapshot.Links) != 2 || snapshot.Links["a"].Link.Active != 1 {
+		t.Errorf("Snapshot() = %v", snapshot.Links)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	server := &testServer{}
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := tinysrc.NewClient(context.Background(), "test", nil)
+	_ = client.SetBaseURL(ts.URL + "/v1")
+
+	watcher, _ := New(client, "")
+	watcher.MinInterval = time.Millisecond
+	watcher.MaxInterval = time.Millisecond
+	channel := watcher.Subscribe(10)
+
+	// Snapshot is taken before Run, so the link added later is created
+	watcher.Poll(time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watcher.Run(ctx) }()
+
+	server.update(func() { server.links = []*models.LinkUserResponse{{Hash: "a"}} })
+
+	event := <-channel
+	if event.Type != LinkCreated || event.Hash != "a" {
+		t.Errorf("Run() event = %s", event)
+	}
+
+	cancel()
+	if e := <-done; e != context.Canceled {
+		t.Errorf("Run() error = %v", e)
+	}
+
+	if _, ok := <-channel; ok {
+		t.Errorf("Run() did not close channel")
+	}
+}
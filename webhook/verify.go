@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Requests older or newer than DEFAULT_TOLERANCE are rejected, so captured requests can not be replayed later
+const DEFAULT_TOLERANCE = 5 * time.Minute
+
+// Limit of request body read by Verify
+const MAX_BODY_SIZE = 1 << 20
+
+const SIGNATURE_PREFIX = "sha256="
+
+var ErrMissingSignature = errors.New("webhook: missing signature")
+var ErrInvalidSignature = errors.New("webhook: invalid signature")
+var ErrExpiredTimestamp = errors.New("webhook: timestamp is out of tolerance")
+
+// Signature of request: hex HMAC-SHA256 of "timestamp.body" with prefix sha256=
+func Sign(secret models.Secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret.Reveal()))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return SIGNATURE_PREFIX + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Check signature and timestamp of body, tolerance <= 0 means DEFAULT_TOLERANCE
+func VerifySignature(secret models.Secret, timestamp string, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	if tolerance <= 0 {
+		tolerance = DEFAULT_TOLERANCE
+	}
+
+	seconds, e := strconv.ParseInt(timestamp, 10, 64)
+	if e != nil {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Read body of webhook request and check its signature, payload is returned only when signature is valid
+func Verify(secret models.Secret, r *http.Request, tolerance time.Duration) (*Payload, error) {
+	body, e := io.ReadAll(io.LimitReader(r.Body, MAX_BODY_SIZE+1))
+	if e != nil {
+		return nil, e
+	}
+	if len(body) > MAX_BODY_SIZE {
+		return nil, errors.New("webhook: body is too large")
+	}
+
+	if e = VerifySignature(secret, r.Header.Get(TIMESTAMP_HEADER), r.Header.Get(SIGNATURE_HEADER), body, time.Now(), tolerance); e != nil {
+		return nil, e
+	}
+
+	payload := &Payload{}
+	if e = json.Unmarshal(body, payload); e != nil {
+		return nil, e
+	}
+
+	return payload, nil
+}
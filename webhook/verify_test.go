@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"bytes"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Unix(1648814400, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"id":"1","type":"link.created","hash":"a"}`)
+	signature := Sign("secret", timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		want      error
+	}{
+		{name: "test_valid", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: now},
+		{name: "test_clock_skew", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: now.Add(-4 * time.Minute)},
+		{name: "test_wrong_secret", secret: "other", timestamp: timestamp, signature: signature, body: body, now: now, want: ErrInvalidSignature},
+		{name: "test_changed_body", secret: "secret", timestamp: timestamp, signature: signature, body: []byte(`{"id":"2"}`), now: now, want: ErrInvalidSignature},
+		{name: "test_changed_timestamp", secret: "secret", timestamp: strconv.FormatInt(now.Unix()+1, 10), signature: signature, body: body, now: now, want: ErrInvalidSignature},
+		{name: "test_expired", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: now.Add(6 * time.Minute), want: ErrExpiredTimestamp},
+		{name: "test_missing", secret: "secret", timestamp: timestamp, body: body, now: now, want: ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if e := VerifySignature(models.Secret(tt.secret), tt.timestamp, tt.signature, tt.body, tt.now, 0); e != tt.want {
+				t.Errorf("VerifySignature() error = %v, want %v", e, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1","type":"click.recorded","hash":"a","time":"2022-04-01T12:00:00Z"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r := httptest.NewRequest("POST", "/hooks", bytes.NewReader(body))
+	r.Header.Set(TIMESTAMP_HEADER, timestamp)
+	r.Header.Set(SIGNATURE_HEADER, Sign("secret", timestamp, body))
+
+	payload, e := Verify("secret", r, time.Minute)
+	if e != nil {
+		t.Fatalf("Verify() error = %v", e)
+	}
+	if payload.Id != "1" || payload.Type != "click.recorded" || payload.Hash != "a" {
+		t.Errorf("Verify() = %+v", payload)
+	}
+}
@@ -0,0 +1,392 @@
+// Package webhook posts watcher events as signed JSON to HTTP endpoints.
+// Every request carries HMAC-SHA256 signature of timestamp and body, failed
+// deliveries are retried with exponential backoff and finally written to a
+// dead-letter file. Receivers check requests with Verify.
+//
+//	dispatcher := webhook.NewDispatcher(&webhook.Endpoint{Name: "crm", Url: "https://crm.test.com/hooks", Secret: "secret"})
+//	dispatcher.DeadLetter = "webhooks.dead.jsonl"
+//	w.Handle(dispatcher.Handler(ctx))
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/watcher"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const SIGNATURE_HEADER = "X-Tinysrc-Signature"
+const TIMESTAMP_HEADER = "X-Tinysrc-Timestamp"
+const EVENT_HEADER = "X-Tinysrc-Event"
+const DELIVERY_HEADER = "X-Tinysrc-Delivery"
+
+const DEFAULT_ATTEMPTS = 5
+const DEFAULT_BACKOFF = time.Second
+const DEFAULT_MAX_BACKOFF = time.Minute
+const DEFAULT_TIMEOUT = 10 * time.Second
+
+// Body of webhook request, fields of event are inlined
+type Payload struct {
+	Id string `json:"id"`
+	*watcher.Event
+}
+
+type Endpoint struct {
+	Name   string        `json:"name"`
+	Url    string        `json:"url"`
+	Secret models.Secret `json:"secret"`
+	// Event types sent to endpoint, all types when empty
+	Events []watcher.Type `json:"events,omitempty"`
+	// Hashes sent to endpoint, all hashes when empty
+	Hashes []string `json:"hashes,omitempty"`
+}
+
+// Filters of endpoint accept event
+func (endpoint *Endpoint) Accepts(event *watcher.Event) bool {
+	if len(endpoint.Events) > 0 {
+		accepted := false
+		for _, eventType := range endpoint.Events {
+			accepted = accepted || eventType == event.Type
+		}
+		if !accepted {
+			return false
+		}
+	}
+
+	if len(endpoint.Hashes) > 0 {
+		for _, hash := range endpoint.Hashes {
+			if hash == event.Hash {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Delivery which failed every attempt, one JSON line of dead-letter file
+type Delivery struct {
+	Endpoint string   `json:"endpoint"`
+	Url      string   `json:"url"`
+	Payload  *Payload `json:"payload"`
+	Attempts int      `json:"attempts"`
+	Error    string   `json:"error"`
+	// Time of the last attempt
+	Failed time.Time `json:"failed"`
+}
+
+// Error of endpoint response
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook: unexpected status %d: %s", e.Status, e.Body)
+}
+
+// Request timeouts, 408, 429 and 5xx responses are retried
+func (e *StatusError) Temporary() bool {
+	return e.Status == http.StatusRequestTimeout || e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+type Dispatcher struct {
+	Endpoints []*Endpoint
+	// HTTP client of requests, client with DEFAULT_TIMEOUT when nil
+	Client *http.Client
+	// Attempts of one delivery, DEFAULT_ATTEMPTS when zero
+	Attempts int
+	// Delay after the first failed attempt, doubled after every next one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// File of failed deliveries in JSON lines, failed deliveries are dropped when empty
+	DeadLetter string
+
+	mutex sync.Mutex
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewDispatcher(endpoints ...*Endpoint) *Dispatcher {
+	return &Dispatcher{
+		Endpoints:  endpoints,
+		Client:     &http.Client{Timeout: DEFAULT_TIMEOUT},
+		Attempts:   DEFAULT_ATTEMPTS,
+		Backoff:    DEFAULT_BACKOFF,
+		MaxBackoff: DEFAULT_MAX_BACKOFF,
+	}
+}
+
+// Watcher handler dispatching every event, errors are recorded in dead-letter file only.
+// Handlers block polling, so retries delay the next poll.
+func (dispatcher *Dispatcher) Handler(ctx context.Context) watcher.Handler {
+	return func(event *watcher.Event) {
+		_ = dispatcher.Dispatch(ctx, event)
+	}
+}
+
+// Send event to every endpoint accepting it, endpoints are called concurrently
+func (dispatcher *Dispatcher) Dispatch(ctx context.Context, event *watcher.Event) error {
+	// Passwords of links are not sent to endpoints
+	if event.Link != nil && (event.Link.Password != "" || event.Link.StatPassword != "") {
+		link := *event.Link
+		link.Password, link.StatPassword = "", ""
+
+		copied := *event
+		copied.Link = &link
+		event = &copied
+	}
+
+	payload := &Payload{Id: newId(), Event: event}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(dispatcher.Endpoints))
+
+	for i, endpoint := range dispatcher.Endpoints {
+		if !endpoint.Accepts(event) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, endpoint *Endpoint) {
+			defer wg.Done()
+			errs[i] = dispatcher.Deliver(ctx, endpoint, payload)
+		}(i, endpoint)
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Send payload to endpoint with retries, failed delivery is written to dead-letter file
+func (dispatcher *Dispatcher) Deliver(ctx context.Context, endpoint *Endpoint, payload *Payload) error {
+	body, e := json.Marshal(payload)
+	if e != nil {
+		return e
+	}
+
+	attempts := dispatcher.Attempts
+	if attempts <= 0 {
+		attempts = DEFAULT_ATTEMPTS
+	}
+
+	attempt := 0
+	for {
+		attempt++
+
+		var wait time.Duration
+		wait, e = dispatcher.send(ctx, endpoint, payload, body)
+		if e == nil {
+			return nil
+		}
+
+		var statusError *StatusError
+		if attempt >= attempts || ctx.Err() != nil || (errors.As(e, &statusError) && !statusError.Temporary()) {
+			break
+		}
+
+		if backoff := dispatcher.backoff(attempt); wait < backoff {
+			wait = backoff
+		}
+
+		if e = dispatcher.wait(ctx, wait); e != nil {
+			break
+		}
+	}
+
+	e = fmt.Errorf("webhook: %s: %w", endpoint.Name, e)
+
+	if dispatcher.DeadLetter != "" {
+		delivery := &Delivery{
+			Endpoint: endpoint.Name,
+			Url:      endpoint.Url,
+			Payload:  payload,
+			Attempts: attempt,
+			Error:    e.Error(),
+			Failed:   dispatcher.time(),
+		}
+
+		if writeError := dispatcher.writeDeadLetter(delivery); writeError != nil {
+			return errors.Join(e, writeError)
+		}
+	}
+
+	return e
+}
+
+// Deliver dead letter again to endpoint of the same name
+func (dispatcher *Dispatcher) Redeliver(ctx context.Context, delivery *Delivery) error {
+	for _, endpoint := range dispatcher.Endpoints {
+		if endpoint.Name == delivery.Endpoint {
+			return dispatcher.Deliver(ctx, endpoint, delivery.Payload)
+		}
+	}
+
+	return fmt.Errorf("webhook: unknown endpoint %q", delivery.Endpoint)
+}
+
+// One attempt, wait is delay requested with Retry-After
+func (dispatcher *Dispatcher) send(ctx context.Context, endpoint *Endpoint, payload *Payload, body []byte) (time.Duration, error) {
+	request, e := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+	if e != nil {
+		return 0, e
+	}
+
+	timestamp := strconv.FormatInt(dispatcher.time().Unix(), 10)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "tinysrc-webhook")
+	request.Header.Set(EVENT_HEADER, string(payload.Type))
+	request.Header.Set(DELIVERY_HEADER, payload.Id)
+	request.Header.Set(TIMESTAMP_HEADER, timestamp)
+	request.Header.Set(SIGNATURE_HEADER, Sign(endpoint.Secret, timestamp, body))
+
+	client := dispatcher.Client
+	if client == nil {
+		client = &http.Client{Timeout: DEFAULT_TIMEOUT}
+	}
+
+	response, e := client.Do(request)
+	if e != nil {
+		return 0, e
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return 0, nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+
+	var wait time.Duration
+	if seconds, e := strconv.Atoi(response.Header.Get("Retry-After")); e == nil && seconds > 0 {
+		wait = time.Duration(seconds) * time.Second
+		if wait > dispatcher.maxBackoff() {
+			wait = dispatcher.maxBackoff()
+		}
+	}
+
+	return wait, &StatusError{Status: response.StatusCode, Body: string(bytes.TrimSpace(data))}
+}
+
+// Delay after attempt, Backoff * 2^(attempt-1) up to MaxBackoff
+func (dispatcher *Dispatcher) backoff(attempt int) time.Duration {
+	backoff := dispatcher.Backoff
+	if backoff <= 0 {
+		backoff = DEFAULT_BACKOFF
+	}
+
+	for i := 1; i < attempt && backoff < dispatcher.maxBackoff(); i++ {
+		backoff *= 2
+	}
+
+	if backoff > dispatcher.maxBackoff() {
+		return dispatcher.maxBackoff()
+	}
+
+	return backoff
+}
+
+func (dispatcher *Dispatcher) maxBackoff() time.Duration {
+	if dispatcher.MaxBackoff <= 0 {
+		return DEFAULT_MAX_BACKOFF
+	}
+
+	return dispatcher.MaxBackoff
+}
+
+func (dispatcher *Dispatcher) wait(ctx context.Context, d time.Duration) error {
+	if dispatcher.sleep != nil {
+		return dispatcher.sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (dispatcher *Dispatcher) time() time.Time {
+	if dispatcher.now != nil {
+		return dispatcher.now()
+	}
+
+	return time.Now()
+}
+
+// Append delivery to dead-letter file, deliveries of concurrent endpoints are not interleaved
+func (dispatcher *Dispatcher) writeDeadLetter(delivery *Delivery) error {
+	line, e := json.Marshal(delivery)
+	if e != nil {
+		return e
+	}
+
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	file, e := os.OpenFile(dispatcher.DeadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if e != nil {
+		return e
+	}
+
+	if _, e = file.Write(append(line, '\n')); e != nil {
+		_ = file.Close()
+		return e
+	}
+
+	return file.Close()
+}
+
+// Read deliveries of dead-letter file, missing file has no deliveries
+func ReadDeadLetters(path string) ([]*Delivery, error) {
+	data, e := os.ReadFile(path)
+	if errors.Is(e, os.ErrNotExist) {
+		return nil, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	var deliveries []*Delivery
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		delivery := &Delivery{}
+		if e = json.Unmarshal(line, delivery); e != nil {
+			return nil, fmt.Errorf("webhook: %s:%d: %w", path, i+1, e)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func newId() string {
+	buf := make([]byte, 16)
+	if _, e := rand.Read(buf); e != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"github.com/dmitrypro77/tinysrc-api-sdk/models"
+	"github.com/dmitrypro77/tinysrc-api-sdk/watcher"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEndpoint_Accepts(t *testing.T) {
+	endpoint := &Endpoint{Events: []watcher.Type{watcher.ClickRecorded, watcher.LinkExpired}, Hashes: []string{"a"}}
+
+	tests := []struct {
+		name  string
+		event *watcher.Event
+		want  bool
+	}{
+		{name: "test_accepted", event: &watcher.Event{Type: watcher.ClickRecorded, Hash: "a"}, want: true},
+		{name: "test_other_type", event: &watcher.Event{Type: watcher.LinkCreated, Hash: "a"}, want: false},
+		{name: "test_other_hash", event: &watcher.Event{Type: watcher.LinkExpired, Hash: "b"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := endpoint.Accepts(tt.event); got != tt.want {
+				t.Errorf("Accepts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !(&Endpoint{}).Accepts(&watcher.Event{Type: watcher.LinkCreated, Hash: "b"}) {
+		t.Errorf("Accepts() without filters = false")
+	}
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	failures := map[string]int{"flaky": 2}
+
+	handler := func(name string, secret models.Secret, status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			payload, e := Verify(secret, r, 0)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if e != nil {
+				t.Errorf("%s: Verify() error = %v", name, e)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if failures[name] > 0 {
+				failures[name]--
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			received = append(received, name+" "+string(payload.Type)+" "+payload.Link.Password.Reveal())
+			if r.Header.Get(DELIVERY_HEADER) != payload.Id || r.Header.Get(EVENT_HEADER) != string(payload.Type) {
+				t.Errorf("%s: headers = %v", name, r.Header)
+			}
+			w.WriteHeader(status)
+		}
+	}
+
+	stable := httptest.NewServer(handler("stable", "secret-1", http.StatusOK))
+	defer stable.Close()
+	flaky := httptest.NewServer(handler("flaky", "secret-2", http.StatusNoContent))
+	defer flaky.Close()
+	rejecting := httptest.NewServer(handler("rejecting", "secret-3", http.StatusBadRequest))
+	defer rejecting.Close()
+
+	dispatcher := NewDispatcher(
+		&Endpoint{Name: "stable", Url: stable.URL, Secret: "secret-1"},
+		&Endpoint{Name: "flaky", Url: flaky.URL, Secret: "secret-2"},
+		&Endpoint{Name: "rejecting", Url: rejecting.URL, Secret: "secret-3"},
+		&Endpoint{Name: "filtered", Url: "http://127.0.0.1:1", Events: []watcher.Type{watcher.LinkExpired}},
+	)
+	dispatcher.DeadLetter = filepath.Join(t.TempDir(), "dead.jsonl")
+
+	var waits []time.Duration
+	dispatcher.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		waits = append(waits, d)
+		mu.Unlock()
+		return nil
+	}
+
+	event := &watcher.Event{
+		Type: watcher.ClickCountChanged,
+		Hash: "a",
+		Link: &models.LinkUserResponse{Hash: "a", Password: "link-password"},
+	}
+
+	e := dispatcher.Dispatch(context.Background(), event)
+	if e == nil || !strings.Contains(e.Error(), "webhook: rejecting: webhook: unexpected status 400") {
+		t.Fatalf("Dispatch() error = %v", e)
+	}
+
+	// Password of event is not changed, only payload has no password
+	if event.Link.Password != "link-password" {
+		t.Errorf("Dispatch() changed event")
+	}
+
+	want := []string{"flaky click.count_changed ", "rejecting click.count_changed ", "stable click.count_changed "}
+	mu.Lock()
+	sort.Strings(received)
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("received = %v, want %v", received, want)
+	}
+	// Retry-After of 1s is longer than backoff of the first attempt
+	if !reflect.DeepEqual(waits, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("waits = %v", waits)
+	}
+	mu.Unlock()
+
+	deliveries, e := ReadDeadLetters(dispatcher.DeadLetter)
+	if e != nil || len(deliveries) != 1 {
+		t.Fatalf("ReadDeadLetters() = %v, %v", deliveries, e)
+	}
+
+	delivery := deliveries[0]
+	if delivery.Endpoint != "rejecting" || delivery.Attempts != 1 || delivery.Payload.Hash != "a" || delivery.Payload.Link.Password != "" {
+		t.Errorf("ReadDeadLetters() = %+v", delivery)
+	}
+
+	// Dead letter is sent again with the same id
+	dispatcher.Endpoints[2].Url = stable.URL
+	dispatcher.Endpoints[2].Secret = "secret-1"
+	if e = dispatcher.Redeliver(context.Background(), delivery); e != nil {
+		t.Errorf("Redeliver() error = %v", e)
+	}
+	if e = dispatcher.Redeliver(context.Background(), &Delivery{Endpoint: "unknown"}); e == nil {
+		t.Errorf("Redeliver() expected error")
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("upstream is down"))
+	}))
+	defer ts.Close()
+
+	dispatcher := NewDispatcher()
+	dispatcher.Attempts = 4
+	dispatcher.Backoff = 10 * time.Millisecond
+	dispatcher.MaxBackoff = 25 * time.Millisecond
+
+	var waits []time.Duration
+	dispatcher.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	payload := &Payload{Id: "1", Event: &watcher.Event{Type: watcher.LinkCreated, Hash: "a"}}
+	e := dispatcher.Deliver(context.Background(), &Endpoint{Name: "down", Url: ts.URL}, payload)
+	if e == nil || !strings.HasSuffix(e.Error(), "status 502: upstream is down") {
+		t.Errorf("Deliver() error = %v", e)
+	}
+
+	if attempts != 4 || !reflect.DeepEqual(waits, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond}) {
+		t.Errorf("Deliver() attempts = %d, waits = %v", attempts, waits)
+	}
+
+	// Cancelled context stops retries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.sleep = nil
+	attempts = 0
+	if e = dispatcher.Deliver(ctx, &Endpoint{Name: "down", Url: ts.URL}, payload); e == nil || attempts != 0 {
+		t.Errorf("Deliver() = %v after %d attempts", e, attempts)
+	}
+}